@@ -2,20 +2,28 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
 	"github.com/EgorLis/my-docs/internal/auth/blacklist"
+	"github.com/EgorLis/my-docs/internal/auth/epoch"
+	"github.com/EgorLis/my-docs/internal/auth/mfa"
+	"github.com/EgorLis/my-docs/internal/auth/oidc"
 	"github.com/EgorLis/my-docs/internal/auth/password"
+	"github.com/EgorLis/my-docs/internal/auth/throttle"
+	"github.com/EgorLis/my-docs/internal/auth/ticket"
 	"github.com/EgorLis/my-docs/internal/auth/token"
 	"github.com/EgorLis/my-docs/internal/config"
 	"github.com/EgorLis/my-docs/internal/domain"
 	redisx "github.com/EgorLis/my-docs/internal/infra/cache/redis"
 	"github.com/EgorLis/my-docs/internal/infra/database/postgres"
+	"github.com/EgorLis/my-docs/internal/infra/jsonschema"
 	s3storage "github.com/EgorLis/my-docs/internal/infra/storage/s3"
 	"github.com/EgorLis/my-docs/internal/transport/web"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
 )
 
 type App struct {
@@ -25,6 +33,9 @@ type App struct {
 	storage domain.BlobStorage
 	cache   domain.Cache
 	repo    domain.UsersRepo
+	// фоновая чистка корзины
+	trash     domain.TrashRepo
+	revisions domain.RevisionsRepo
 }
 
 func Build(ctx context.Context) (*App, error) {
@@ -64,24 +75,88 @@ func Build(ctx context.Context) (*App, error) {
 	}
 
 	base.Println("init Redis")
-	rc := redisx.New(redisx.Config{
-		Addr:     cfg.RedisAddr,
-		DB:       cfg.RedisDB,
-		Password: cfg.RedisPassword,
+	rc, err := redisx.New(redisx.Config{
+		Mode:                  redisx.Mode(cfg.RedisMode),
+		Addr:                  cfg.RedisAddr,
+		Addrs:                 cfg.RedisAddrs,
+		MasterName:            cfg.RedisMasterName,
+		SentinelUsername:      cfg.RedisSentinelUsername,
+		SentinelPassword:      cfg.RedisSentinelPassword,
+		DB:                    cfg.RedisDB,
+		Username:              cfg.RedisUsername,
+		Password:              cfg.RedisPassword,
+		TLS:                   cfg.RedisTLS,
+		TLSServerName:         cfg.RedisTLSServerName,
+		TLSInsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		PoolSize:              cfg.RedisPoolSize,
+		MinIdleConns:          cfg.RedisMinIdleConns,
 	}, redisLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed init redis: %w", err)
+	}
 	if err := rc.Ping(ctx); err != nil {
 		return nil, fmt.Errorf("failed init redis: %w", err)
 	}
 	base.Println("Redis is initialized")
 
 	// Auth primitives
-	hasher := password.NewDefault()
-	tm := token.New(cfg.AuthJWTSecret, cfg.AuthIssuer, cfg.AuthTokenTTL)
-	blacklist := blacklist.NewStore(rc, "jti:")
+	hasher := password.NewWithParams(password.Params{
+		Memory:      cfg.AuthArgon2Memory,
+		Iterations:  cfg.AuthArgon2Iterations,
+		Parallelism: cfg.AuthArgon2Parallelism,
+		SaltLength:  cfg.AuthArgon2SaltLength,
+		KeyLength:   cfg.AuthArgon2KeyLength,
+	})
+	tm, err := newTokenManager(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed init token manager: %w", err)
+	}
+	blacklist := blacklist.NewStore(rc)
+	limiter := throttle.New(rc, throttle.Config{
+		BackoffAfter:   cfg.ThrottleBackoffAfter,
+		IPBackoffAfter: cfg.ThrottleIPBackoffAfter,
+		BackoffBase:    cfg.ThrottleBackoffBase,
+		BackoffMax:     cfg.ThrottleBackoffMax,
+		LockoutAfter:   cfg.ThrottleLockoutAfter,
+		LockoutTTL:     cfg.ThrottleLockoutTTL,
+	})
+
+	var box domain.SecretBox
+	if cfg.AuthMFAKey != "" {
+		b, err := mfa.NewBox(cfg.AuthMFAKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed init mfa: %w", err)
+		}
+		box = b
+	}
+
+	// OIDC: без OIDC_ISSUER вход через провайдера отключён
+	var provider domain.OIDCProvider
+	if cfg.OIDCIssuer != "" {
+		provider = oidc.New(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		})
+	}
+
+	cookies, err := newCookies(cfg, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed init cookies: %w", err)
+	}
 
 	base.Println("init Server")
-	rep := web.Repos{Users: pgRepo, Docs: pgRepo, Shares: pgRepo}
-	auth := web.AuthDeps{Hasher: hasher, Tokens: tm, Blacklist: blacklist}
+	rep := web.Repos{Users: pgRepo, Docs: pgRepo, Shares: pgRepo, ShareLinks: pgRepo, Groups: pgRepo, Transfers: pgRepo, Revisions: pgRepo, Trash: pgRepo, Folders: pgRepo, Schemas: pgRepo, RefreshTokens: pgRepo, PersonalTokens: pgRepo, Sessions: pgRepo,
+		LoginAttempts: pgRepo, MFA: pgRepo, UserAdmin: pgRepo, Invites: pgRepo, Identities: pgRepo,
+		SchemaValidator: jsonschema.New(0)}
+	auth := web.AuthDeps{Hasher: hasher, Tokens: tm, Blacklist: blacklist, Keys: tm,
+		Epochs: epoch.NewStore(rc, cfg.AuthTokenTTL), Disabled: epoch.NewDisabled(pgRepo, 0), Throttle: limiter,
+		Box: box, MFAPending: mfa.NewPendingStore(rc, cfg.AuthMFAPendingTTL),
+		OIDC: provider, OIDCStates: oidc.NewStateStore(rc, cfg.OIDCStateTTL),
+		Cookies: cookies, QueryTokens: mw.ParseQueryTokenMode(cfg.AuthQueryToken),
+		Tickets: ticket.NewStore(rc, cfg.AuthDownloadLinkTTL)}
 	server := web.New(serverLog, cfg, rep, auth, s3, rc)
 	base.Println("Server is initialized")
 
@@ -92,12 +167,35 @@ func Build(ctx context.Context) (*App, error) {
 		log:     base,
 		storage: s3,
 		repo:    pgRepo,
-		cache:   rc}, nil
+		cache:   rc,
+
+		trash:     pgRepo,
+		revisions: pgRepo}, nil
+}
+
+// newTokenManager: без AUTH_JWT_KEYS_DIR — HS256 на общем секрете (как раньше).
+// С каталогом ключей подписываем AUTH_JWT_ACTIVE_KID; секрет (если задан) остаётся
+// ключом только для проверки, чтобы уже выданные HS256-токены дожили до exp.
+func newTokenManager(cfg *config.Config) (*token.Manager, error) {
+	if cfg.AuthJWTKeysDir == "" {
+		return token.New(cfg.AuthJWTSecret, cfg.AuthIssuer, cfg.AuthTokenTTL), nil
+	}
+	keys, err := token.LoadKeysDir(cfg.AuthJWTKeysDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AuthJWTSecret != "" {
+		keys = append(keys, token.NewHMACKey(token.LegacyHMACKID, []byte(cfg.AuthJWTSecret)))
+	}
+	return token.NewWithKeys(cfg.AuthIssuer, cfg.AuthTokenTTL, cfg.AuthJWTActiveKID, keys)
 }
 
 func (a *App) Run(ctx context.Context) error {
 	a.log.Println("start application...")
 	go a.server.Run()
+	if a.config.TrashRetention > 0 {
+		go a.purgeTrash(ctx)
+	}
 	<-ctx.Done()
 	a.log.Println("stop application...")
 
@@ -109,3 +207,27 @@ func (a *App) Run(ctx context.Context) error {
 
 	return nil
 }
+
+// newCookies — настройки браузерных cookie-сессий. Без AUTH_CSRF_KEY ключ CSRF
+// случайный: после рестарта или на другой реплике CSRF-токены не сойдутся.
+func newCookies(cfg *config.Config, lg *log.Logger) (*mw.Cookies, error) {
+	c := &mw.Cookies{
+		Enabled:    cfg.AuthCookies,
+		Secure:     !cfg.AuthCookieInsecure,
+		Domain:     cfg.AuthCookieDomain,
+		SameSite:   mw.ParseSameSite(cfg.AuthCookieSameSite),
+		CSRFKey:    []byte(cfg.AuthCSRFKey),
+		RefreshTTL: cfg.AuthRefreshTTL,
+	}
+	if c.RefreshTTL <= 0 {
+		c.RefreshTTL = 30 * 24 * time.Hour
+	}
+	if c.Enabled && len(c.CSRFKey) == 0 {
+		c.CSRFKey = make([]byte, 32)
+		if _, err := rand.Read(c.CSRFKey); err != nil {
+			return nil, err
+		}
+		lg.Println("WARN: AUTH_CSRF_KEY is empty, using a random per-process key")
+	}
+	return c, nil
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+)
+
+// Сколько документов удаляется за одну транзакцию фоновой чистки
+const trashPurgeBatch = 100
+
+// purgeTrash раз в TRASH_PURGE_INTERVAL окончательно удаляет документы, пролежавшие
+// в корзине дольше TRASH_RETENTION, и освобождает их объекты в storage.
+func (a *App) purgeTrash(ctx context.Context) {
+	interval := a.config.TrashPurgeInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	a.log.Printf("trash purge started: retention=%s interval=%s", a.config.TrashRetention, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.purgeTrashOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) purgeTrashOnce(ctx context.Context) {
+	before := time.Now().Add(-a.config.TrashRetention)
+	total := 0
+	for ctx.Err() == nil {
+		n, keys, err := a.trash.PurgeExpired(ctx, before, trashPurgeBatch)
+		if err != nil {
+			a.log.Printf("trash purge error: %v", err)
+			return
+		}
+		if _, err := doc.DropBlobs(ctx, a.revisions, a.storage, keys); err != nil {
+			a.log.Printf("trash purge drop blobs error: %v", err)
+		}
+		total += n
+		if n < trashPurgeBatch {
+			break
+		}
+	}
+	if total > 0 {
+		a.log.Printf("trash purge ok: docs=%d", total)
+	}
+}
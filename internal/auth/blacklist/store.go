@@ -14,15 +14,11 @@ type KV interface {
 }
 
 type Store struct {
-	kv     KV
-	prefix string
+	kv KV
 }
 
-func NewStore(kv KV, prefix string) *Store {
-	if prefix == "" {
-		prefix = "jti:"
-	}
-	return &Store{kv: kv, prefix: prefix}
+func NewStore(kv KV) *Store {
+	return &Store{kv: kv}
 }
 
 func (s *Store) key(jti string) string { return domain.CacheKeyTokenJTI(jti) }
@@ -0,0 +1,72 @@
+package epoch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// UserLookup — чтение пользователя из БД (users.disabled_at).
+type UserLookup interface {
+	UserByID(ctx context.Context, id domain.UserID) (domain.User, error)
+}
+
+type disabledEntry struct {
+	disabled bool
+	until    time.Time
+}
+
+// Disabled — отключён ли пользователь, по users.disabled_at с коротким кешем
+// в памяти процесса. Эпоха в Redis отсекает токены сразу после отключения, но
+// ключ может быть вытеснен или истечь — тогда решает эта проверка (с задержкой
+// не больше ttl).
+type Disabled struct {
+	users UserLookup
+	ttl   time.Duration
+	max   int
+
+	mu    sync.Mutex
+	cache map[domain.UserID]disabledEntry
+}
+
+var _ domain.DisabledUsers = (*Disabled)(nil)
+
+func NewDisabled(users UserLookup, ttl time.Duration) *Disabled {
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+	return &Disabled{users: users, ttl: ttl, max: 10000, cache: map[domain.UserID]disabledEntry{}}
+}
+
+// IsDisabled: удалённый пользователь считается отключённым.
+func (d *Disabled) IsDisabled(ctx context.Context, id domain.UserID) (bool, error) {
+	now := time.Now()
+	d.mu.Lock()
+	e, ok := d.cache[id]
+	d.mu.Unlock()
+	if ok && now.Before(e.until) {
+		return e.disabled, nil
+	}
+
+	u, err := d.users.UserByID(ctx, id)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		e = disabledEntry{disabled: true}
+	case err != nil:
+		return false, err
+	default:
+		e = disabledEntry{disabled: u.DisabledAt != nil}
+	}
+	e.until = now.Add(d.ttl)
+
+	d.mu.Lock()
+	if len(d.cache) >= d.max {
+		clear(d.cache)
+	}
+	d.cache[id] = e
+	d.mu.Unlock()
+	return e.disabled, nil
+}
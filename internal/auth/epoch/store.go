@@ -0,0 +1,61 @@
+package epoch
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// KV — минимальный интерфейс, который нам нужен от кеша.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttlSeconds int) error
+}
+
+// Store хранит эпоху токенов пользователя в Redis.
+// TTL = время жизни access-токена: после него все токены, выданные до эпохи,
+// истекут сами, и ключ больше не нужен.
+type Store struct {
+	kv  KV
+	ttl time.Duration
+}
+
+var _ domain.TokenEpochs = (*Store)(nil)
+
+func NewStore(kv KV, tokenTTL time.Duration) *Store {
+	return &Store{kv: kv, ttl: tokenTTL}
+}
+
+// Bump делает недействительными все JWT пользователя, выпущенные до текущего момента.
+// Эпоха и время выпуска токена (iat_us) хранятся в микросекундах, поэтому сессия,
+// начатая сразу после Bump в том же запросе (смена пароля), остаётся действительной.
+func (s *Store) Bump(ctx context.Context, userID domain.UserID) (time.Time, error) {
+	now := time.Now().Truncate(time.Microsecond)
+	ttl := s.ttl
+	if ttl <= 0 {
+		ttl = 24 * time.Hour // подстраховка, если TTL токенов не задан
+	}
+	err := s.kv.Set(ctx, domain.CacheKeyTokenEpoch(userID.String()),
+		[]byte(strconv.FormatInt(now.UnixMicro(), 10)), int(ttl.Seconds())+1)
+	return now, err
+}
+
+// legacySeconds: раньше эпоха хранилась в секундах; такие значения на порядки меньше.
+const legacySeconds = 1e11
+
+func (s *Store) Epoch(ctx context.Context, userID domain.UserID) (time.Time, error) {
+	b, err := s.kv.Get(ctx, domain.CacheKeyTokenEpoch(userID.String()))
+	if err != nil || len(b) == 0 {
+		return time.Time{}, err
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n < legacySeconds {
+		return time.Unix(n, 0), nil
+	}
+	return time.UnixMicro(n), nil
+}
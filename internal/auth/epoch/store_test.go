@@ -0,0 +1,52 @@
+package epoch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+type memKV map[string][]byte
+
+func (m memKV) Get(_ context.Context, key string) ([]byte, error) { return m[key], nil }
+func (m memKV) Set(_ context.Context, key string, val []byte, _ int) error {
+	m[key] = val
+	return nil
+}
+
+func TestBumpEpoch(t *testing.T) {
+	kv := memKV{}
+	s := NewStore(kv, time.Minute)
+	user := uuid.New()
+	ctx := context.Background()
+
+	if ep, err := s.Epoch(ctx, user); err != nil || !ep.IsZero() {
+		t.Fatalf("Epoch before Bump = (%v, %v), want zero", ep, err)
+	}
+	before := time.Now()
+	bumped, err := s.Bump(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep, err := s.Epoch(ctx, user)
+	if err != nil || !ep.Equal(bumped) {
+		t.Fatalf("Epoch = (%v, %v), want %v", ep, err, bumped)
+	}
+	// без округления до секунд: токен, выпущенный сразу после Bump, не раньше эпохи
+	if ep.Before(before.Truncate(time.Microsecond)) || ep.After(time.Now()) {
+		t.Errorf("epoch %v outside of Bump call", ep)
+	}
+}
+
+func TestEpochLegacySeconds(t *testing.T) {
+	user := uuid.New()
+	kv := memKV{domain.CacheKeyTokenEpoch(user.String()): []byte("1700000000")}
+	ep, err := NewStore(kv, time.Minute).Epoch(context.Background(), user)
+	if err != nil || !ep.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("legacy Epoch = (%v, %v), want 1700000000s", ep, err)
+	}
+}
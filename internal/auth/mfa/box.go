@@ -0,0 +1,55 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// Box — AES-256-GCM; формат: nonce || ciphertext.
+type Box struct {
+	aead cipher.AEAD
+}
+
+var _ domain.SecretBox = (*Box)(nil)
+
+// NewBox принимает ключ в base64 (32 байта после декодирования).
+func NewBox(keyB64 string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(keyB64)
+	if err != nil {
+		return nil, fmt.Errorf("mfa key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("mfa key: want 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+func (b *Box) Seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (b *Box) Open(sealed []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("sealed data too short")
+	}
+	return b.aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
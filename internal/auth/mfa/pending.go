@@ -0,0 +1,63 @@
+package mfa
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/google/uuid"
+)
+
+// KV — минимальный интерфейс, который нам нужен от кеша.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttlSeconds int) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// PendingStore держит "mfa pending" токены в Redis (ключ — хэш токена).
+type PendingStore struct {
+	kv  KV
+	ttl time.Duration
+}
+
+var _ domain.MFAPendingStore = (*PendingStore)(nil)
+
+func NewPendingStore(kv KV, ttl time.Duration) *PendingStore {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &PendingStore{kv: kv, ttl: ttl}
+}
+
+func (s *PendingStore) key(raw string) string {
+	return domain.CacheKeyMFAPending(hex.EncodeToString(token.HashOpaque(raw)))
+}
+
+func (s *PendingStore) Create(ctx context.Context, userID domain.UserID) (string, time.Time, error) {
+	raw, _, err := token.NewOpaque()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.kv.Set(ctx, s.key(raw), []byte(userID.String()), int(s.ttl.Seconds())); err != nil {
+		return "", time.Time{}, err
+	}
+	return raw, time.Now().Add(s.ttl), nil
+}
+
+func (s *PendingStore) Lookup(ctx context.Context, raw string) (domain.UserID, error) {
+	b, err := s.kv.Get(ctx, s.key(raw))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(b) == 0 {
+		return uuid.Nil, domain.ErrUnauth
+	}
+	return uuid.Parse(string(b))
+}
+
+func (s *PendingStore) Delete(ctx context.Context, raw string) error {
+	return s.kv.Del(ctx, s.key(raw))
+}
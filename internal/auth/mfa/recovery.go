@@ -0,0 +1,34 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+// RecoveryCodesCount — сколько кодов выдаём при включении 2FA
+const RecoveryCodesCount = 10
+
+var recoveryEnc = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes — коды вида "abcd-efgh" и их хэши для БД.
+func NewRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for range RecoveryCodesCount {
+		b := make([]byte, 5) // 40 бит → 8 символов base32
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEnc.EncodeToString(b))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, HashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode нормализует ввод (регистр, дефисы, пробелы) и хэширует.
+func HashRecoveryCode(code string) []byte {
+	norm := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(norm))
+	return sum[:]
+}
@@ -0,0 +1,107 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет и моменты из приложения B RFC 6238 (SHA-1); там коды из 8 цифр,
+// наши 6 — их младшие разряды.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		if got := Code(rfcSecret, step); got != tt.want {
+			t.Errorf("Code(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	cur := Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"текущий шаг", Code(rfcSecret, cur), cur, true},
+		{"предыдущий шаг", Code(rfcSecret, cur-1), cur - 1, true},
+		{"следующий шаг", Code(rfcSecret, cur+1), cur + 1, true},
+		{"пробелы по краям", " " + Code(rfcSecret, cur) + "\n", cur, true},
+		{"за окном назад", Code(rfcSecret, cur-2), 0, false},
+		{"за окном вперёд", Code(rfcSecret, cur+2), 0, false},
+		{"короткий", "12345", 0, false},
+		{"длинный", "1234567", 0, false},
+		{"пустой", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Verify(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Verify(%q) = (%d, %v), want (%d, %v)", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyOtherSecret(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	other := []byte("abcdefghijabcdefghij")
+	if _, ok := Verify(other, Code(rfcSecret, Step(now)), now); ok {
+		t.Error("code for another secret accepted")
+	}
+}
+
+func TestLooksLikeTOTP(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"123456", true},
+		{" 123456 ", true},
+		{"12345", false},
+		{"1234567", false},
+		{"12345a", false},
+		{"abcd-efgh", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeTOTP(tt.code); got != tt.want {
+			t.Errorf("LooksLikeTOTP(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("my-docs", "alice@example.com", rfcSecret)
+	for _, part := range []string{
+		"otpauth://totp/my-docs:alice@example.com?",
+		"secret=" + EncodeSecret(rfcSecret),
+		"issuer=my-docs",
+		"digits=6",
+		"period=30",
+	} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("ProvisioningURI = %q, missing %q", uri, part)
+		}
+	}
+	if strings.Contains(EncodeSecret(rfcSecret), "=") {
+		t.Error("EncodeSecret must not pad")
+	}
+}
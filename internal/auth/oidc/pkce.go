@@ -0,0 +1,37 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewVerifier — code_verifier PKCE (RFC 7636): 32 случайных байта → 43 символа base64url.
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// Challenge — code_challenge для метода S256.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewState / NewNonce — непредсказуемые значения для state и nonce.
+func NewState() (string, error) { return randomString(24) }
+func NewNonce() (string, error) { return randomString(24) }
+
+// StateBinding — хеш state для cookie браузера, начавшего вход: callback
+// принимается только в том же браузере (защита от подсунутого чужого state).
+func StateBinding(state string) string {
+	sum := sha256.Sum256([]byte("mydocs-oidc-state:" + state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,36 @@
+package oidc
+
+import "testing"
+
+// Пример из приложения B RFC 7636
+func TestChallengeRFC7636(t *testing.T) {
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const want = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got := Challenge(verifier); got != want {
+		t.Errorf("Challenge = %q, want %q", got, want)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	v, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RFC 7636: 43–128 символов
+	if len(v) != 43 {
+		t.Errorf("len(verifier) = %d, want 43", len(v))
+	}
+}
+
+func TestStateBinding(t *testing.T) {
+	a, b := StateBinding("state-a"), StateBinding("state-b")
+	if a == b {
+		t.Error("different states share a binding")
+	}
+	if a != StateBinding("state-a") {
+		t.Error("binding is not deterministic")
+	}
+	if a == "state-a" {
+		t.Error("binding must not expose the state")
+	}
+}
@@ -0,0 +1,284 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// Не чаще раза в минуту перечитываем JWKS из-за неизвестного kid
+const jwksMinRefresh = time.Minute
+
+var (
+	errNoIDToken  = errors.New("oidc: token response has no id_token")
+	errUnknownKID = errors.New("oidc: unknown kid")
+)
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // пусто — публичный клиент (только PKCE)
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// Метаданные провайдера (/.well-known/openid-configuration)
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Provider — OIDC-клиент. Метаданные и ключи загружаются лениво и кешируются,
+// чтобы недоступный IdP не мешал старту сервиса.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]any // kid -> публичный ключ
+	keysAt    time.Time
+	singleKey any // ключ без kid (если у IdP он один)
+}
+
+var _ domain.OIDCProvider = (*Provider)(nil)
+
+func New(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) Issuer() string { return p.cfg.Issuer }
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	u := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var m metadata
+	if err := p.getJSON(ctx, u, &m); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// OIDC Discovery §4.3: issuer в метаданных обязан совпадать с ожидаемым
+	if m.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q != %q", m.Issuer, p.cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+	p.meta = &m
+	return p.meta, nil
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (domain.OIDCClaims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return domain.OIDCClaims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domain.OIDCClaims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic (RFC 6749 §2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return domain.OIDCClaims{}, fmt.Errorf("oidc token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return domain.OIDCClaims{}, fmt.Errorf("oidc token: status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return domain.OIDCClaims{}, fmt.Errorf("oidc token: status %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.IDToken == "" {
+		return domain.OIDCClaims{}, errNoIDToken
+	}
+	return p.verify(ctx, m, tr.IDToken)
+}
+
+type idTokenClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // некоторые IdP присылают строкой
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Nonce             string `json:"nonce"`
+	AZP               string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// verify проверяет подпись ID-токена ключами из JWKS и стандартные клеймы.
+func (p *Provider) verify(ctx context.Context, m *metadata, raw string) (domain.OIDCClaims, error) {
+	var cl idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &cl,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.key(ctx, m, kid)
+		},
+		jwt.WithValidMethods(p.algs(m)),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return domain.OIDCClaims{}, fmt.Errorf("oidc id_token: %w", err)
+	}
+	// несколько аудиторий — azp обязан указывать на нас (OIDC Core §3.1.3.7)
+	if len(cl.Audience) > 1 && cl.AZP != p.cfg.ClientID {
+		return domain.OIDCClaims{}, fmt.Errorf("oidc id_token: azp %q is not client", cl.AZP)
+	}
+	if cl.Subject == "" {
+		return domain.OIDCClaims{}, errors.New("oidc id_token: empty sub")
+	}
+
+	verified := false
+	switch v := cl.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	return domain.OIDCClaims{
+		Subject:           cl.Subject,
+		Email:             cl.Email,
+		EmailVerified:     verified,
+		PreferredUsername: cl.PreferredUsername,
+		Name:              cl.Name,
+		Nonce:             cl.Nonce,
+	}, nil
+}
+
+// algs — асимметричные алгоритмы, которые объявил провайдер (RS256 по умолчанию).
+// HS*/none не принимаем никогда.
+func (p *Provider) algs(m *metadata) []string {
+	supported := map[string]bool{"RS256": true, "RS384": true, "RS512": true, "ES256": true, "ES384": true, "EdDSA": true}
+	var out []string
+	for _, a := range m.SigningAlgs {
+		if supported[a] {
+			out = append(out, a)
+		}
+	}
+	if len(out) == 0 {
+		out = []string{"RS256"}
+	}
+	return out
+}
+
+// key ищет ключ по kid; неизвестный kid — повод перечитать JWKS (ротация у IdP).
+func (p *Provider) key(ctx context.Context, m *metadata, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lookup := func() (any, bool) {
+		if kid == "" {
+			return p.singleKey, p.singleKey != nil
+		}
+		k, ok := p.keys[kid]
+		return k, ok
+	}
+	if k, ok := lookup(); ok {
+		return k, nil
+	}
+	if p.keys != nil && time.Since(p.keysAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("%w %q", errUnknownKID, kid)
+	}
+
+	var set jwkSet
+	if err := p.getJSON(ctx, m.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys, single := set.publicKeys()
+	p.keys, p.singleKey, p.keysAt = keys, single, time.Now()
+
+	if k, ok := lookup(); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKID, kid)
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
@@ -0,0 +1,65 @@
+package oidc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// KV — минимальный интерфейс, который нам нужен от кеша.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttlSeconds int) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// StateStore держит nonce и code_verifier незавершённых входов в Redis (ключ — хэш state).
+type StateStore struct {
+	kv  KV
+	ttl time.Duration
+}
+
+var _ domain.OIDCStateStore = (*StateStore)(nil)
+
+func NewStateStore(kv KV, ttl time.Duration) *StateStore {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &StateStore{kv: kv, ttl: ttl}
+}
+
+func (s *StateStore) key(state string) string {
+	return domain.CacheKeyOIDCState(hex.EncodeToString(token.HashOpaque(state)))
+}
+
+func (s *StateStore) Save(ctx context.Context, state string, f domain.OIDCFlow) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.kv.Set(ctx, s.key(state), b, int(s.ttl.Seconds()))
+}
+
+// Take: state одноразовый — удаляем сразу после чтения. Гонка двух callback-ов
+// с одним state безопасна: code у IdP тоже одноразовый.
+func (s *StateStore) Take(ctx context.Context, state string) (domain.OIDCFlow, error) {
+	k := s.key(state)
+	b, err := s.kv.Get(ctx, k)
+	if err != nil {
+		return domain.OIDCFlow{}, err
+	}
+	if len(b) == 0 {
+		return domain.OIDCFlow{}, domain.ErrUnauth
+	}
+	_ = s.kv.Del(ctx, k)
+
+	var f domain.OIDCFlow
+	if err := json.Unmarshal(b, &f); err != nil {
+		return domain.OIDCFlow{}, err
+	}
+	return f, nil
+}
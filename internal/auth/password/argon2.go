@@ -1,15 +1,21 @@
 package password
 
 import (
+	"crypto/rand"
 	"errors"
+	"math/big"
 
 	"github.com/alexedwards/argon2id"
+
+	"github.com/EgorLis/my-docs/internal/domain"
 )
 
 type Hasher struct {
 	params *argon2id.Params
 }
 
+var _ domain.PasswordHasher = (*Hasher)(nil)
+
 func NewDefault() *Hasher {
 	// параметры по умолчанию (достаточно безопасны и не слишком тяжёлые)
 	return &Hasher{params: argon2id.DefaultParams}
@@ -17,6 +23,35 @@ func NewDefault() *Hasher {
 
 func New(p *argon2id.Params) *Hasher { return &Hasher{params: p} }
 
+// Params — параметры из конфига; нули заменяются значениями argon2id.DefaultParams.
+type Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func NewWithParams(p Params) *Hasher {
+	d := *argon2id.DefaultParams
+	if p.Memory > 0 {
+		d.Memory = p.Memory
+	}
+	if p.Iterations > 0 {
+		d.Iterations = p.Iterations
+	}
+	if p.Parallelism > 0 {
+		d.Parallelism = p.Parallelism
+	}
+	if p.SaltLength > 0 {
+		d.SaltLength = p.SaltLength
+	}
+	if p.KeyLength > 0 {
+		d.KeyLength = p.KeyLength
+	}
+	return &Hasher{params: &d}
+}
+
 // Hash возвращает закодированную строку формата $argon2id$v=19$m=..., которую можно хранить в БД.
 func (h *Hasher) Hash(plain string) (string, error) {
 	if h == nil || h.params == nil {
@@ -29,3 +64,41 @@ func (h *Hasher) Hash(plain string) (string, error) {
 func (h *Hasher) Verify(plain, encodedHash string) (bool, error) {
 	return argon2id.ComparePasswordAndHash(plain, encodedHash)
 }
+
+// NeedsRehash — хэш сделан с более слабыми параметрами, чем текущие
+// (или не разбирается вовсе). Пересчитываем при следующем успешном входе.
+func (h *Hasher) NeedsRehash(encodedHash string) bool {
+	p, _, _, err := argon2id.DecodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	cur := h.params
+	return p.Memory < cur.Memory ||
+		p.Iterations < cur.Iterations ||
+		p.Parallelism < cur.Parallelism ||
+		p.SaltLength < cur.SaltLength ||
+		p.KeyLength < cur.KeyLength
+}
+
+const genAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789!@#$%^&*-_"
+
+// Generate — случайный временный пароль, проходящий domain.ValidPassword.
+func Generate(n int) (string, error) {
+	if n < 8 {
+		n = 8
+	}
+	buf := make([]byte, n)
+	limit := big.NewInt(int64(len(genAlphabet)))
+	for {
+		for i := range buf {
+			k, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", err
+			}
+			buf[i] = genAlphabet[k.Int64()]
+		}
+		if s := string(buf); domain.ValidPassword(s) {
+			return s, nil
+		}
+	}
+}
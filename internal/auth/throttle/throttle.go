@@ -0,0 +1,243 @@
+package throttle
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// KV — минимальный интерфейс, который нам нужен от кеша.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttlSeconds int) error
+	Del(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Decr(ctx context.Context, key string) (int64, error)
+	SetNX(ctx context.Context, key string, val []byte, ttlSeconds int) (bool, error)
+	Expire(ctx context.Context, key string, ttlSeconds int) error
+}
+
+type Config struct {
+	BackoffAfter   int           // попыток по логину до начала backoff
+	IPBackoffAfter int           // попыток с одного IP до начала backoff (выше: за NAT много людей)
+	BackoffBase    time.Duration // первая задержка, дальше удваивается
+	BackoffMax     time.Duration
+	LockoutAfter   int           // неудачных попыток по логину до блокировки аккаунта
+	LockoutTTL     time.Duration // длительность блокировки; она же окно счёта попыток
+}
+
+func (c Config) withDefaults() Config {
+	if c.BackoffAfter <= 0 {
+		c.BackoffAfter = 3
+	}
+	if c.IPBackoffAfter <= 0 {
+		c.IPBackoffAfter = 20
+	}
+	if c.BackoffBase <= 0 {
+		c.BackoffBase = time.Second
+	}
+	if c.BackoffMax <= 0 {
+		c.BackoffMax = 5 * time.Minute
+	}
+	if c.LockoutAfter <= 0 {
+		c.LockoutAfter = 10
+	}
+	if c.LockoutTTL <= 0 {
+		c.LockoutTTL = 15 * time.Minute
+	}
+	return c
+}
+
+const (
+	kindLogin = "login"
+	kindIP    = "ip"
+)
+
+// Limiter — backoff/блокировки в Redis.
+// Для субъекта храним счётчик попыток (fails: неудачные и ещё не проверенные)
+// и ключи block/lock, значение которых — unix-время окончания; TTL ключа
+// совпадает с ним.
+type Limiter struct {
+	kv  KV
+	cfg Config
+}
+
+var _ domain.LoginThrottle = (*Limiter)(nil)
+
+func New(kv KV, cfg Config) *Limiter {
+	return &Limiter{kv: kv, cfg: cfg.withDefaults()}
+}
+
+// Attempt учитывает попытку до проверки учётных данных: счётчик растёт
+// атомарно (INCR), поэтому параллельная пачка запросов не проскакивает мимо
+// backoff. Заблокированная попытка не считается — счётчик возвращается назад.
+func (l *Limiter) Attempt(ctx context.Context, login, ip string) (domain.ThrottleState, error) {
+	if login != "" {
+		until, err := l.until(ctx, domain.CacheKeyThrottle(kindLogin, login, "lock"))
+		if err != nil {
+			return domain.ThrottleState{}, err
+		}
+		if d := time.Until(until); d > 0 {
+			return domain.ThrottleState{RetryAfter: d, Locked: true}, nil
+		}
+	}
+	var counted []string // субъекты, чьи счётчики уже увеличены этой попыткой
+	refund := func() {
+		for _, key := range counted {
+			_ = l.refund(ctx, key)
+		}
+	}
+	if login != "" {
+		st, err := l.take(ctx, kindLogin, login, l.cfg.BackoffAfter)
+		if err != nil || st.Blocked() {
+			return st, err
+		}
+		counted = append(counted, domain.CacheKeyThrottle(kindLogin, login, "fails"))
+	}
+	if ip != "" {
+		st, err := l.take(ctx, kindIP, ip, l.cfg.IPBackoffAfter)
+		if err != nil || st.Blocked() {
+			refund()
+			return st, err
+		}
+	}
+	return domain.ThrottleState{}, nil
+}
+
+// take занимает попытку субъекта. Начиная с after-й попытки каждая открывает
+// окно backoff (SETNX ключа block); пока окно открыто, остальные попытки
+// отклоняются с Retry-After из него.
+func (l *Limiter) take(ctx context.Context, kind, subject string, after int) (domain.ThrottleState, error) {
+	var st domain.ThrottleState
+	blockKey := domain.CacheKeyThrottle(kind, subject, "block")
+	until, err := l.until(ctx, blockKey)
+	if err != nil {
+		return st, err
+	}
+	if d := time.Until(until); d > 0 {
+		return domain.ThrottleState{RetryAfter: d}, nil
+	}
+
+	failsKey := domain.CacheKeyThrottle(kind, subject, "fails")
+	n, err := l.count(ctx, failsKey)
+	if err != nil {
+		return st, err
+	}
+	if kind == kindLogin && l.cfg.LockoutAfter > 0 && n > int64(l.cfg.LockoutAfter) {
+		if err := l.mark(ctx, domain.CacheKeyThrottle(kind, subject, "lock"), l.cfg.LockoutTTL); err != nil {
+			return st, err
+		}
+		// окно счёта начинается заново после снятия блокировки
+		if err := l.kv.Del(ctx, failsKey, blockKey); err != nil {
+			return st, err
+		}
+		return domain.ThrottleState{RetryAfter: l.cfg.LockoutTTL, Locked: true}, nil
+	}
+	d := l.backoff(n, after)
+	if d <= 0 {
+		return st, nil
+	}
+	ok, err := l.kv.SetNX(ctx, blockKey, untilValue(d), ttlSeconds(d))
+	if err != nil {
+		return st, err
+	}
+	if ok {
+		return st, nil
+	}
+	// окно уже открыла параллельная попытка — эта не считается
+	if err := l.refund(ctx, failsKey); err != nil {
+		return st, err
+	}
+	if until, err = l.until(ctx, blockKey); err != nil {
+		return st, err
+	}
+	return domain.ThrottleState{RetryAfter: max(time.Until(until), time.Second)}, nil
+}
+
+// Succeed — учётные данные верны: снимает backoff логина и возвращает IP
+// его попытку, чтобы удачные входы из-за NAT не копились в счётчике.
+func (l *Limiter) Succeed(ctx context.Context, login, ip string) error {
+	if login != "" {
+		if err := l.Reset(ctx, login); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		return l.refund(ctx, domain.CacheKeyThrottle(kindIP, ip, "fails"))
+	}
+	return nil
+}
+
+func (l *Limiter) Reset(ctx context.Context, login string) error {
+	return l.kv.Del(ctx,
+		domain.CacheKeyThrottle(kindLogin, login, "fails"),
+		domain.CacheKeyThrottle(kindLogin, login, "block"),
+		domain.CacheKeyThrottle(kindLogin, login, "lock"),
+	)
+}
+
+// count увеличивает счётчик попыток; окно счёта начинается с первой попытки.
+func (l *Limiter) count(ctx context.Context, key string) (int64, error) {
+	n, err := l.kv.Incr(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		if err := l.kv.Expire(ctx, key, ttlSeconds(l.cfg.LockoutTTL)); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// refund возвращает попытку; счётчик, ушедший в ноль (или истёкший), удаляется.
+func (l *Limiter) refund(ctx context.Context, key string) error {
+	n, err := l.kv.Decr(ctx, key)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return l.kv.Del(ctx, key)
+	}
+	return nil
+}
+
+// backoff: base, 2*base, 4*base ... начиная с after-й неудачи, не больше BackoffMax.
+func (l *Limiter) backoff(n int64, after int) time.Duration {
+	if n < int64(after) {
+		return 0
+	}
+	d := l.cfg.BackoffBase
+	for i := int64(after); i < n && d < l.cfg.BackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, l.cfg.BackoffMax)
+}
+
+func (l *Limiter) mark(ctx context.Context, key string, d time.Duration) error {
+	return l.kv.Set(ctx, key, untilValue(d), ttlSeconds(d))
+}
+
+// untilValue — unix-время окончания окна длиной d, округлённое вверх.
+func untilValue(d time.Duration) []byte {
+	until := time.Now().Add(d + time.Second - 1)
+	return []byte(strconv.FormatInt(until.Unix(), 10))
+}
+
+func (l *Limiter) until(ctx context.Context, key string) (time.Time, error) {
+	b, err := l.kv.Get(ctx, key)
+	if err != nil || len(b) == 0 {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func ttlSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
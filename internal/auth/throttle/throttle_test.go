@@ -0,0 +1,258 @@
+package throttle
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// memKV — кеш в памяти; TTL не истекают, окно «истекает» удалением ключа.
+type memKV struct {
+	mu sync.Mutex
+	m  map[string][]byte
+}
+
+func newMemKV() *memKV { return &memKV{m: map[string][]byte{}} }
+
+func (k *memKV) Get(_ context.Context, key string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.m[key], nil
+}
+
+func (k *memKV) Set(_ context.Context, key string, val []byte, _ int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.m[key] = val
+	return nil
+}
+
+func (k *memKV) SetNX(_ context.Context, key string, val []byte, _ int) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.m[key]; ok {
+		return false, nil
+	}
+	k.m[key] = val
+	return true, nil
+}
+
+func (k *memKV) Del(_ context.Context, keys ...string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, key := range keys {
+		delete(k.m, key)
+	}
+	return nil
+}
+
+func (k *memKV) Incr(ctx context.Context, key string) (int64, error) { return k.add(key, 1) }
+func (k *memKV) Decr(ctx context.Context, key string) (int64, error) { return k.add(key, -1) }
+
+func (k *memKV) add(key string, d int64) (int64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n, _ := strconv.ParseInt(string(k.m[key]), 10, 64)
+	n += d
+	k.m[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+func (k *memKV) Expire(context.Context, string, int) error { return nil }
+
+func (k *memKV) fails(kind, subject string) string {
+	b, _ := k.Get(context.Background(), domain.CacheKeyThrottle(kind, subject, "fails"))
+	return string(b)
+}
+
+func TestBackoff(t *testing.T) {
+	l := New(nil, Config{BackoffBase: time.Second, BackoffMax: 10 * time.Second})
+
+	tests := []struct {
+		name  string
+		n     int64
+		after int
+		want  time.Duration
+	}{
+		{"до порога", 2, 3, 0},
+		{"первая задержка", 3, 3, time.Second},
+		{"удвоение", 4, 3, 2 * time.Second},
+		{"ещё удвоение", 6, 3, 8 * time.Second},
+		{"упёрлись в максимум", 7, 3, 10 * time.Second},
+		{"далеко за максимумом", 1_000, 3, 10 * time.Second},
+		{"порог ip", 19, 20, 0},
+		{"порог ip достигнут", 20, 20, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.backoff(tt.n, tt.after); got != tt.want {
+				t.Errorf("backoff(%d, %d) = %s, want %s", tt.n, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	l := New(nil, Config{})
+	if got := l.backoff(3, l.cfg.BackoffAfter); got != time.Second {
+		t.Errorf("first backoff = %s, want 1s", got)
+	}
+	if got := l.backoff(1_000, l.cfg.BackoffAfter); got != 5*time.Minute {
+		t.Errorf("max backoff = %s, want 5m", got)
+	}
+	if l.cfg.LockoutAfter != 10 {
+		t.Errorf("LockoutAfter = %d, want 10", l.cfg.LockoutAfter)
+	}
+}
+
+func TestAttemptBackoff(t *testing.T) {
+	ctx := context.Background()
+	kv := newMemKV()
+	l := New(kv, Config{BackoffAfter: 3, IPBackoffAfter: 100, BackoffBase: time.Second})
+
+	for i := 1; i <= 3; i++ {
+		if st, err := l.Attempt(ctx, "alice", "10.0.0.1"); err != nil || st.Blocked() {
+			t.Fatalf("attempt %d: %+v, %v", i, st, err)
+		}
+	}
+	// третья попытка открыла окно в 1s — четвёртая ждёт и не считается
+	st, err := l.Attempt(ctx, "alice", "10.0.0.1")
+	if err != nil || !st.Blocked() || st.Locked || st.RetryAfter > 2*time.Second {
+		t.Fatalf("attempt 4: %+v, %v; want backoff ≤ 2s", st, err)
+	}
+	if got := kv.fails(kindLogin, "alice"); got != "3" {
+		t.Errorf("login fails = %s, want 3", got)
+	}
+	if got := kv.fails(kindIP, "10.0.0.1"); got != "3" {
+		t.Errorf("ip fails = %s, want 3", got)
+	}
+
+	// окно истекло — следующая попытка проходит и открывает окно вдвое длиннее
+	_ = kv.Del(ctx, domain.CacheKeyThrottle(kindLogin, "alice", "block"))
+	if st, err := l.Attempt(ctx, "alice", "10.0.0.1"); err != nil || st.Blocked() {
+		t.Fatalf("attempt after window: %+v, %v", st, err)
+	}
+	st, _ = l.Attempt(ctx, "alice", "10.0.0.1")
+	if st.RetryAfter <= time.Second || st.RetryAfter > 3*time.Second {
+		t.Errorf("second window = %s, want ~2s", st.RetryAfter)
+	}
+}
+
+func TestAttemptLockout(t *testing.T) {
+	ctx := context.Background()
+	kv := newMemKV()
+	l := New(kv, Config{BackoffAfter: 100, IPBackoffAfter: 100, LockoutAfter: 3, LockoutTTL: time.Minute})
+
+	for i := 1; i <= 3; i++ {
+		if st, err := l.Attempt(ctx, "alice", "10.0.0.1"); err != nil || st.Blocked() {
+			t.Fatalf("attempt %d: %+v, %v", i, st, err)
+		}
+	}
+	for i := 4; i <= 5; i++ {
+		st, err := l.Attempt(ctx, "alice", "10.0.0.1")
+		if err != nil || !st.Locked || st.RetryAfter <= 0 || st.RetryAfter > time.Minute+time.Second {
+			t.Fatalf("attempt %d: %+v, %v; want lockout", i, st, err)
+		}
+	}
+	// другой логин с того же IP не заблокирован
+	if st, _ := l.Attempt(ctx, "bob", "10.0.0.1"); st.Blocked() {
+		t.Errorf("bob blocked: %+v", st)
+	}
+	// админ снимает блокировку
+	if err := l.Reset(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := l.Attempt(ctx, "alice", "10.0.0.1"); st.Blocked() {
+		t.Errorf("alice blocked after reset: %+v", st)
+	}
+}
+
+// Параллельная пачка попыток не обходит backoff: проходит ровно порог,
+// остальные получают 429 и не увеличивают счётчик.
+func TestAttemptParallel(t *testing.T) {
+	ctx := context.Background()
+	kv := newMemKV()
+	l := New(kv, Config{BackoffAfter: 3, IPBackoffAfter: 100, LockoutAfter: 10})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st, err := l.Attempt(ctx, "alice", "10.0.0.1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !st.Blocked() {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 {
+		t.Errorf("allowed = %d, want 3", allowed)
+	}
+	if got := kv.fails(kindLogin, "alice"); got != "3" {
+		t.Errorf("login fails = %s, want 3", got)
+	}
+}
+
+func TestSucceed(t *testing.T) {
+	ctx := context.Background()
+	kv := newMemKV()
+	l := New(kv, Config{})
+
+	for range 2 {
+		_, _ = l.Attempt(ctx, "alice", "10.0.0.1")
+	}
+	_, _ = l.Attempt(ctx, "bob", "10.0.0.1")
+
+	if err := l.Succeed(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := kv.fails(kindLogin, "alice"); got != "" {
+		t.Errorf("login fails = %q, want reset", got)
+	}
+	// IP возвращает только одну (удачную) попытку
+	if got := kv.fails(kindIP, "10.0.0.1"); got != "2" {
+		t.Errorf("ip fails = %s, want 2", got)
+	}
+	for range 2 {
+		_ = l.Succeed(ctx, "", "10.0.0.1")
+	}
+	if got := kv.fails(kindIP, "10.0.0.1"); got != "" {
+		t.Errorf("ip fails = %q, want deleted", got)
+	}
+	if got := kv.fails(kindLogin, "bob"); got != "1" {
+		t.Errorf("bob fails = %s, want 1", got)
+	}
+}
+
+func TestTTLSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{15 * time.Minute, 900},
+	}
+	for _, tt := range tests {
+		if got := ttlSeconds(tt.d); got != tt.want {
+			t.Errorf("ttlSeconds(%s) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ticket
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// KV — минимальный интерфейс, который нам нужен от кеша.
+type KV interface {
+	Set(ctx context.Context, key string, val []byte, ttlSeconds int) error
+	// GetDel — атомарно: один билет нельзя использовать дважды даже параллельно
+	GetDel(ctx context.Context, key string) ([]byte, error)
+}
+
+// Store держит одноразовые ссылки на скачивание в Redis (ключ — хэш билета).
+type Store struct {
+	kv  KV
+	ttl time.Duration
+}
+
+var _ domain.DownloadTickets = (*Store)(nil)
+
+func NewStore(kv KV, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &Store{kv: kv, ttl: ttl}
+}
+
+func (s *Store) key(raw string) string {
+	return domain.CacheKeyDownloadTicket(hex.EncodeToString(token.HashOpaque(raw)))
+}
+
+func (s *Store) Create(ctx context.Context, t domain.DownloadTicket) (string, time.Time, error) {
+	rnd, _, err := token.NewOpaque()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	raw := domain.DownloadTicketPrefix + rnd
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.kv.Set(ctx, s.key(raw), b, int(s.ttl.Seconds())); err != nil {
+		return "", time.Time{}, err
+	}
+	return raw, time.Now().Add(s.ttl), nil
+}
+
+func (s *Store) Take(ctx context.Context, raw string) (domain.DownloadTicket, error) {
+	b, err := s.kv.GetDel(ctx, s.key(raw))
+	if err != nil {
+		return domain.DownloadTicket{}, err
+	}
+	if len(b) == 0 {
+		return domain.DownloadTicket{}, domain.ErrUnauth
+	}
+	var t domain.DownloadTicket
+	if err := json.Unmarshal(b, &t); err != nil {
+		return domain.DownloadTicket{}, err
+	}
+	return t, nil
+}
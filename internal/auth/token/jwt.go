@@ -2,6 +2,8 @@ package token
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,13 +13,37 @@ import (
 )
 
 type Manager struct {
-	secret []byte
 	issuer string
 	ttl    time.Duration
+
+	active Key            // ключ, которым подписываем новые токены
+	keys   map[string]Key // все ключи для проверки (по kid)
 }
 
+// New — HS256 с общим секретом (обратная совместимость).
 func New(secret string, issuer string, ttl time.Duration) *Manager {
-	return &Manager{secret: []byte(secret), issuer: issuer, ttl: ttl}
+	k := NewHMACKey(LegacyHMACKID, []byte(secret))
+	return &Manager{issuer: issuer, ttl: ttl, active: k, keys: map[string]Key{k.KID: k}}
+}
+
+// NewWithKeys — набор ключей с ротацией: activeKID подписывает, остальные только проверяют.
+func NewWithKeys(issuer string, ttl time.Duration, activeKID string, keys []Key) (*Manager, error) {
+	m := &Manager{issuer: issuer, ttl: ttl, keys: make(map[string]Key, len(keys))}
+	for _, k := range keys {
+		if _, dup := m.keys[k.KID]; dup {
+			return nil, fmt.Errorf("duplicate kid %q", k.KID)
+		}
+		m.keys[k.KID] = k
+	}
+	active, ok := m.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active kid %q not found", activeKID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active kid %q has no private key", activeKID)
+	}
+	m.active = active
+	return m, nil
 }
 
 // внутренний тип для подписи/парсинга с jwt.RegisteredClaims
@@ -25,6 +51,10 @@ type jwtClaims struct {
 	JTI    string    `json:"jti"`
 	UserID uuid.UUID `json:"uid"`
 	Login  string    `json:"login"`
+	Role   string    `json:"role,omitempty"`
+	// Время выпуска в микросекундах: NumericDate в iat отбрасывает доли секунды,
+	// а эпоха токенов сравнивается точнее (новая сессия сразу после Bump)
+	IssuedAtUS int64 `json:"iat_us,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -32,25 +62,28 @@ type jwtClaims struct {
 var _ domain.TokenManager = (*Manager)(nil)
 
 // Issue выпускает JWT и возвращает доменные клеймы
-func (m *Manager) Issue(_ context.Context, userID domain.UserID, login string) (domain.Token, domain.TokenClaims, error) {
-	now := time.Now().UTC()
+func (m *Manager) Issue(_ context.Context, u domain.User) (domain.Token, domain.TokenClaims, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
 	jti := uuid.NewString()
 
 	cl := jwtClaims{
-		JTI:    jti,
-		UserID: userID,
-		Login:  login,
+		JTI:        jti,
+		UserID:     u.ID,
+		Login:      u.Login,
+		Role:       u.Role,
+		IssuedAtUS: now.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.issuer,
-			Subject:   userID.String(),
+			Subject:   u.ID.String(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ID:        jti,
 		},
 	}
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, cl)
-	tokenStr, err := t.SignedString(m.secret)
+	t := jwt.NewWithClaims(m.active.method(), cl)
+	t.Header["kid"] = m.active.KID
+	tokenStr, err := t.SignedString(m.active.signKey)
 	if err != nil {
 		return "", domain.TokenClaims{}, err
 	}
@@ -59,7 +92,8 @@ func (m *Manager) Issue(_ context.Context, userID domain.UserID, login string) (
 		JTI:       cl.JTI,
 		UserID:    cl.UserID,
 		Login:     cl.Login,
-		IssuedAt:  cl.IssuedAt.Time,
+		Role:      cl.Role,
+		IssuedAt:  now,
 		ExpiresAt: cl.ExpiresAt.Time,
 	}, nil
 }
@@ -67,21 +101,67 @@ func (m *Manager) Issue(_ context.Context, userID domain.UserID, login string) (
 // Parse валидирует подпись/сроки и возвращает доменные клеймы
 func (m *Manager) Parse(_ context.Context, raw domain.Token) (domain.TokenClaims, error) {
 	var out jwtClaims
-	tkn, err := jwt.ParseWithClaims(string(raw), &out, func(token *jwt.Token) (any, error) {
-		return m.secret, nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	tkn, err := jwt.ParseWithClaims(string(raw), &out, m.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}))
 	if err != nil {
 		return domain.TokenClaims{}, err
 	}
 	if !tkn.Valid {
 		return domain.TokenClaims{}, jwt.ErrTokenInvalidClaims
 	}
+	var issued time.Time
+	switch {
+	case out.IssuedAtUS != 0:
+		issued = time.UnixMicro(out.IssuedAtUS).UTC()
+	case out.IssuedAt != nil:
+		issued = out.IssuedAt.Time // токены, выпущенные до iat_us
+	}
 
 	return domain.TokenClaims{
 		JTI:       out.JTI,
 		UserID:    out.UserID,
 		Login:     out.Login,
-		IssuedAt:  out.IssuedAt.Time,
+		Role:      out.Role,
+		IssuedAt:  issued,
 		ExpiresAt: out.ExpiresAt.Time,
 	}, nil
 }
+
+// keyFunc выбирает ключ по kid; токены без kid (выпущенные до ротации) — HMAC-ключом.
+// Алгоритм из заголовка обязан совпадать с алгоритмом ключа (защита от alg confusion).
+func (m *Manager) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyHMACKID
+	}
+	k, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errUnknownKID, kid)
+	}
+	if t.Method.Alg() != k.Alg {
+		return nil, fmt.Errorf("kid %q: alg %q does not match key alg %q", kid, t.Method.Alg(), k.Alg)
+	}
+	return k.verifyKey, nil
+}
+
+// JWKS — публичные ключи (RS256/EdDSA) для /.well-known/jwks.json.
+func (m *Manager) JWKS() domain.JWKSet {
+	set := domain.JWKSet{Keys: []domain.JWK{}}
+	// активный ключ первым, дальше — остальные
+	if jwk, ok := m.active.publicJWK(); ok {
+		set.Keys = append(set.Keys, jwk)
+	}
+	kids := make([]string, 0, len(m.keys))
+	for kid := range m.keys {
+		if kid != m.active.KID {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		if jwk, ok := m.keys[kid].publicJWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
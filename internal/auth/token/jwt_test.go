@@ -0,0 +1,55 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func TestIssuedAtMicroseconds(t *testing.T) {
+	m := New("test-secret", "test", time.Minute)
+	raw, issued, err := m.Issue(context.Background(), domain.User{ID: uuid.New(), Login: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.IssuedAt.Nanosecond()%1000 != 0 {
+		t.Errorf("IssuedAt %v not truncated to microseconds", issued.IssuedAt)
+	}
+	parsed, err := m.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.IssuedAt.Equal(issued.IssuedAt) {
+		t.Errorf("parsed IssuedAt = %v, want %v (sub-second precision lost)", parsed.IssuedAt, issued.IssuedAt)
+	}
+}
+
+// Токены, выпущенные до iat_us, разбираются по iat с точностью до секунды.
+func TestIssuedAtLegacyToken(t *testing.T) {
+	m := New("test-secret", "test", time.Minute)
+	iat := time.Now().Truncate(time.Second)
+	cl := jwtClaims{
+		JTI:    uuid.NewString(),
+		UserID: uuid.New(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(iat),
+			ExpiresAt: jwt.NewNumericDate(iat.Add(time.Minute)),
+		},
+	}
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, cl).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := m.Parse(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.IssuedAt.Equal(iat) {
+		t.Errorf("IssuedAt = %v, want %v", parsed.IssuedAt, iat)
+	}
+}
@@ -0,0 +1,150 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// KID для ключа из AUTH_JWT_SECRET (токены без kid в заголовке проверяются им)
+const LegacyHMACKID = "hs256"
+
+// Key — ключ подписи/проверки JWT.
+// signKey == nil — ключ только для проверки (выведен из ротации, но токены ещё живы).
+type Key struct {
+	KID       string
+	Alg       string
+	signKey   any // []byte | *rsa.PrivateKey | ed25519.PrivateKey
+	verifyKey any // []byte | *rsa.PublicKey  | ed25519.PublicKey
+}
+
+func (k Key) CanSign() bool { return k.signKey != nil }
+
+func (k Key) method() jwt.SigningMethod {
+	switch k.Alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func NewHMACKey(kid string, secret []byte) Key {
+	return Key{KID: kid, Alg: AlgHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParsePEMKey разбирает приватный (PKCS#8 / PKCS#1) или публичный (PKIX) ключ RSA/Ed25519.
+// Из публичного ключа получается verify-only ключ.
+func ParsePEMKey(kid string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %q: no PEM block", kid)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %q: %w", kid, err)
+		}
+		switch k := pk.(type) {
+		case *rsa.PrivateKey:
+			return Key{KID: kid, Alg: AlgRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return Key{KID: kid, Alg: AlgEdDSA, signKey: k, verifyKey: k.Public()}, nil
+		default:
+			return Key{}, fmt.Errorf("key %q: unsupported private key type %T", kid, pk)
+		}
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %q: %w", kid, err)
+		}
+		return Key{KID: kid, Alg: AlgRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %q: %w", kid, err)
+		}
+		switch k := pub.(type) {
+		case *rsa.PublicKey:
+			return Key{KID: kid, Alg: AlgRS256, verifyKey: k}, nil
+		case ed25519.PublicKey:
+			return Key{KID: kid, Alg: AlgEdDSA, verifyKey: k}, nil
+		default:
+			return Key{}, fmt.Errorf("key %q: unsupported public key type %T", kid, pub)
+		}
+	default:
+		return Key{}, fmt.Errorf("key %q: unsupported PEM block %q", kid, block.Type)
+	}
+}
+
+// LoadKeysDir читает все *.pem из каталога; kid = имя файла без расширения.
+// Приватные ключи могут подписывать, публичные — только проверять (ротация:
+// старый ключ кладём публичным, пока не истекут подписанные им токены).
+func LoadKeysDir(dir string) ([]Key, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	keys := make([]Key, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		k, err := ParsePEMKey(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// publicJWK — публичная часть ключа в формате JWK. Симметричные ключи не публикуются.
+func (k Key) publicJWK() (domain.JWK, bool) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return domain.JWK{
+			Kty: "RSA", Use: "sig", Alg: k.Alg, Kid: k.KID,
+			N: b64(pub.N.Bytes()),
+			E: b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return domain.JWK{
+			Kty: "OKP", Use: "sig", Alg: k.Alg, Kid: k.KID,
+			Crv: "Ed25519", X: b64(pub),
+		}, true
+	default:
+		return domain.JWK{}, false
+	}
+}
+
+var errUnknownKID = errors.New("unknown kid")
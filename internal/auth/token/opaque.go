@@ -0,0 +1,24 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewOpaque генерирует случайный непрозрачный токен (256 бит, base64url)
+// и его sha256 — в БД храним только хэш.
+func NewOpaque() (raw string, hash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashOpaque(raw), nil
+}
+
+// HashOpaque — хэш для поиска токена в БД.
+func HashOpaque(raw string) []byte {
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:]
+}
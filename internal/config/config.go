@@ -19,6 +19,8 @@ type Config struct {
 	DBName     string `mapstructure:"DB_NAME"`
 	DBScheme   string `mapstructure:"DB_SCHEME"`
 	AppPort    string `mapstructure:"APP_PORT"`
+	// За доверенным reverse proxy: IP клиента берём из X-Forwarded-For / X-Real-IP
+	HTTPTrustProxy bool `mapstructure:"HTTP_TRUST_PROXY"`
 
 	// --- S3 ---
 	S3Endpoint  string `mapstructure:"S3_ENDPOINT"`
@@ -30,15 +32,72 @@ type Config struct {
 	S3PathStyle bool   `mapstructure:"S3_PATH_STYLE"`
 
 	// --- Redis ---
-	RedisAddr     string `mapstructure:"REDIS_ADDR"`
-	RedisDB       int    `mapstructure:"REDIS_DB"`
-	RedisPassword string `mapstructure:"REDIS_PASSWORD"`
+	RedisMode                  string   `mapstructure:"REDIS_MODE"` // single | sentinel | cluster
+	RedisAddr                  string   `mapstructure:"REDIS_ADDR"`
+	RedisAddrs                 []string `mapstructure:"REDIS_ADDRS"` // через запятую: sentinel-ы или узлы кластера
+	RedisMasterName            string   `mapstructure:"REDIS_MASTER_NAME"`
+	RedisDB                    int      `mapstructure:"REDIS_DB"`
+	RedisUsername              string   `mapstructure:"REDIS_USERNAME"` // ACL-пользователь
+	RedisPassword              string   `mapstructure:"REDIS_PASSWORD"`
+	RedisSentinelUsername      string   `mapstructure:"REDIS_SENTINEL_USERNAME"`
+	RedisSentinelPassword      string   `mapstructure:"REDIS_SENTINEL_PASSWORD"`
+	RedisTLS                   bool     `mapstructure:"REDIS_TLS"`
+	RedisTLSServerName         string   `mapstructure:"REDIS_TLS_SERVER_NAME"`
+	RedisTLSInsecureSkipVerify bool     `mapstructure:"REDIS_TLS_INSECURE_SKIP_VERIFY"`
+	RedisPoolSize              int      `mapstructure:"REDIS_POOL_SIZE"`
+	RedisMinIdleConns          int      `mapstructure:"REDIS_MIN_IDLE_CONNS"`
 
 	// --- Auth ---
-	AdminToken    string        `mapstructure:"ADMIN_TOKEN"`
-	AuthJWTSecret string        `mapstructure:"AUTH_JWT_SECRET"`
-	AuthTokenTTL  time.Duration `mapstructure:"AUTH_TOKEN_TTL"` // напр. "15m", "24h"
-	AuthIssuer    string        `mapstructure:"AUTH_ISSUER"`    // напр. "my-docs"
+	AdminToken     string        `mapstructure:"ADMIN_TOKEN"`
+	AuthJWTSecret  string        `mapstructure:"AUTH_JWT_SECRET"`
+	AuthTokenTTL   time.Duration `mapstructure:"AUTH_TOKEN_TTL"`   // напр. "15m", "24h"
+	AuthRefreshTTL time.Duration `mapstructure:"AUTH_REFRESH_TTL"` // напр. "720h"
+	AuthIssuer     string        `mapstructure:"AUTH_ISSUER"`      // напр. "my-docs"
+	// Асимметричная подпись: каталог с <kid>.pem (приватные — подписывают, публичные — только проверяют)
+	AuthJWTKeysDir   string `mapstructure:"AUTH_JWT_KEYS_DIR"`
+	AuthJWTActiveKID string `mapstructure:"AUTH_JWT_ACTIVE_KID"`
+	// Параметры argon2id (0 — значения по умолчанию библиотеки). Хэши со слабыми
+	// параметрами пересчитываются при следующем успешном входе.
+	AuthArgon2Memory      uint32 `mapstructure:"AUTH_ARGON2_MEMORY"` // KiB
+	AuthArgon2Iterations  uint32 `mapstructure:"AUTH_ARGON2_ITERATIONS"`
+	AuthArgon2Parallelism uint8  `mapstructure:"AUTH_ARGON2_PARALLELISM"`
+	AuthArgon2SaltLength  uint32 `mapstructure:"AUTH_ARGON2_SALT_LENGTH"`
+	AuthArgon2KeyLength   uint32 `mapstructure:"AUTH_ARGON2_KEY_LENGTH"`
+	// TOTP 2FA: ключ шифрования секретов (base64, 32 байта; пусто — 2FA недоступна)
+	AuthMFAKey        string        `mapstructure:"AUTH_MFA_KEY"`
+	AuthMFAPendingTTL time.Duration `mapstructure:"AUTH_MFA_PENDING_TTL"` // срок mfa_token, напр. "5m"
+	// Браузерные сессии в HttpOnly cookie (включаются клиентом: X-Session-Mode: cookie)
+	AuthCookies        bool   `mapstructure:"AUTH_COOKIES"`
+	AuthCookieDomain   string `mapstructure:"AUTH_COOKIE_DOMAIN"`
+	AuthCookieSameSite string `mapstructure:"AUTH_COOKIE_SAMESITE"` // lax (по умолчанию) | strict | none
+	AuthCookieInsecure bool   `mapstructure:"AUTH_COOKIE_INSECURE"` // без Secure — только для локального http
+	AuthCSRFKey        string `mapstructure:"AUTH_CSRF_KEY"`        // пусто — случайный на процесс (одна реплика)
+	// ?token= в URL: any (по умолчанию) | download (только одноразовые ссылки) | off
+	AuthQueryToken      string        `mapstructure:"AUTH_QUERY_TOKEN"`
+	AuthDownloadLinkTTL time.Duration `mapstructure:"AUTH_DOWNLOAD_LINK_TTL"` // напр. "1m"
+	// OpenID Connect (пусто OIDC_ISSUER — вход через провайдера выключен)
+	OIDCIssuer        string        `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID      string        `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret  string        `mapstructure:"OIDC_CLIENT_SECRET"` // пусто — публичный клиент (только PKCE)
+	OIDCRedirectURL   string        `mapstructure:"OIDC_REDIRECT_URL"`  // .../api/auth/oidc/callback
+	OIDCScopes        []string      `mapstructure:"OIDC_SCOPES"`        // через запятую; по умолчанию openid,email,profile
+	OIDCAutoProvision bool          `mapstructure:"OIDC_AUTO_PROVISION"`
+	OIDCStateTTL      time.Duration `mapstructure:"OIDC_STATE_TTL"` // срок state между редиректом и callback, напр. "10m"
+	// Приглашения: разрешить обычным пользователям выпускать инвайты (только с ролью user)
+	InvitesAllowUsers bool `mapstructure:"INVITES_ALLOW_USERS"`
+	// Ревизии контента: сколько последних хранить на документ (0 — без ограничения)
+	DocVersionsKeep int `mapstructure:"DOC_VERSIONS_KEEP"`
+	// Корзина: через сколько удалённый документ удаляется окончательно (0 — только вручную)
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`      // напр. "720h"
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"` // период фоновой чистки, напр. "1h"
+
+	// --- Защита от перебора (login/register) ---
+	ThrottleBackoffAfter   int           `mapstructure:"AUTH_THROTTLE_BACKOFF_AFTER"`    // неудач по логину до backoff
+	ThrottleIPBackoffAfter int           `mapstructure:"AUTH_THROTTLE_IP_BACKOFF_AFTER"` // неудач с IP до backoff
+	ThrottleBackoffBase    time.Duration `mapstructure:"AUTH_THROTTLE_BACKOFF_BASE"`     // напр. "1s", удваивается
+	ThrottleBackoffMax     time.Duration `mapstructure:"AUTH_THROTTLE_BACKOFF_MAX"`      // напр. "5m"
+	ThrottleLockoutAfter   int           `mapstructure:"AUTH_THROTTLE_LOCKOUT_AFTER"`    // по умолчанию 10
+	ThrottleLockoutTTL     time.Duration `mapstructure:"AUTH_THROTTLE_LOCKOUT_TTL"`      // напр. "15m"
 }
 
 // String реализует интерфейс Stringer
@@ -51,6 +110,7 @@ func (c *Config) String() string {
 	sb.WriteString(fmt.Sprintf("  DBName: %s\n", c.DBName))
 	sb.WriteString(fmt.Sprintf("  DBScheme: %s\n", c.DBScheme))
 	sb.WriteString(fmt.Sprintf("  AppPort: %s\n", c.AppPort))
+	sb.WriteString(fmt.Sprintf("  HTTPTrustProxy: %t\n", c.HTTPTrustProxy))
 
 	// пароль маскируем
 	if c.DBPassword != "" {
@@ -77,13 +137,25 @@ func (c *Config) String() string {
 	sb.WriteString(fmt.Sprintf("  S3PathStyle: %v\n", c.S3PathStyle))
 
 	// Redis
+	sb.WriteString(fmt.Sprintf("  RedisMode: %s\n", c.RedisMode))
 	sb.WriteString(fmt.Sprintf("  RedisAddr: %s\n", c.RedisAddr))
+	sb.WriteString(fmt.Sprintf("  RedisAddrs: %v\n", c.RedisAddrs))
+	sb.WriteString(fmt.Sprintf("  RedisMasterName: %s\n", c.RedisMasterName))
 	sb.WriteString(fmt.Sprintf("  RedisDB: %d\n", c.RedisDB))
+	sb.WriteString(fmt.Sprintf("  RedisUsername: %s\n", c.RedisUsername))
 	if c.RedisPassword != "" {
 		sb.WriteString("  RedisPass: ********\n")
 	} else {
 		sb.WriteString("  RedisPass: (empty)\n")
 	}
+	if c.RedisSentinelPassword != "" {
+		sb.WriteString("  RedisSentinelPass: ********\n")
+	} else {
+		sb.WriteString("  RedisSentinelPass: (empty)\n")
+	}
+	sb.WriteString(fmt.Sprintf("  RedisTLS: %v\n", c.RedisTLS))
+	sb.WriteString(fmt.Sprintf("  RedisPoolSize: %d\n", c.RedisPoolSize))
+	sb.WriteString(fmt.Sprintf("  RedisMinIdleConns: %d\n", c.RedisMinIdleConns))
 
 	// Auth
 	sb.WriteString(fmt.Sprintf("  AuthIssuer: %s\n", c.AuthIssuer))
@@ -92,8 +164,39 @@ func (c *Config) String() string {
 	} else {
 		sb.WriteString("  AuthJWTSecret: (empty)\n")
 	}
+	sb.WriteString(fmt.Sprintf("  AuthJWTKeysDir: %s\n", c.AuthJWTKeysDir))
+	sb.WriteString(fmt.Sprintf("  AuthJWTActiveKID: %s\n", c.AuthJWTActiveKID))
 	sb.WriteString(fmt.Sprintf("  AuthTokenTTL: %s\n", c.AuthTokenTTL))
+	sb.WriteString(fmt.Sprintf("  AuthRefreshTTL: %s\n", c.AuthRefreshTTL))
+	sb.WriteString(fmt.Sprintf("  AuthArgon2: m=%d t=%d p=%d salt=%d key=%d\n", c.AuthArgon2Memory,
+		c.AuthArgon2Iterations, c.AuthArgon2Parallelism, c.AuthArgon2SaltLength, c.AuthArgon2KeyLength))
+	sb.WriteString(fmt.Sprintf("  AuthMFAKey: %s\n", mask(c.AuthMFAKey)))
+	sb.WriteString(fmt.Sprintf("  AuthMFAPendingTTL: %s\n", c.AuthMFAPendingTTL))
 	sb.WriteString(fmt.Sprintf("  AdminToken: %s\n", mask(c.AdminToken)))
+	sb.WriteString(fmt.Sprintf("  AuthCookies: %t\n", c.AuthCookies))
+	sb.WriteString(fmt.Sprintf("  AuthCookieDomain: %s\n", c.AuthCookieDomain))
+	sb.WriteString(fmt.Sprintf("  AuthCookieSameSite: %s\n", c.AuthCookieSameSite))
+	sb.WriteString(fmt.Sprintf("  AuthCookieInsecure: %t\n", c.AuthCookieInsecure))
+	sb.WriteString(fmt.Sprintf("  AuthCSRFKey: %s\n", mask(c.AuthCSRFKey)))
+	sb.WriteString(fmt.Sprintf("  AuthQueryToken: %s\n", c.AuthQueryToken))
+	sb.WriteString(fmt.Sprintf("  AuthDownloadLinkTTL: %s\n", c.AuthDownloadLinkTTL))
+	sb.WriteString(fmt.Sprintf("  InvitesAllowUsers: %t\n", c.InvitesAllowUsers))
+	sb.WriteString(fmt.Sprintf("  DocVersionsKeep: %d\n", c.DocVersionsKeep))
+	sb.WriteString(fmt.Sprintf("  TrashRetention: %s\n", c.TrashRetention))
+	sb.WriteString(fmt.Sprintf("  TrashPurgeInterval: %s\n", c.TrashPurgeInterval))
+	sb.WriteString(fmt.Sprintf("  OIDCIssuer: %s\n", c.OIDCIssuer))
+	sb.WriteString(fmt.Sprintf("  OIDCClientID: %s\n", c.OIDCClientID))
+	sb.WriteString(fmt.Sprintf("  OIDCClientSecret: %s\n", mask(c.OIDCClientSecret)))
+	sb.WriteString(fmt.Sprintf("  OIDCRedirectURL: %s\n", c.OIDCRedirectURL))
+	sb.WriteString(fmt.Sprintf("  OIDCScopes: %v\n", c.OIDCScopes))
+	sb.WriteString(fmt.Sprintf("  OIDCAutoProvision: %t\n", c.OIDCAutoProvision))
+	sb.WriteString(fmt.Sprintf("  OIDCStateTTL: %s\n", c.OIDCStateTTL))
+	sb.WriteString(fmt.Sprintf("  ThrottleBackoffAfter: %d\n", c.ThrottleBackoffAfter))
+	sb.WriteString(fmt.Sprintf("  ThrottleIPBackoffAfter: %d\n", c.ThrottleIPBackoffAfter))
+	sb.WriteString(fmt.Sprintf("  ThrottleBackoffBase: %s\n", c.ThrottleBackoffBase))
+	sb.WriteString(fmt.Sprintf("  ThrottleBackoffMax: %s\n", c.ThrottleBackoffMax))
+	sb.WriteString(fmt.Sprintf("  ThrottleLockoutAfter: %d\n", c.ThrottleLockoutAfter))
+	sb.WriteString(fmt.Sprintf("  ThrottleLockoutTTL: %s\n", c.ThrottleLockoutTTL))
 
 	return sb.String()
 }
@@ -112,11 +215,25 @@ func LoadFromEnv() (*Config, error) {
 
 	// Регистрируем интересующие ключи окружения
 	keys := []string{
-		"APP_ENV", "APP_PORT",
+		"APP_ENV", "APP_PORT", "HTTP_TRUST_PROXY",
 		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SCHEME",
 		"S3_ENDPOINT", "S3_REGION", "S3_BUCKET", "S3_ACCESS_KEY", "S3_SECRET_KEY",
-		"S3_USE_SSL", "S3_PATH_STYLE", "REDIS_ADDR", "REDIS_DB", "REDIS_PASSWORD",
-		"ADMIN_TOKEN", "AUTH_JWT_SECRET", "AUTH_TOKEN_TTL", "AUTH_ISSUER",
+		"S3_USE_SSL", "S3_PATH_STYLE",
+		"REDIS_MODE", "REDIS_ADDR", "REDIS_ADDRS", "REDIS_MASTER_NAME", "REDIS_DB",
+		"REDIS_USERNAME", "REDIS_PASSWORD", "REDIS_SENTINEL_USERNAME", "REDIS_SENTINEL_PASSWORD",
+		"REDIS_TLS", "REDIS_TLS_SERVER_NAME", "REDIS_TLS_INSECURE_SKIP_VERIFY",
+		"REDIS_POOL_SIZE", "REDIS_MIN_IDLE_CONNS",
+		"ADMIN_TOKEN", "AUTH_JWT_SECRET", "AUTH_TOKEN_TTL", "AUTH_REFRESH_TTL", "AUTH_ISSUER",
+		"AUTH_JWT_KEYS_DIR", "AUTH_JWT_ACTIVE_KID", "AUTH_MFA_KEY", "AUTH_MFA_PENDING_TTL",
+		"AUTH_COOKIES", "AUTH_COOKIE_DOMAIN", "AUTH_COOKIE_SAMESITE", "AUTH_COOKIE_INSECURE", "AUTH_CSRF_KEY",
+		"AUTH_QUERY_TOKEN", "AUTH_DOWNLOAD_LINK_TTL",
+		"INVITES_ALLOW_USERS", "DOC_VERSIONS_KEEP", "TRASH_RETENTION", "TRASH_PURGE_INTERVAL",
+		"OIDC_ISSUER", "OIDC_CLIENT_ID", "OIDC_CLIENT_SECRET", "OIDC_REDIRECT_URL", "OIDC_SCOPES",
+		"OIDC_AUTO_PROVISION", "OIDC_STATE_TTL",
+		"AUTH_ARGON2_MEMORY", "AUTH_ARGON2_ITERATIONS", "AUTH_ARGON2_PARALLELISM",
+		"AUTH_ARGON2_SALT_LENGTH", "AUTH_ARGON2_KEY_LENGTH",
+		"AUTH_THROTTLE_BACKOFF_AFTER", "AUTH_THROTTLE_IP_BACKOFF_AFTER", "AUTH_THROTTLE_BACKOFF_BASE",
+		"AUTH_THROTTLE_BACKOFF_MAX", "AUTH_THROTTLE_LOCKOUT_AFTER", "AUTH_THROTTLE_LOCKOUT_TTL",
 	}
 	for _, k := range keys {
 		_ = v.BindEnv(k)
@@ -4,6 +4,16 @@ package domain
 type APIError struct {
 	Code int    `json:"code,omitempty"`
 	Text string `json:"text,omitempty"`
+	// Подробности (например, нарушения JSON Schema) — по одной на место в JSON
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// Одна ошибка проверки: Path — JSON Pointer в проверяемом JSON,
+// Keyword — место в схеме, где сработало правило.
+type ErrorDetail struct {
+	Path    string `json:"path"`
+	Keyword string `json:"keyword,omitempty"`
+	Message string `json:"message"`
 }
 
 type APIEnvelope struct {
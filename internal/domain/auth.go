@@ -3,6 +3,8 @@ package domain
 import (
 	"context"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Токен и клеймы
@@ -12,22 +14,274 @@ type TokenClaims struct {
 	JTI       string
 	UserID    UserID
 	Login     string
+	Role      Role
 	IssuedAt  time.Time
 	ExpiresAt time.Time
 }
 
+// Refresh-токен (opaque). В БД хранится только sha256 от сырого значения.
+// Все токены, полученные ротацией из одного логина, образуют семейство (FamilyID).
+type RefreshToken struct {
+	ID              uuid.UUID
+	UserID          UserID
+	FamilyID        uuid.UUID
+	TokenHash       []byte
+	AccessJTI       string // access-токен, выданный в паре с этим refresh
+	AccessExpiresAt time.Time
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+	RotatedAt       *time.Time // != nil — токен уже обменян (повторное использование = reuse)
+	ReplacedBy      *uuid.UUID
+	RevokedAt       *time.Time
+}
+
+// Скоупы персональных токенов (PAT). JWT-сессия имеет все скоупы.
+const (
+	ScopeDocsRead  = "docs:read"
+	ScopeDocsWrite = "docs:write"
+	ScopeDocsShare = "docs:share"
+)
+
+var AllScopes = []string{ScopeDocsRead, ScopeDocsWrite, ScopeDocsShare}
+
+func ValidScope(s string) bool {
+	for _, sc := range AllScopes {
+		if s == sc {
+			return true
+		}
+	}
+	return false
+}
+
+// Префикс сырых PAT — по нему middleware отличает их от JWT.
+const PersonalTokenPrefix = "mdp_"
+
+// Персональный токен доступа для автоматизации (CI, скрипты).
+// Сырое значение показывается один раз при создании, в БД — sha256.
+type PersonalToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     UserID     `json:"-"`
+	Login      string     `json:"-"` // логин владельца (для контекста запроса)
+	Role       Role       `json:"-"` // роль владельца
+	Name       string     `json:"name"`
+	TokenHash  []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Префикс кодов приглашения
+const InviteCodePrefix = "mdi_"
+
+// Приглашение на регистрацию: одно- или многоразовое, с ролью по умолчанию.
+type Invite struct {
+	ID        uuid.UUID  `json:"id"`
+	CodeHash  []byte     `json:"-"`
+	CreatedBy *UserID    `json:"created_by,omitempty"`
+	Role      Role       `json:"role"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Сессия пользователя (один логин). ID = семейство refresh-токенов,
+// CurrentJTI — последний выданный в ней access-токен.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     UserID     `json:"-"`
+	CurrentJTI string     `json:"jti"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"issued_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Состояние троттлинга субъекта (логин/IP)
+type ThrottleState struct {
+	RetryAfter time.Duration // 0 — можно пробовать
+	Locked     bool          // аккаунт заблокирован (а не просто backoff)
+}
+
+func (s ThrottleState) Blocked() bool { return s.RetryAfter > 0 }
+
+// Err — доменная ошибка для ответа клиенту
+func (s ThrottleState) Err() error {
+	if s.Locked {
+		return ErrAccountLocked
+	}
+	return ErrTooManyAttempts
+}
+
+// Троттлинг попыток входа. login == "" — только по IP (напр. подбор admin-токена).
+type LoginThrottle interface {
+	// Attempt учитывает попытку до проверки учётных данных; Blocked() — отказать.
+	Attempt(ctx context.Context, login, ip string) (ThrottleState, error)
+	// Succeed — учётные данные верны: попытка не считается неудачей.
+	Succeed(ctx context.Context, login, ip string) error
+	// Reset снимает backoff и блокировку логина (админ).
+	Reset(ctx context.Context, login string) error
+}
+
+// Причины в аудите попыток входа
+const (
+	AttemptOK           = "ok"
+	AttemptUnknownLogin = "unknown_login"
+	AttemptBadPassword  = "bad_password"
+	AttemptThrottled    = "throttled"
+	AttemptLocked       = "locked"
+	AttemptMFAPending   = "mfa_pending" // пароль верен, ждём второй фактор
+	AttemptBadMFACode   = "bad_mfa_code"
+	AttemptDisabled     = "disabled"
+	AttemptOIDC         = "oidc"             // вход через OpenID Connect
+	AttemptOIDCUnknown  = "oidc_unknown_sub" // внешняя учётка не привязана, авто-создание выключено
+)
+
+// Запись аудита попытки входа
+type LoginAttempt struct {
+	ID        int64      `json:"id"`
+	Login     string     `json:"login"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	Success   bool       `json:"success"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Состояние TOTP пользователя. Secret — зашифрованный (см. SecretBox).
+type TOTPState struct {
+	Secret   []byte
+	Enabled  bool
+	LastStep int64 // последний принятый 30-секундный шаг (коды не принимаются повторно)
+}
+
+// Шифрование секретов at rest
+type SecretBox interface {
+	Seal(plain []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+// Короткоживущие "mfa pending" токены: пароль уже проверен, ждём второй фактор.
+type MFAPendingStore interface {
+	Create(ctx context.Context, userID UserID) (token string, expiresAt time.Time, err error)
+	// Lookup возвращает пользователя; ErrUnauth — токен неизвестен или истёк.
+	Lookup(ctx context.Context, token string) (UserID, error)
+	Delete(ctx context.Context, token string) error
+}
+
+// Префикс одноразовых ссылок на скачивание (?token=mdt_...)
+const DownloadTicketPrefix = "mdt_"
+
+// Одноразовый билет на GET одного пути: заменяет долгоживущий токен в query string.
+type DownloadTicket struct {
+	UserID UserID `json:"uid"`
+	Login  string `json:"login"`
+	Role   Role   `json:"role"`
+	Path   string `json:"path"`
+}
+
+type DownloadTickets interface {
+	Create(ctx context.Context, t DownloadTicket) (raw string, expiresAt time.Time, err error)
+	// Take одноразово достаёт билет; ErrUnauth — неизвестен, истёк или уже использован.
+	Take(ctx context.Context, raw string) (DownloadTicket, error)
+}
+
+// Внешняя учётная запись (OpenID Connect): пара issuer + sub уникальна.
+type ExternalIdentity struct {
+	Issuer  string
+	Subject string
+	Email   string
+}
+
+// Проверенные клеймы ID-токена OIDC
+type OIDCClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Nonce             string
+}
+
+// Провайдер OIDC: authorization code + PKCE.
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange меняет code на токены и проверяет ID-токен (подпись по JWKS, iss, aud, exp).
+	// Nonce сверяет вызывающий.
+	Exchange(ctx context.Context, code, codeVerifier string) (OIDCClaims, error)
+}
+
+// Состояние незавершённого OIDC-входа (между редиректом на IdP и callback).
+type OIDCFlow struct {
+	Nonce        string  `json:"nonce"`
+	CodeVerifier string  `json:"code_verifier"`
+	LinkUserID   *UserID `json:"link_user_id,omitempty"` // привязка к уже вошедшему пользователю
+	Cookie       bool    `json:"cookie,omitempty"`       // выдать сессию в cookie
+}
+
+type OIDCStateStore interface {
+	Save(ctx context.Context, state string, f OIDCFlow) error
+	// Take одноразово достаёт состояние; ErrUnauth — неизвестно или истекло.
+	Take(ctx context.Context, state string) (OIDCFlow, error)
+}
+
 // Hash/Verify — строковые (argon2id)
 type PasswordHasher interface {
 	Hash(plain string) (string, error)
 	Verify(plain, encodedHash string) (bool, error)
+	// NeedsRehash — хэш сделан с параметрами слабее текущих
+	NeedsRehash(encodedHash string) bool
 }
 
 // JWT/PASETO менеджер — TTL конфигурируется при создании менеджера
 type TokenManager interface {
-	Issue(ctx context.Context, userID UserID, login string) (Token, TokenClaims, error)
+	Issue(ctx context.Context, u User) (Token, TokenClaims, error)
 	Parse(ctx context.Context, raw Token) (TokenClaims, error)
 }
 
+// Публичный ключ в формате JWK (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Источник публичных ключей для /.well-known/jwks.json
+type KeySetProvider interface {
+	JWKS() JWKSet
+}
+
+// Эпоха токенов пользователя: все JWT, выданные раньше эпохи, недействительны
+// ("выйти со всех устройств" без записи в блэклист на каждый токен).
+type TokenEpochs interface {
+	Bump(ctx context.Context, userID UserID) (time.Time, error)
+	Epoch(ctx context.Context, userID UserID) (time.Time, error) // zero — эпохи нет
+}
+
+// Отключён ли пользователь (users.disabled_at) — страховка эпохи токенов,
+// ключ которой в кеше может пропасть. Удалённый пользователь — отключён.
+type DisabledUsers interface {
+	IsDisabled(ctx context.Context, userID UserID) (bool, error)
+}
+
 // Блэклист (logout)
 type TokenBlacklist interface {
 	Revoke(ctx context.Context, jti string, exp time.Time) error
@@ -3,10 +3,26 @@ package domain
 import "context"
 
 // Ключи кеша — единое место, чтобы не расползались по коду.
-func CacheKeyDocMeta(id DocID) string                    { return "docmeta:" + id.String() }
-func CacheKeyDocJSON(id DocID) string                    { return "docjson:" + id.String() }
-func CacheKeyDocList(user string, pageKey string) string { return "list:" + user + ":" + pageKey } // pageKey = хэш фильтров/сортировки
-func CacheKeyTokenJTI(jti string) string                 { return "jti:" + jti }
+//
+// Часть ключа в {фигурных скобках} — hash tag Redis Cluster: слот считается
+// только по ней. Ключи одного документа (docmeta/docjson) и все страницы списка
+// одного пользователя попадают в один слот, поэтому multi-key DEL по ним
+// работает и в кластере.
+func CacheKeyDocMeta(id DocID) string                    { return "docmeta:{" + id.String() + "}" }
+func CacheKeyDocJSON(id DocID) string                    { return "docjson:{" + id.String() + "}" }
+func CacheKeyDocList(user string, pageKey string) string { return "list:{" + user + "}:" + pageKey } // pageKey = хэш фильтров/сортировки
+func CacheKeyDocListVer(user string) string              { return "listver:{" + user + "}" }         // версия списков пользователя (INCR = инвалидация)
+func CacheKeyTokenJTI(jti string) string                 { return "jti:" + jti }                     // прежний формат: иначе уже отозванные токены снова пройдут
+func CacheKeyTokenEpoch(user string) string              { return "epoch:{" + user + "}" }
+func CacheKeyMFAPending(tokenHash string) string         { return "mfa:{" + tokenHash + "}" }
+func CacheKeyOIDCState(stateHash string) string          { return "oidc:{" + stateHash + "}" }
+func CacheKeyDownloadTicket(hash string) string          { return "dl:{" + hash + "}" }
+func CacheKeyShareResume(linkID, client string) string   { return "resume:{" + linkID + "}:" + client } // client = хэш IP и User-Agent
+
+// Ключи троттлинга: kind = "login" | "ip". fails/block/lock одного субъекта — в одном слоте.
+func CacheKeyThrottle(kind, subject, part string) string {
+	return "throttle:{" + kind + ":" + subject + "}:" + part
+}
 
 // Простой k/v интерфейс. Реализация — Redis.
 type Cache interface {
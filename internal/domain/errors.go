@@ -1,16 +1,27 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Бизнес-ошибки (маппятся на HTTP коды по правилам из ТЗ)
 var (
-	ErrBadParams        = errors.New("bad_params")         // 400
-	ErrUnauth           = errors.New("unauthorized")       // 401
-	ErrForbidden        = errors.New("forbidden")          // 403
-	ErrNotFound         = errors.New("not_found")          // 404 (в ТЗ нет, но удобно внутри; наружу всё равно 200 с error?)
-	ErrMethodNotAllowed = errors.New("method_not_allowed") // 405
-	ErrNotImplemented   = errors.New("not_implemented")    // 501
-	ErrUnexpected       = errors.New("unexpected")         // 500
+	ErrBadParams        = errors.New("bad_params")            // 400
+	ErrUnauth           = errors.New("unauthorized")          // 401
+	ErrForbidden        = errors.New("forbidden")             // 403
+	ErrNotFound         = errors.New("not_found")             // 404 (в ТЗ нет, но удобно внутри; наружу всё равно 200 с error?)
+	ErrMethodNotAllowed = errors.New("method_not_allowed")    // 405
+	ErrConflict         = errors.New("conflict")              // 409: имя занято / состояние не позволяет
+	ErrPrecondition     = errors.New("precondition_failed")   // 412: If-Match не совпал с текущим ETag
+	ErrPreconditionReq  = errors.New("precondition_required") // 428: нужен If-Match
+	ErrNotImplemented   = errors.New("not_implemented")       // 501
+	ErrUnexpected       = errors.New("unexpected")            // 500
+	ErrTokenReused      = errors.New("token_reused")          // 401: повторное использование обменянного refresh-токена
+	ErrTooManyAttempts  = errors.New("too_many_attempts")     // 429: backoff после неудачных попыток
+	ErrAccountLocked    = errors.New("account_locked")        // 429: временная блокировка аккаунта
+	ErrAccountDisabled  = errors.New("account_disabled")      // 403: аккаунт отключён администратором
+	ErrSchemaViolation  = errors.New("schema_violation")      // 422: JSON-тело не соответствует схеме документа
 )
 
 // Числовые error.code в конверте (произвольно, но стабильны)
@@ -18,8 +29,32 @@ const (
 	ErrCodeBadParams        = 1000
 	ErrCodeUnauth           = 1001
 	ErrCodeForbidden        = 1003
+	ErrCodeAccountDisabled  = 1010
 	ErrCodeNotFound         = 1004
 	ErrCodeMethodNotAllowed = 1005
+	ErrCodeConflict         = 1009
+	ErrCodePrecondition     = 1412
+	ErrCodeSchemaViolation  = 1422
+	ErrCodeAccountLocked    = 1423
+	ErrCodePreconditionReq  = 1428
+	ErrCodeTooManyAttempts  = 1429
 	ErrCodeUnexpected       = 1500
 	ErrCodeNotImplemented   = 1501
 )
+
+// ValidationError — ошибка с подробностями для конверта; errors.Is видит Base
+// (ErrBadParams для некорректной схемы, ErrSchemaViolation для тела документа).
+type ValidationError struct {
+	Base    error
+	Details []ErrorDetail
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Details) == 0 {
+		return e.Base.Error()
+	}
+	d := e.Details[0]
+	return fmt.Sprintf("%v: %s: %s (+%d more)", e.Base, d.Path, d.Message, len(e.Details)-1)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Base }
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения запросов по JSON-телу документа
+const (
+	MaxJSONPathDepth = 16
+	MaxJSONFilters   = 10
+	MaxJSONFields    = 50
+	maxJSONPathKey   = 256
+	maxJSONPathIndex = 1_000_000
+)
+
+// JSONPath — безопасное подмножество JSONPath: $.a.b, $["ключ с пробелом"], $.items[0]
+// ("$" в начале можно не писать). Без *, срезов, фильтров и рекурсивного спуска —
+// путь указывает ровно на одно значение.
+type JSONPath []PathSeg
+
+// Сегмент пути: ключ объекта или (Elem) индекс массива.
+type PathSeg struct {
+	Key   string
+	Index int
+	Elem  bool
+}
+
+// Операции фильтра по JSON-телу (GET /api/docs?json.*)
+type JSONFilterOp string
+
+const (
+	JSONContains JSONFilterOp = "contains" // тело содержит объект (@>)
+	JSONEq       JSONFilterOp = "eq"       // значение по пути равно скаляру
+	JSONExists   JSONFilterOp = "exists"   // путь есть в теле
+)
+
+type JSONFilter struct {
+	Op    JSONFilterOp
+	Path  JSONPath        // eq, exists
+	Value json.RawMessage // contains — объект, eq — скаляр
+}
+
+// ParseJSONPath: строка целиком — путь.
+func ParseJSONPath(s string) (JSONPath, bool) {
+	p, rest, ok := CutJSONPath(s)
+	return p, ok && rest == ""
+}
+
+// CutJSONPath разбирает путь в начале s и возвращает остаток строки
+// (для записи вида path=value и списков через запятую).
+func CutJSONPath(s string) (JSONPath, string, bool) {
+	var p JSONPath
+	switch {
+	case strings.HasPrefix(s, "$"):
+		s = s[1:]
+	case s != "" && s[0] != '[':
+		// без "$" первый ключ пишется без точки
+		key, rest := cutIdent(s)
+		if key == "" {
+			return nil, s, false
+		}
+		p, s = append(p, PathSeg{Key: key}), rest
+	}
+	for s != "" && (s[0] == '.' || s[0] == '[') {
+		var seg PathSeg
+		var ok bool
+		if s[0] == '.' {
+			seg.Key, s = cutIdent(s[1:])
+			ok = seg.Key != ""
+		} else {
+			seg, s, ok = cutBracket(s[1:])
+		}
+		if !ok || len(seg.Key) > maxJSONPathKey {
+			return nil, s, false
+		}
+		p = append(p, seg)
+	}
+	if len(p) == 0 || len(p) > MaxJSONPathDepth {
+		return nil, s, false
+	}
+	return p, s, true
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func cutIdent(s string) (string, string) {
+	i := 0
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if !isIdentRune(r) {
+			break
+		}
+		i += n
+	}
+	return s[:i], s[i:]
+}
+
+// cutBracket: ["ключ"] (строка JSON) или [N]; s — после "[".
+func cutBracket(s string) (PathSeg, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) || !strings.HasPrefix(s[end+1:], "]") {
+			return PathSeg{}, s, false
+		}
+		var key string
+		if json.Unmarshal([]byte(s[:end+1]), &key) != nil {
+			return PathSeg{}, s, false
+		}
+		return PathSeg{Key: key}, s[end+2:], true
+	}
+	end := strings.IndexByte(s, ']')
+	if end <= 0 || end > 7 {
+		return PathSeg{}, s, false
+	}
+	for _, c := range s[:end] {
+		if c < '0' || c > '9' {
+			return PathSeg{}, s, false
+		}
+	}
+	n, err := strconv.Atoi(s[:end])
+	if err != nil || n > maxJSONPathIndex {
+		return PathSeg{}, s, false
+	}
+	return PathSeg{Index: n, Elem: true}, s[end+1:], true
+}
+
+// String — каноническая запись: $.a["b c"][0]; разбирается обратно в тот же путь.
+func (p JSONPath) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range p {
+		switch {
+		case seg.Elem:
+			b.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+		case plainKey(seg.Key):
+			b.WriteString("." + seg.Key)
+		default:
+			q, _ := json.Marshal(seg.Key)
+			b.WriteString("[" + string(q) + "]")
+		}
+	}
+	return b.String()
+}
+
+func plainKey(k string) bool {
+	ident, rest := cutIdent(k)
+	return ident != "" && rest == ""
+}
+
+// Lookup — значение по пути в разобранном JSON (map[string]any / []any).
+func (p JSONPath) Lookup(v any) (any, bool) {
+	for _, seg := range p {
+		if seg.Elem {
+			arr, ok := v.([]any)
+			if !ok || seg.Index >= len(arr) {
+				return nil, false
+			}
+			v = arr[seg.Index]
+			continue
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[seg.Key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// JSONObject: значение фильтра contains — объект JSON, записанный компактно.
+func JSONObject(s string) (json.RawMessage, bool) {
+	var buf bytes.Buffer
+	if json.Compact(&buf, []byte(s)) != nil || !bytes.HasPrefix(buf.Bytes(), []byte("{")) || hasNUL(buf.Bytes()) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// hasNUL: jsonb и jsonpath не принимают \u0000 в строках.
+func hasNUL(raw []byte) bool {
+	return bytes.Contains(bytes.ToLower(raw), []byte(`\u0000`))
+}
+
+// JSONScalar: значение фильтра eq — литерал JSON (строка, число, true/false/null).
+// Невалидный JSON считается строкой: ?json.eq=$.status=paid то же, что ="paid".
+func JSONScalar(s string) (json.RawMessage, bool) {
+	raw := bytes.TrimSpace([]byte(s))
+	if !json.Valid(raw) {
+		raw, _ = json.Marshal(s)
+	}
+	if raw[0] == '{' || raw[0] == '[' || hasNUL(raw) {
+		return nil, false
+	}
+	return raw, true
+}
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func segKey(k string) PathSeg { return PathSeg{Key: k} }
+func segElem(i int) PathSeg   { return PathSeg{Index: i, Elem: true} }
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want JSONPath
+		ok   bool
+	}{
+		{"a", JSONPath{segKey("a")}, true},
+		{"a.b", JSONPath{segKey("a"), segKey("b")}, true},
+		{"$.a.b", JSONPath{segKey("a"), segKey("b")}, true},
+		{"$.items[0].sku", JSONPath{segKey("items"), segElem(0), segKey("sku")}, true},
+		{"[2]", JSONPath{segElem(2)}, true},
+		{"$[3][1]", JSONPath{segElem(3), segElem(1)}, true},
+		{`$["key with space"].x`, JSONPath{segKey("key with space"), segKey("x")}, true},
+		{`$["a\"b"]`, JSONPath{segKey(`a"b`)}, true},
+		{`$["a.b"]`, JSONPath{segKey("a.b")}, true},
+		{`$[""]`, JSONPath{segKey("")}, true},
+		{"заказ.сумма", JSONPath{segKey("заказ"), segKey("сумма")}, true},
+		{"snake_case-key.v2", JSONPath{segKey("snake_case-key"), segKey("v2")}, true},
+		{"a[999999]", JSONPath{segKey("a"), segElem(999999)}, true},
+
+		{"", nil, false},
+		{"$", nil, false},
+		{".a", nil, false},
+		{"a.", nil, false},
+		{"a..b", nil, false},
+		{"a b", nil, false},
+		{"a.*", nil, false},
+		{"$..a", nil, false},
+		{"a[*]", nil, false},
+		{"a[-1]", nil, false},
+		{"a[1:2]", nil, false},
+		{"a[]", nil, false},
+		{"a[1000001]", nil, false},
+		{"a[12345678]", nil, false},
+		{"a[?(@.x)]", nil, false},
+		{`$["unterminated`, nil, false},
+		{`$["a"`, nil, false},
+		{`$['a']`, nil, false},
+		{`$["bad \q"]`, nil, false},
+		{"a)", nil, false},
+		{"a'||'b", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseJSONPath(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("ParseJSONPath(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJSONPath(%q) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathLimits(t *testing.T) {
+	deep := "a" + strings.Repeat(".a", MaxJSONPathDepth-1)
+	if _, ok := ParseJSONPath(deep); !ok {
+		t.Errorf("path of depth %d rejected", MaxJSONPathDepth)
+	}
+	if _, ok := ParseJSONPath(deep + ".a"); ok {
+		t.Errorf("path deeper than %d accepted", MaxJSONPathDepth)
+	}
+	long := strings.Repeat("k", maxJSONPathKey)
+	if _, ok := ParseJSONPath(long); !ok {
+		t.Errorf("key of %d bytes rejected", maxJSONPathKey)
+	}
+	if _, ok := ParseJSONPath("$." + long + "k"); ok {
+		t.Errorf("key longer than %d bytes accepted", maxJSONPathKey)
+	}
+	if _, ok := ParseJSONPath(`$["` + long + `k"]`); ok {
+		t.Errorf("quoted key longer than %d bytes accepted", maxJSONPathKey)
+	}
+}
+
+func TestCutJSONPath(t *testing.T) {
+	tests := []struct {
+		in, path, rest string
+	}{
+		{"status=paid", "$.status", "=paid"},
+		{"$.a[0]=1", "$.a[0]", "=1"},
+		{`$["x=y"]=1`, `$["x=y"]`, "=1"},
+		{"a.b, c", "$.a.b", ", c"},
+		{"a", "$.a", ""},
+	}
+	for _, tt := range tests {
+		p, rest, ok := CutJSONPath(tt.in)
+		if !ok || p.String() != tt.path || rest != tt.rest {
+			t.Errorf("CutJSONPath(%q) = (%s, %q, %v), want (%s, %q, true)", tt.in, p, rest, ok, tt.path, tt.rest)
+		}
+	}
+}
+
+func TestJSONPathString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a.b", "$.a.b"},
+		{"$.items[0]", "$.items[0]"},
+		{`$["b c"][1].d`, `$["b c"][1].d`},
+		{`$["a\"b"]`, `$["a\"b"]`},
+		{`$["a.b"]`, `$["a.b"]`},
+		{`$["1x"]`, "$.1x"},
+		{`$[""]`, `$[""]`},
+	}
+	for _, tt := range tests {
+		p, ok := ParseJSONPath(tt.in)
+		if !ok {
+			t.Fatalf("ParseJSONPath(%q) failed", tt.in)
+		}
+		s := p.String()
+		if s != tt.want {
+			t.Errorf("String(%q) = %s, want %s", tt.in, s, tt.want)
+		}
+		// каноническая запись разбирается обратно в тот же путь
+		back, ok := ParseJSONPath(s)
+		if !ok || !reflect.DeepEqual(back, p) {
+			t.Errorf("round trip of %s = %#v, want %#v", s, back, p)
+		}
+	}
+}
+
+func TestJSONPathLookup(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":{"b":[10,{"c":"x"}]},"n":null,"s":"str"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"a.b[0]", float64(10), true},
+		{"a.b[1].c", "x", true},
+		{"n", nil, true},
+		{"s", "str", true},
+		{"a.b[2]", nil, false},
+		{"a.x", nil, false},
+		{"s.x", nil, false},
+		{"s[0]", nil, false},
+		{"a[0]", nil, false},
+		{"a.b.c", nil, false},
+	}
+	for _, tt := range tests {
+		p, _ := ParseJSONPath(tt.path)
+		got, ok := p.Lookup(doc)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%s) = (%v, %v), want (%v, %v)", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestJSONObject(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{`{"a": 1, "b": {"c": "d"}}`, `{"a":1,"b":{"c":"d"}}`, true},
+		{` {} `, `{}`, true},
+		{`[1]`, "", false},
+		{`"x"`, "", false},
+		{`1`, "", false},
+		{`{"a":`, "", false},
+		{`{"a":"\u0000"}`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := JSONObject(tt.in)
+		if ok != tt.ok || (ok && string(got) != tt.want) {
+			t.Errorf("JSONObject(%q) = (%s, %v), want (%s, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestJSONScalar(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{`1`, `1`, true},
+		{` 2.5 `, `2.5`, true},
+		{`true`, `true`, true},
+		{`null`, `null`, true},
+		{`"paid"`, `"paid"`, true},
+		{`paid`, `"paid"`, true},
+		{`a "quoted" word`, `"a \"quoted\" word"`, true},
+		{``, `""`, true},
+		{`{"a":1}`, "", false},
+		{`[1,2]`, "", false},
+		{`"\u0000"`, "", false},
+		{"nul\x00", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := JSONScalar(tt.in)
+		if ok != tt.ok || (ok && string(got) != tt.want) {
+			t.Errorf("JSONScalar(%q) = (%s, %v), want (%s, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,12 +11,100 @@ import (
 type UserID = uuid.UUID
 type DocID = uuid.UUID
 
+// Роли пользователей
+type Role = string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+func ValidRole(r string) bool { return r == RoleUser || r == RoleAdmin }
+
 // Пользователь
 type User struct {
 	ID        UserID    `json:"id"`
 	Login     string    `json:"login"`
 	PassHash  []byte    `json:"-"` // никогда не отдаём наружу
 	CreatedAt time.Time `json:"created_at"`
+	// Включена ли TOTP 2FA (секрет хранится отдельно, зашифрованным)
+	MFAEnabled bool       `json:"mfa_enabled"`
+	Role       Role       `json:"role"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"` // отключён администратором
+	InvitedBy  *UserID    `json:"invited_by,omitempty"`  // кто пригласил (регистрация по инвайту)
+}
+
+func (u User) IsAdmin() bool  { return u.Role == RoleAdmin }
+func (u User) Disabled() bool { return u.DisabledAt != nil }
+
+// Фильтр списка пользователей (админка)
+type UserFilter struct {
+	Query    string // подстрока логина
+	Role     Role
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// Занятое пользователем место
+type StorageUsage struct {
+	Docs  int64 `json:"docs"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Принципал — от чьего имени репозиторий применяет ACL документов.
+// Нулевое значение — аноним: видит только публичные документы.
+type PrincipalKind int
+
+const (
+	PrincipalAnonymous PrincipalKind = iota // без аутентификации
+	PrincipalUser                           // аутентифицированный пользователь
+	PrincipalSystem                         // внутренний вызов без ACL (например, анонимная ссылка)
+)
+
+type Principal struct {
+	Kind   PrincipalKind
+	UserID UserID // только для PrincipalUser
+}
+
+var (
+	Anonymous = Principal{Kind: PrincipalAnonymous}
+	System    = Principal{Kind: PrincipalSystem}
+)
+
+func AsUser(u User) Principal { return Principal{Kind: PrincipalUser, UserID: u.ID} }
+
+func (p Principal) IsAnonymous() bool { return p.Kind == PrincipalAnonymous }
+
+// Права на документ (по возрастанию). Owner не выдаётся через шаринг —
+// это владелец документа.
+type Permission = string
+
+const (
+	PermNone      Permission = ""
+	PermViewer    Permission = "viewer"    // чтение
+	PermCommenter Permission = "commenter" // чтение + комментарии
+	PermEditor    Permission = "editor"    // замена контента, правка метаданных/JSON
+	PermCoOwner   Permission = "co_owner"  // шаринг, удаление, смена public
+	PermOwner     Permission = "owner"
+)
+
+var permRank = map[Permission]int{
+	PermViewer:    1,
+	PermCommenter: 2,
+	PermEditor:    3,
+	PermCoOwner:   4,
+	PermOwner:     5,
+}
+
+// PermAllows: право have покрывает требуемое need.
+func PermAllows(have, need Permission) bool {
+	return permRank[have] > 0 && permRank[have] >= permRank[need]
+}
+
+// ValidGrantPermission: права, которые можно выдать через шаринг.
+func ValidGrantPermission(p string) bool {
+	return p == PermViewer || p == PermCommenter || p == PermEditor || p == PermCoOwner
 }
 
 // Метаданные документа (без тела файла)
@@ -36,12 +125,196 @@ type Document struct {
 
 	// Где лежит контент (локально/S3/MinIO)
 	StorageKey string `json:"-"`
+
+	// Права текущего пользователя (заполняется при чтении с ACL)
+	Permission Permission `json:"permission,omitempty"`
+
+	// Когда перенесён в корзину (только в выдаче корзины)
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Папка документа; nil — корень владельца
+	FolderID *uuid.UUID `json:"folder_id,omitempty"`
+
+	// JSON Schema, которой обязано соответствовать JSON-тело; nil — без проверки
+	SchemaID *uuid.UUID `json:"schema_id,omitempty"`
+
+	// Пользовательские теги и атрибуты (заполняются при чтении с ACL)
+	Tags  []string           `json:"tags,omitempty"`
+	Attrs map[string]DocAttr `json:"attrs,omitempty"`
+}
+
+// Тип пользовательского атрибута документа
+type AttrType = string
+
+const (
+	AttrString AttrType = "string"
+	AttrNumber AttrType = "number"
+	AttrDate   AttrType = "date" // YYYY-MM-DD
+)
+
+// Лимиты на документ
+const (
+	MaxDocTags  = 50
+	MaxDocAttrs = 50
+)
+
+// Пользовательский атрибут: Value — string для string и date, float64 для number.
+type DocAttr struct {
+	Type  AttrType `json:"type"`
+	Value any      `json:"value"`
+}
+
+// Изменение документа (PATCH): nil — поле не меняется.
+type DocUpdate struct {
+	Name   *string
+	MIME   *string
+	Public *bool
+	// SetJSON: JSON заменяется целиком на JSON (nil — удаляется)
+	SetJSON bool
+	JSON    DocJSON
+	// SetFolder: документ переносится в FolderID (nil — в корень)
+	SetFolder bool
+	FolderID  *uuid.UUID
+	// SetTags/SetAttrs: теги и атрибуты заменяются целиком
+	SetTags  bool
+	Tags     []string
+	SetAttrs bool
+	Attrs    map[string]DocAttr
+	// SetSchema: документ привязывается к схеме SchemaID (nil — отвязывается)
+	SetSchema bool
+	SchemaID  *uuid.UUID
+}
+
+// Ревизия контента файла (PUT /api/docs/{id}/content, восстановление старой)
+type DocRevision struct {
+	DocID      DocID     `json:"doc_id"`
+	Revision   int       `json:"revision"`
+	StorageKey string    `json:"-"`
+	SizeBytes  int64     `json:"size_bytes"`
+	SHA256     []byte    `json:"-"`
+	Checksum   string    `json:"sha256"` // hex SHA256, заполняется при выдаче
+	MIME       string    `json:"mime"`
+	AuthorID   *UserID   `json:"-"`
+	Author     string    `json:"author,omitempty"` // логин; пусто — автор удалён
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
 }
 
-// Шаринг: доступ на чтение конкретному пользователю
+// Шаринг: доступ конкретному пользователю или группе (заполнено одно из Login/Group)
 type DocShare struct {
-	DocID DocID  `json:"doc_id"`
-	Login string `json:"login"` // логин пользователя, которому дан доступ
+	DocID      DocID      `json:"doc_id"`
+	UserID     UserID     `json:"-"`               // для инвалидации кеша списков
+	Login      string     `json:"login,omitempty"` // логин пользователя, которому дан доступ
+	Group      string     `json:"group,omitempty"` // имя группы, которой дан доступ
+	Permission Permission `json:"permission"`
+}
+
+// Группа пользователей (шаринг командам)
+type Group struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy *UserID   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Members   int       `json:"members"`
+}
+
+type GroupMember struct {
+	UserID  UserID    `json:"user_id"`
+	Login   string    `json:"login"`
+	Admin   bool      `json:"admin"` // может менять состав группы
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Папка. Дерево папок принадлежит одному владельцу; права, выданные на папку,
+// действуют на все документы её поддерева.
+type Folder struct {
+	ID       uuid.UUID  `json:"id"`
+	OwnerID  UserID     `json:"owner_id"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"` // nil — корень владельца
+	Name     string     `json:"name"`
+	// Имена документов внутри папки (не в подпапках) уникальны
+	UniqueNames bool      `json:"unique_names"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Права текущего пользователя (владелец или старший грант на папку/предка)
+	Permission Permission `json:"permission,omitempty"`
+}
+
+// Звено пути к папке (хлебные крошки), от корня к самой папке
+type FolderCrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// Изменение папки (PATCH): nil — поле не меняется.
+type FolderUpdate struct {
+	Name        *string
+	UniqueNames *bool
+	// Move: папка переносится в ParentID (nil — в корень)
+	Move     bool
+	ParentID *uuid.UUID
+}
+
+// Именованная JSON Schema (draft 2020-12) для JSON-тел документов. Глобальные
+// (OwnerID == nil) регистрирует администратор, остальные видит только владелец.
+// Тело схемы не меняется: новая версия — новое имя.
+type JSONSchema struct {
+	ID        uuid.UUID       `json:"id"`
+	OwnerID   *UserID         `json:"owner_id,omitempty"`
+	Name      string          `json:"name"`
+	Body      json.RawMessage `json:"schema" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (s JSONSchema) Global() bool { return s.OwnerID == nil }
+
+// Грант на папку
+type FolderShare struct {
+	FolderID   uuid.UUID  `json:"folder_id"`
+	UserID     UserID     `json:"-"` // для инвалидации кеша списков
+	Login      string     `json:"login"`
+	Permission Permission `json:"permission"`
+}
+
+// Префикс анонимных ссылок (по нему видно, что это за токен)
+const ShareLinkPrefix = "mds_"
+
+// Анонимная ссылка на документ: GET /s/{token} без аккаунта
+type ShareLink struct {
+	ID           uuid.UUID  `json:"id"`
+	DocID        DocID      `json:"doc_id"`
+	CreatedBy    *UserID    `json:"created_by,omitempty"`
+	TokenHash    []byte     `json:"-"`
+	PassHash     string     `json:"-"`            // argon2, пусто — без пароля
+	HasPassword  bool       `json:"has_password"` // заполняется из PassHash
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"` // nil — без ограничения
+	Downloads    int        `json:"downloads"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Запрос на передачу владения документом (ждёт согласия получателя)
+type DocTransfer struct {
+	ID         uuid.UUID `json:"id"`
+	DocID      DocID     `json:"doc_id"`
+	DocName    string    `json:"doc_name"`
+	FromUserID UserID    `json:"-"`
+	From       string    `json:"from"` // логин текущего владельца
+	ToUserID   UserID    `json:"-"`
+	To         string    `json:"to"`          // логин получателя
+	KeepEditor bool      `json:"keep_editor"` // прежнему владельцу остаётся editor
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Итог смены владельца: перенесённые документы и пользователи с доступом к ним
+// (их списки нужно инвалидировать — меняются гранты).
+type OwnershipMove struct {
+	From, To UserID
+	Docs     []DocID
+	Audience []UserID
 }
 
 // Произвольный JSON документа (если File=false или в дополнение к файлу)
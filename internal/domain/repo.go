@@ -3,6 +3,8 @@ package domain
 import (
 	"context"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Фильтры и пагинация списков
@@ -18,10 +20,22 @@ const (
 // Фильтрация по произвольному key=value (из ТЗ)
 type ListFilter struct {
 	Login string // если пусто — свои; если задан — по указанному пользователю (учитывая ACL/public)
-	Key   string // имя колонки
+	Key   string // name | mime (теги и атрибуты — в Tags/Attrs)
 	Value string // значение
 	Limit int    // ограничение количества
 	Sort  ListSort
+	// Папка: nil — без фильтра; Root — только документы вне папок.
+	// Recursive — включая подпапки Folder.
+	Folder    *uuid.UUID
+	Root      bool
+	Recursive bool
+	// Теги (все должны быть у документа) и атрибуты key → значение
+	Tags  []string
+	Attrs map[string]string
+	// Только документы, привязанные к схеме
+	Schema *uuid.UUID
+	// Условия на JSON-тело (все должны выполняться)
+	JSON []JSONFilter
 	// Кейсет пагинация (рекомендовано под нагрузку)
 	AfterName    string
 	AfterCreated time.Time
@@ -31,26 +45,248 @@ type ListFilter struct {
 type UsersRepo interface {
 	Close()
 	Ping(context.Context) error
-	CreateUser(ctx context.Context, login string, passHash []byte) (User, error)
+	CreateUser(ctx context.Context, login string, passHash []byte, role Role) (User, error)
 	UserByLogin(ctx context.Context, login string) (User, error)
 	UserByID(ctx context.Context, id UserID) (User, error)
+	SetPasswordHash(ctx context.Context, id UserID, passHash []byte) error
+}
+
+// Управление пользователями (админка)
+type UserAdminRepo interface {
+	ListUsers(ctx context.Context, f UserFilter) ([]User, error)
+	SetUserDisabled(ctx context.Context, id UserID, disabled bool) error
+	SetUserRole(ctx context.Context, id UserID, role Role) error
+	// DeleteUser удаляет пользователя (документы — каскадом) и возвращает
+	// удалённые документы, чтобы вызывающий почистил storage и кеш.
+	DeleteUser(ctx context.Context, id UserID) ([]Document, error)
+	UserStorageUsage(ctx context.Context, id UserID) (StorageUsage, error)
 }
 
 type DocsRepo interface {
+	// ErrConflict — в папке с unique_names уже есть документ с таким именем.
 	CreateDoc(ctx context.Context, meta Document, json DocJSON) (Document, error)
-	// Возвращает метаданные и JSON (если есть). Контент — через BlobStorage.
-	DocByID(ctx context.Context, id DocID, forUser *User) (Document, DocJSON, error)
-	DocDelete(ctx context.Context, id DocID, owner UserID) error
+	// Возвращает метаданные и JSON (если есть) с ACL принципала. Контент — через BlobStorage.
+	DocByID(ctx context.Context, id DocID, p Principal) (Document, DocJSON, error)
+	// Переносит документ в корзину, если у by есть право co_owner и выше.
+	// Строка и контент остаются до окончательного удаления (TrashRepo).
+	DocDelete(ctx context.Context, id DocID, by UserID) error
+	// Единая точка вычисления прав пользователя на документ.
+	// PermNone — документа нет или доступа нет.
+	DocPermission(ctx context.Context, id DocID, p Principal) (Permission, error)
 
-	// Список: свои + расшаренные + публичные (в зависимости от фильтров)
-	DocsList(ctx context.Context, me User, f ListFilter) ([]Document, error)
+	// Список: свои + расшаренные + публичные (анониму — только публичные)
+	DocsList(ctx context.Context, p Principal, f ListFilter) ([]Document, error)
 
 	// Обновления (для повышения версии/etag)
 	Touch(ctx context.Context, id DocID) error
+	// Меняет метаданные/JSON и повышает версию, если текущая версия равна version
+	// (оптимистичная блокировка). Иначе — ErrPrecondition; ErrConflict — имя занято
+	// в папке с unique_names.
+	UpdateDoc(ctx context.Context, id DocID, version int64, u DocUpdate) (Document, error)
+}
+
+// Ревизии контента. Текущая ревизия документа — последняя; её ключ/размер/хэш
+// дублируются в documents. Blob-ключи контентные и общие у разных ревизий и
+// документов — удалять объект из storage можно только после UnreferencedBlobs.
+type RevisionsRepo interface {
+	// Новая ревизия становится текущей, если версия документа равна version
+	// (оптимистичная блокировка), иначе ErrPrecondition. Документ становится файлом.
+	AddRevision(ctx context.Context, docID DocID, version int64, rev DocRevision) (Document, DocRevision, error)
+	// Копия старой ревизии становится новой текущей. ErrNotFound — нет такой ревизии;
+	// ErrPrecondition — версия документа изменилась.
+	RestoreRevision(ctx context.Context, docID DocID, version int64, revision int, author UserID) (Document, DocRevision, error)
+	// От новых к старым.
+	ListRevisions(ctx context.Context, docID DocID) ([]DocRevision, error)
+	// ErrNotFound — нет такой ревизии.
+	RevisionByNumber(ctx context.Context, docID DocID, revision int) (DocRevision, error)
+	// Оставляет keep последних ревизий, возвращает ключи удалённых.
+	PruneRevisions(ctx context.Context, docID DocID, keep int) ([]string, error)
+	// То же для всех документов владельца (удаление пользователя).
+	OwnerBlobKeys(ctx context.Context, owner UserID) ([]string, error)
+	// Ключи из keys, на которые не ссылается ни документ, ни ревизия.
+	UnreferencedBlobs(ctx context.Context, keys []string) ([]string, error)
+}
+
+// Корзина: документы в ней скрыты из DocByID/DocsList. Управляют ею те, у кого
+// есть право co_owner и выше. Окончательное удаление возвращает blob-ключи
+// удалённых документов и их ревизий — объекты чистятся через UnreferencedBlobs.
+type TrashRepo interface {
+	ListTrash(ctx context.Context, by UserID) ([]Document, error)
+	// ErrNotFound — документа нет в корзине или нет права.
+	RestoreDoc(ctx context.Context, id DocID, by UserID) (Document, error)
+	// ErrNotFound — документа нет в корзине или нет права.
+	PurgeDoc(ctx context.Context, id DocID, by UserID) ([]string, error)
+	// Документы, удалённые раньше before (не больше limit за вызов) — фоновая чистка.
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int, []string, error)
 }
 
 type SharesRepo interface {
-	UpsertReadGrant(ctx context.Context, docID DocID, login string, canRead bool) error
-	RemoveGrant(ctx context.Context, docID DocID, login string) error
+	// Возвращает id пользователя, получившего доступ; ErrNotFound — нет такого логина.
+	UpsertGrant(ctx context.Context, docID DocID, login string, perm Permission) (UserID, error)
+	// Возвращает id пользователя, у которого отозван доступ; ErrNotFound — гранта не было.
+	RemoveGrant(ctx context.Context, docID DocID, login string) (UserID, error)
 	ListGrantedLogins(ctx context.Context, docID DocID) ([]string, error)
+	// Личные и групповые гранты документа.
+	ListGrants(ctx context.Context, docID DocID) ([]DocShare, error)
+
+	// Групповые гранты. Возвращают участников группы (для инвалидации кеша списков);
+	// ErrNotFound — нет такой группы (или гранта при удалении).
+	UpsertGroupGrant(ctx context.Context, docID DocID, group string, perm Permission) ([]UserID, error)
+	RemoveGroupGrant(ctx context.Context, docID DocID, group string) ([]UserID, error)
+
+	// Все, кому документ виден через гранты (лично, через группы и папки).
+	DocAudience(ctx context.Context, docID DocID) ([]UserID, error)
+}
+
+// Папки. Менять папку (имя, место, гранты, удаление) может только её владелец;
+// класть в неё документы — editor и выше.
+type FoldersRepo interface {
+	// ErrNotFound — нет родителя у владельца; ErrConflict — имя занято.
+	CreateFolder(ctx context.Context, f Folder) (Folder, error)
+	// С правами пользователя. ErrNotFound — нет папки или доступа к ней.
+	FolderByID(ctx context.Context, id uuid.UUID, user UserID) (Folder, error)
+	// Путь из имён от корня владельца. ErrNotFound — нет такой папки.
+	FolderByPath(ctx context.Context, owner UserID, names []string) (Folder, error)
+	// Хлебные крошки от корня: для не-владельца — начиная с верхней доступной папки.
+	FolderPath(ctx context.Context, id uuid.UUID, user UserID) ([]FolderCrumb, error)
+	// parent == nil — свои корневые папки и папки, расшаренные пользователю напрямую.
+	ListFolders(ctx context.Context, user UserID, parent *uuid.UUID) ([]Folder, error)
+	// ErrConflict — имя занято, перенос внутрь самой себя или (unique_names)
+	// в папке уже есть документы с одинаковыми именами.
+	UpdateFolder(ctx context.Context, id uuid.UUID, u FolderUpdate) (Folder, error)
+	// Удаляет папку с подпапками. Непустая без recursive — ErrConflict; с recursive
+	// документы поддерева уходят в корзину — они возвращаются для инвалидации кеша.
+	DeleteFolder(ctx context.Context, id uuid.UUID, recursive bool) ([]Document, error)
+	// Все, кому видно содержимое папки: владелец и гранты на неё и её предков.
+	FolderAudience(ctx context.Context, id uuid.UUID) ([]UserID, error)
+
+	// Возвращает id пользователя; ErrNotFound — нет такого логина (или гранта при удалении).
+	UpsertFolderGrant(ctx context.Context, id uuid.UUID, login string, perm Permission) (UserID, error)
+	RemoveFolderGrant(ctx context.Context, id uuid.UUID, login string) (UserID, error)
+	ListFolderGrants(ctx context.Context, id uuid.UUID) ([]FolderShare, error)
+}
+
+type SchemasRepo interface {
+	// ErrConflict — у владельца (или среди глобальных) имя занято.
+	CreateSchema(ctx context.Context, s JSONSchema) (JSONSchema, error)
+	// Без проверки прав (схема документа). ErrNotFound — нет такой.
+	SchemaByID(ctx context.Context, id uuid.UUID) (JSONSchema, error)
+	// Своя схема с таким именем, иначе глобальная. ErrNotFound — нет ни той, ни другой.
+	SchemaByName(ctx context.Context, user UserID, name string) (JSONSchema, error)
+	// Свои и глобальные.
+	ListSchemas(ctx context.Context, user UserID) ([]JSONSchema, error)
+	// ErrNotFound — нет такой; ErrConflict — к схеме привязаны документы (в том числе в корзине).
+	DeleteSchema(ctx context.Context, id uuid.UUID) error
+}
+
+type GroupsRepo interface {
+	// Создатель становится администратором группы. ErrConflict — имя занято.
+	CreateGroup(ctx context.Context, name string, creator UserID) (Group, error)
+	GroupByID(ctx context.Context, id uuid.UUID) (Group, error)
+	// member == nil — все группы (для администратора).
+	ListGroups(ctx context.Context, member *UserID) ([]Group, error)
+	// Возвращает бывших участников (для инвалидации кеша списков).
+	DeleteGroup(ctx context.Context, id uuid.UUID) ([]UserID, error)
+	GroupMembers(ctx context.Context, id uuid.UUID) ([]GroupMember, error)
+	// ErrNotFound — нет такого логина.
+	UpsertGroupMember(ctx context.Context, groupID uuid.UUID, login string, admin bool) (UserID, error)
+	// ErrNotFound — пользователь не состоит в группе.
+	RemoveGroupMember(ctx context.Context, groupID uuid.UUID, login string) (UserID, error)
+}
+
+// Передача владения. Blob-ключи контентные, квота считается по owner_id —
+// поэтому смена owner_id переносит и файлы, и учёт места.
+type TransfersRepo interface {
+	// Заменяет ожидающий запрос по тому же документу.
+	CreateTransfer(ctx context.Context, t DocTransfer) (DocTransfer, error)
+	// Действующие запросы, где пользователь — отправитель или получатель.
+	ListTransfers(ctx context.Context, user UserID) ([]DocTransfer, error)
+	// Отмена отправителем или отказ получателя. ErrNotFound — нет такого запроса.
+	CancelTransfer(ctx context.Context, id uuid.UUID, by UserID) error
+	// Принять запрос (только получатель). ErrNotFound — нет действующего запроса;
+	// ErrConflict — документ уже сменил владельца.
+	AcceptTransfer(ctx context.Context, id uuid.UUID, to UserID) (OwnershipMove, error)
+	// Все документы from переходят к to (админка, уход сотрудника).
+	TransferAllDocs(ctx context.Context, from, to UserID, keepEditor bool) (OwnershipMove, error)
+}
+
+type ShareLinksRepo interface {
+	CreateShareLink(ctx context.Context, l ShareLink) (ShareLink, error)
+	ListShareLinks(ctx context.Context, docID DocID) ([]ShareLink, error)
+	// ErrNotFound — нет такой ссылки у документа или уже отозвана.
+	RevokeShareLink(ctx context.Context, docID DocID, id uuid.UUID) error
+	// Только действующая ссылка: не отозвана, не истекла, лимит не исчерпан. Иначе ErrNotFound.
+	ShareLinkByHash(ctx context.Context, tokenHash []byte) (ShareLink, error)
+	// Атомарно засчитывает скачивание; ErrNotFound — ссылка перестала действовать.
+	CountShareDownload(ctx context.Context, id uuid.UUID) error
+}
+
+type RefreshTokensRepo interface {
+	CreateRefreshToken(ctx context.Context, rt RefreshToken) (RefreshToken, error)
+	RefreshTokenByHash(ctx context.Context, hash []byte) (RefreshToken, error)
+	// Атомарно помечает старый токен обменянным и сохраняет новый.
+	// Если старый уже обменян/отозван — ErrTokenReused.
+	RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next RefreshToken) (RefreshToken, error)
+	// Отзывает всё семейство; возвращает отозванные токены (для блэклиста access JTI).
+	RevokeRefreshFamily(ctx context.Context, familyID uuid.UUID) ([]RefreshToken, error)
+	RefreshTokenByAccessJTI(ctx context.Context, jti string) (RefreshToken, error)
+	// Отзывает все refresh-токены пользователя ("выйти везде").
+	RevokeUserRefreshTokens(ctx context.Context, userID UserID) error
+}
+
+type SessionsRepo interface {
+	CreateSession(ctx context.Context, s Session) error
+	// Ротация: новый access-токен в существующей сессии.
+	UpdateSessionToken(ctx context.Context, id uuid.UUID, jti string, expiresAt time.Time, ip, userAgent string) error
+	ListActiveSessions(ctx context.Context, userID UserID) ([]Session, error)
+	SessionByID(ctx context.Context, id uuid.UUID, userID UserID) (Session, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userID UserID) (int64, error)
+}
+
+type PersonalTokensRepo interface {
+	CreatePersonalToken(ctx context.Context, t PersonalToken) (PersonalToken, error)
+	ListPersonalTokens(ctx context.Context, userID UserID) ([]PersonalToken, error)
+	// Возвращает активный (не отозванный) токен вместе с логином владельца.
+	PersonalTokenByHash(ctx context.Context, hash []byte) (PersonalToken, error)
+	RevokePersonalToken(ctx context.Context, id uuid.UUID, userID UserID) error
+	TouchPersonalToken(ctx context.Context, id uuid.UUID) error
+}
+
+type LoginAttemptsRepo interface {
+	RecordLoginAttempt(ctx context.Context, a LoginAttempt) error
+}
+
+type MFARepo interface {
+	TOTPState(ctx context.Context, userID UserID) (TOTPState, error)
+	// Новый (ещё не подтверждённый) секрет; 2FA остаётся выключенной.
+	SetTOTPSecret(ctx context.Context, userID UserID, sealed []byte) error
+	// Включает 2FA и заменяет коды восстановления — одной транзакцией.
+	EnableTOTP(ctx context.Context, userID UserID, step int64, recoveryHashes [][]byte) error
+	DisableTOTP(ctx context.Context, userID UserID) error
+	// UseTOTPStep принимает шаг, только если он новее последнего. false — код уже использован.
+	UseTOTPStep(ctx context.Context, userID UserID, step int64) (bool, error)
+	// UseRecoveryCode гасит неиспользованный код. false — кода нет или он использован.
+	UseRecoveryCode(ctx context.Context, userID UserID, hash []byte) (bool, error)
+}
+
+type InvitesRepo interface {
+	CreateInvite(ctx context.Context, inv Invite) (Invite, error)
+	// createdBy == nil — все приглашения (для администратора).
+	ListInvites(ctx context.Context, createdBy *UserID) ([]Invite, error)
+	// createdBy == nil — отзыв любого приглашения (администратор). ErrNotFound — нет такого.
+	RevokeInvite(ctx context.Context, id uuid.UUID, createdBy *UserID) error
+	// RegisterWithInvite в одной транзакции гасит одно использование приглашения
+	// и создаёт пользователя с его ролью. ErrUnauth — код неизвестен, истёк, отозван или исчерпан.
+	RegisterWithInvite(ctx context.Context, codeHash []byte, login string, passHash []byte) (User, error)
+}
+
+// Привязка внешних (OIDC) учётных записей
+type ExternalIdentityRepo interface {
+	// ErrNotFound — учётка не привязана.
+	UserByExternalIdentity(ctx context.Context, issuer, subject string) (User, error)
+	// ErrBadParams — учётка уже привязана к другому пользователю.
+	LinkExternalIdentity(ctx context.Context, userID UserID, ident ExternalIdentity) error
+	// Создаёт пользователя без локального пароля. ErrBadParams — логин занят.
+	CreateExternalUser(ctx context.Context, login string, role Role, ident ExternalIdentity) (User, error)
 }
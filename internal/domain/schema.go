@@ -0,0 +1,13 @@
+package domain
+
+import "encoding/json"
+
+// Проверка JSON-тел документов по JSON Schema (draft 2020-12).
+// Внешние $ref не загружаются: схема должна быть самодостаточной.
+type SchemaValidator interface {
+	// Check: схема компилируется; иначе *ValidationError поверх ErrBadParams.
+	Check(body json.RawMessage) error
+	// Validate: тело соответствует схеме; иначе *ValidationError поверх ErrSchemaViolation.
+	// nil-тело проверяется как пустой объект.
+	Validate(s JSONSchema, body DocJSON) error
+}
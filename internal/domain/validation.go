@@ -1,26 +1,126 @@
 package domain
 
 import (
+	"math"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 var (
 	loginRe = regexp.MustCompile(`^[A-Za-z0-9]{8,}$`)
+	groupRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{2,63}$`)
 	// Пароль: мин 8, >=2 буквы в разных регистрах, >=1 цифра, >=1 символ
 	// Упростим проверку в домене; детально можно валидировать в слое HTTP.
 	upperRe = regexp.MustCompile(`[A-Z]`)
 	lowerRe = regexp.MustCompile(`[a-z]`)
 	digitRe = regexp.MustCompile(`[0-9]`)
 	symRe   = regexp.MustCompile(`[^A-Za-z0-9]`)
+	// Тег: буквы/цифры и разделители, без запятых и пробелов
+	tagRe     = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.:-]{0,63}$`)
+	attrKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	schemaRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
 )
 
+const maxAttrString = 1024
+
 func ValidLogin(s string) bool {
 	return loginRe.MatchString(s)
 }
 
+func ValidGroupName(s string) bool {
+	return groupRe.MatchString(s)
+}
+
 func ValidPassword(s string) bool {
 	if len(s) < 8 {
 		return false
 	}
 	return upperRe.MatchString(s) && lowerRe.MatchString(s) && digitRe.MatchString(s) && symRe.MatchString(s)
 }
+
+func ValidSchemaName(s string) bool {
+	return schemaRe.MatchString(s)
+}
+
+// ValidFolderName: имя папки — сегмент пути, поэтому без "/" и не "." / "..".
+func ValidFolderName(s string) bool {
+	return s != "" && len(s) <= 255 && s != "." && s != ".." && !strings.ContainsAny(s, "/\x00")
+}
+
+// NormalizeTags: теги регистронезависимы — приводятся к нижнему регистру,
+// дубликаты убираются, порядок — по алфавиту.
+func NormalizeTags(in []string) ([]string, bool) {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]string, 0, len(in))
+	for _, t := range in {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !tagRe.MatchString(t) {
+			return nil, false
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	if len(out) > MaxDocTags {
+		return nil, false
+	}
+	sort.Strings(out)
+	return out, true
+}
+
+func ValidAttrKey(s string) bool {
+	return attrKeyRe.MatchString(s)
+}
+
+// NormalizeAttr проверяет значение по типу. Без type тип выводится из JSON
+// (строка → string, число → number); date — только явно, строкой YYYY-MM-DD.
+func NormalizeAttr(a DocAttr) (DocAttr, bool) {
+	if a.Type == "" {
+		switch a.Value.(type) {
+		case string:
+			a.Type = AttrString
+		case float64:
+			a.Type = AttrNumber
+		}
+	}
+	switch a.Type {
+	case AttrString:
+		s, ok := a.Value.(string)
+		return a, ok && utf8.RuneCountInString(s) <= maxAttrString
+	case AttrNumber:
+		n, ok := a.Value.(float64)
+		return a, ok && !math.IsInf(n, 0) && !math.IsNaN(n)
+	case AttrDate:
+		s, ok := a.Value.(string)
+		if !ok {
+			return a, false
+		}
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return a, false
+		}
+		a.Value = t.Format(time.DateOnly)
+		return a, true
+	default:
+		return a, false
+	}
+}
+
+// AttrText — каноническая текстовая запись значения: по ней хранится
+// и фильтруется атрибут (attr.<key>=<value>).
+func AttrText(a DocAttr) string {
+	switch v := a.Value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
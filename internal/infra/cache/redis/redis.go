@@ -2,30 +2,131 @@ package redisx
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Режим подключения к Redis
+type Mode string
+
+const (
+	ModeSingle   Mode = "single"   // один узел (redis.NewClient)
+	ModeSentinel Mode = "sentinel" // master/replica с failover (redis.NewFailoverClient)
+	ModeCluster  Mode = "cluster"  // Redis Cluster (redis.NewClusterClient)
+)
+
 type Cache struct {
-	rdb    *redis.Client
+	rdb    redis.UniversalClient
+	mode   Mode
 	logger *log.Logger
 }
 
 type Config struct {
-	Addr     string
-	DB       int
+	Mode Mode // пусто = single
+
+	Addr  string   // адрес для single
+	Addrs []string // адреса sentinel-ов или seed-узлы кластера (если пусто — берём Addr)
+
+	MasterName       string // имя мастера в sentinel
+	SentinelUsername string
+	SentinelPassword string
+
+	DB       int // в cluster не поддерживается (всегда 0)
+	Username string
 	Password string
+
+	// TLS
+	TLS                   bool
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	// Пул соединений (0 = дефолты go-redis)
+	PoolSize     int
+	MinIdleConns int
 }
 
-func New(cfg Config, logger *log.Logger) *Cache {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		DB:       cfg.DB,
-		Password: cfg.Password,
-	})
-	return &Cache{rdb: rdb, logger: logger}
+func New(cfg Config, logger *log.Logger) (*Cache, error) {
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeSingle
+	}
+
+	addrs := cfg.Addrs
+	if len(addrs) == 0 && cfg.Addr != "" {
+		addrs = []string{cfg.Addr}
+	}
+
+	var tlsCfg *tls.Config
+	if cfg.TLS {
+		tlsCfg = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			ServerName:         cfg.TLSServerName,
+			InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+		}
+	}
+
+	logger.Printf("init redis client mode=%s addrs=%v master=%q db=%d tls=%v pool=%d min_idle=%d",
+		mode, addrs, cfg.MasterName, cfg.DB, cfg.TLS, cfg.PoolSize, cfg.MinIdleConns)
+
+	var rdb redis.UniversalClient
+	switch mode {
+	case ModeSingle:
+		addr := cfg.Addr
+		if addr == "" && len(addrs) > 0 {
+			addr = addrs[0]
+		}
+		rdb = redis.NewClient(&redis.Options{
+			Addr:         addr,
+			DB:           cfg.DB,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsCfg,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+		})
+	case ModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel: master name is required")
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel: at least one sentinel address is required")
+		}
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    addrs,
+			SentinelUsername: cfg.SentinelUsername,
+			SentinelPassword: cfg.SentinelPassword,
+			DB:               cfg.DB,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			TLSConfig:        tlsCfg,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+		})
+	case ModeCluster:
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("redis cluster: at least one node address is required")
+		}
+		if cfg.DB != 0 {
+			logger.Printf("cluster mode ignores db=%d (only db 0 is available)", cfg.DB)
+		}
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsCfg,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+		})
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q (want single|sentinel|cluster)", mode)
+	}
+
+	return &Cache{rdb: rdb, mode: mode, logger: logger}, nil
 }
 
 func (c *Cache) Ping(ctx context.Context) error {
@@ -68,6 +169,21 @@ func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+// GetDel атомарно читает и удаляет ключ (одноразовые значения). Нет ключа — nil, nil.
+func (c *Cache) GetDel(ctx context.Context, key string) ([]byte, error) {
+	b, err := c.rdb.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		c.logger.Printf("GETDEL %q: not found", key)
+		return nil, nil
+	}
+	if err != nil {
+		c.logger.Printf("GETDEL %q: error: %v", key, err)
+	} else {
+		c.logger.Printf("GETDEL %q: hit (%d bytes)", key, len(b))
+	}
+	return b, err
+}
+
 func (c *Cache) Set(ctx context.Context, key string, val []byte, ttlSeconds int) error {
 	var ttl time.Duration
 	if ttlSeconds > 0 {
@@ -82,8 +198,35 @@ func (c *Cache) Set(ctx context.Context, key string, val []byte, ttlSeconds int)
 	return err
 }
 
+// Del удаляет ключи. В кластере ключи из разных слотов нельзя удалить одной
+// командой (CROSSSLOT), поэтому там шлём по DEL на ключ в одном пайплайне —
+// go-redis сам разведёт команды по узлам.
 func (c *Cache) Del(ctx context.Context, keys ...string) error {
-	n, err := c.rdb.Del(ctx, keys...).Result()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var (
+		n   int64
+		err error
+	)
+	if c.mode == ModeCluster && len(keys) > 1 {
+		var cmds []redis.Cmder
+		cmds, err = c.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, k := range keys {
+				p.Del(ctx, k)
+			}
+			return nil
+		})
+		for _, cmd := range cmds {
+			if ic, ok := cmd.(*redis.IntCmd); ok {
+				n += ic.Val()
+			}
+		}
+	} else {
+		n, err = c.rdb.Del(ctx, keys...).Result()
+	}
+
 	if err != nil {
 		c.logger.Printf("DEL %v failed: %v", keys, err)
 	} else {
@@ -102,6 +245,27 @@ func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
 	return n, err
 }
 
+func (c *Cache) Decr(ctx context.Context, key string) (int64, error) {
+	n, err := c.rdb.Decr(ctx, key).Result()
+	if err != nil {
+		c.logger.Printf("DECR %q failed: %v", key, err)
+	} else {
+		c.logger.Printf("DECR %q -> %d", key, n)
+	}
+	return n, err
+}
+
+// Expire выставляет TTL существующему ключу (для счётчиков после Incr).
+func (c *Cache) Expire(ctx context.Context, key string, ttlSeconds int) error {
+	ttl := time.Duration(ttlSeconds) * time.Second
+	if err := c.rdb.Expire(ctx, key, ttl).Err(); err != nil {
+		c.logger.Printf("EXPIRE %q failed: %v", key, err)
+		return err
+	}
+	c.logger.Printf("EXPIRE %q ok (ttl=%s)", key, ttl)
+	return nil
+}
+
 // SetNX устанавливает значение только если ключ ещё не существует.
 func (c *Cache) SetNX(ctx context.Context, key string, val []byte, ttlSeconds int) (bool, error) {
 	var ttl time.Duration
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) RecordLoginAttempt(ctx context.Context, a domain.LoginAttempt) error {
+	q := r.qb().Insert(fmt.Sprintf("%s.login_attempts", r.schema)).
+		Columns("login", "user_id", "ip", "user_agent", "success", "reason").
+		Values(a.Login, a.UserID, a.IP, a.UserAgent, a.Success, a.Reason)
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RecordLoginAttempt", sqlStr, args)
+
+	start := time.Now()
+	if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("RecordLoginAttempt exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("RecordLoginAttempt ok in %s login=%s success=%t reason=%s", time.Since(start), a.Login, a.Success, a.Reason)
+	return nil
+}
@@ -3,37 +3,78 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// CreateDoc — метаданные, первая ревизия, JSON-тело и метки в одной транзакции:
+// при ошибке на любом шаге документ не появляется наполовину.
 func (r *PGRepo) CreateDoc(ctx context.Context, meta domain.Document, jsonBody domain.DocJSON) (domain.Document, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("CreateDoc begin error: %v", err)
+		return domain.Document{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if meta.FolderID != nil {
+		if err := r.lockFolderNames(ctx, tx, *meta.FolderID); err != nil {
+			return domain.Document{}, err
+		}
+		taken, err := r.nameTaken(ctx, tx, *meta.FolderID, meta.Name, uuid.Nil)
+		if err != nil {
+			return domain.Document{}, err
+		}
+		if taken {
+			r.logger.Printf("CreateDoc name taken in folder=%s name=%q", *meta.FolderID, meta.Name)
+			return domain.Document{}, domain.ErrConflict
+		}
+	}
+
 	// вставляем метаданные
 	q := r.qb().Insert(fmt.Sprintf("%s.documents", r.schema)).
-		Columns("owner_id", "name", "mime_type", "file", "public", "size_bytes", "storage_key", "content_sha256").
-		Values(meta.OwnerID, meta.Name, meta.MIME, meta.File, meta.Public, meta.SizeBytes, meta.StorageKey, meta.SHA256).
-		Suffix("RETURNING id, owner_id, name, mime_type, file, public, size_bytes, storage_key, content_sha256, version, created_at, updated_at")
+		Columns("owner_id", "name", "mime_type", "file", "public", "size_bytes", "storage_key", "content_sha256", "folder_id", "schema_id").
+		Values(meta.OwnerID, meta.Name, meta.MIME, meta.File, meta.Public, meta.SizeBytes, meta.StorageKey, meta.SHA256, meta.FolderID, meta.SchemaID).
+		Suffix("RETURNING id, owner_id, name, mime_type, file, public, size_bytes, storage_key, content_sha256, version, created_at, updated_at, folder_id, schema_id")
 
 	sqlStr, args, _ := q.ToSql()
 	r.logSQL("CreateDoc", sqlStr, args)
 
 	start := time.Now()
-	row := r.pool.QueryRow(ctx, sqlStr, args...)
+	row := tx.QueryRow(ctx, sqlStr, args...)
 	var out domain.Document
 	if err := row.Scan(
 		&out.ID, &out.OwnerID, &out.Name, &out.MIME, &out.File, &out.Public,
-		&out.SizeBytes, &out.StorageKey, &out.SHA256, &out.Version, &out.CreatedAt, &out.UpdatedAt,
+		&out.SizeBytes, &out.StorageKey, &out.SHA256, &out.Version, &out.CreatedAt, &out.UpdatedAt, &out.FolderID, &out.SchemaID,
 	); err != nil {
 		r.logger.Printf("CreateDoc scan error after %s: %v", time.Since(start), err)
 		return domain.Document{}, err
 	}
 	r.logger.Printf("CreateDoc meta ok in %s id=%s name=%q", time.Since(start), out.ID, out.Name)
 
+	// файл — первая ревизия контента
+	if out.File {
+		qv := r.qb().Insert(fmt.Sprintf("%s.document_versions", r.schema)).
+			Columns("doc_id", "revision", "storage_key", "size_bytes", "content_sha256", "mime_type", "author_id", "created_at").
+			Values(out.ID, 1, out.StorageKey, out.SizeBytes, out.SHA256, out.MIME, out.OwnerID, out.CreatedAt)
+		sqlStr, args, _ = qv.ToSql()
+		r.logSQL("CreateDoc.revision", sqlStr, args)
+
+		startV := time.Now()
+		if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+			r.logger.Printf("CreateDoc.revision exec error after %s: %v", time.Since(startV), err)
+			return domain.Document{}, err
+		}
+	}
+
 	// json (опционально)
 	if jsonBody != nil {
 		payload, err := json.Marshal(jsonBody)
@@ -47,33 +88,46 @@ func (r *PGRepo) CreateDoc(ctx context.Context, meta domain.Document, jsonBody d
 		r.logSQL("CreateDoc.json", sqlStr, args)
 
 		startJ := time.Now()
-		if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
 			r.logger.Printf("CreateDoc.json exec error after %s: %v", time.Since(startJ), err)
 			return domain.Document{}, err
 		}
 		r.logger.Printf("CreateDoc json ok in %s id=%s", time.Since(startJ), out.ID)
 	}
+
+	// теги и атрибуты (опционально)
+	if len(meta.Tags) > 0 {
+		if err := r.setTags(ctx, tx, out.ID, meta.Tags); err != nil {
+			return domain.Document{}, err
+		}
+		out.Tags = meta.Tags
+	}
+	if len(meta.Attrs) > 0 {
+		if err := r.setAttrs(ctx, tx, out.ID, meta.Attrs); err != nil {
+			return domain.Document{}, err
+		}
+		out.Attrs = meta.Attrs
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("CreateDoc commit error: %v", err)
+		return domain.Document{}, err
+	}
 	return out, nil
 }
 
-// Если forUser != nil, применяем ACL: владелец ИЛИ public ИЛИ есть share(can_read).
-func (r *PGRepo) DocByID(ctx context.Context, id domain.DocID, forUser *domain.User) (domain.Document, domain.DocJSON, error) {
+// ACL принципала применяется через visibleExpr; Permission (permExpr) заполняется всегда.
+func (r *PGRepo) DocByID(ctx context.Context, id domain.DocID, p domain.Principal) (domain.Document, domain.DocJSON, error) {
 	docs := fmt.Sprintf("%s.documents d", r.schema)
+	permSQL, permArgs := r.permExpr(p)
 	sb := r.qb().Select(
 		"d.id", "d.owner_id", "d.name", "d.mime_type", "d.file", "d.public",
 		"d.size_bytes", "d.storage_key", "d.content_sha256",
-		"d.version", "d.created_at", "d.updated_at",
-	).From(docs).Where(sq.Eq{"d.id": id})
-
-	if forUser != nil {
-		shares := fmt.Sprintf("%s.doc_shares s", r.schema)
-		acl := sq.Or{
-			sq.Eq{"d.owner_id": forUser.ID},
-			sq.Eq{"d.public": true},
-			sq.Expr("EXISTS (SELECT 1 FROM "+shares+" WHERE s.doc_id = d.id AND s.user_id = ? AND s.can_read = TRUE)", forUser.ID),
-		}
-		sb = sb.Where(acl)
-	}
+		"d.version", "d.created_at", "d.updated_at", "d.folder_id", "d.schema_id",
+	).Column(sq.Expr(permSQL+" AS permission", permArgs...)).
+		From(docs).
+		Where(sq.Eq{"d.id": id}).
+		Where(r.visibleExpr(p))
 
 	sqlStr, args, _ := sb.ToSql()
 	r.logSQL("DocByID.meta", sqlStr, args)
@@ -84,13 +138,19 @@ func (r *PGRepo) DocByID(ctx context.Context, id domain.DocID, forUser *domain.U
 	if err := row.Scan(
 		&d.ID, &d.OwnerID, &d.Name, &d.MIME, &d.File, &d.Public,
 		&d.SizeBytes, &d.StorageKey, &d.SHA256,
-		&d.Version, &d.CreatedAt, &d.UpdatedAt,
+		&d.Version, &d.CreatedAt, &d.UpdatedAt, &d.FolderID, &d.SchemaID, &d.Permission,
 	); err != nil {
 		r.logger.Printf("DocByID meta scan error after %s: %v", time.Since(start), err)
 		return domain.Document{}, nil, err
 	}
 	r.logger.Printf("DocByID meta ok in %s id=%s", time.Since(start), d.ID)
 
+	one := []domain.Document{d}
+	if err := r.loadLabels(ctx, r.pool, one); err != nil {
+		return domain.Document{}, nil, err
+	}
+	d = one[0]
+
 	// doc_json (может отсутствовать)
 	var jsonRaw []byte
 	qj := r.qb().Select("body").
@@ -114,55 +174,167 @@ func (r *PGRepo) DocByID(ctx context.Context, id domain.DocID, forUser *domain.U
 	return d, dj, nil
 }
 
-func (r *PGRepo) DocDelete(ctx context.Context, id domain.DocID, owner domain.UserID) error {
-	q := r.qb().Delete(fmt.Sprintf("%s.documents", r.schema)).
-		Where(sq.And{sq.Eq{"id": id}, sq.Eq{"owner_id": owner}})
+// Удаление (в корзину) разрешено владельцу и co_owner. Ожидающие запросы
+// на передачу владения удаляются: принять документ из корзины нельзя.
+func (r *PGRepo) DocDelete(ctx context.Context, id domain.DocID, by domain.UserID) error {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("DocDelete begin error: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	permSQL, permArgs := r.permExpr(domain.Principal{Kind: domain.PrincipalUser, UserID: by})
+	q := r.qb().Update(fmt.Sprintf("%s.documents d", r.schema)).
+		Set("deleted_at", sq.Expr("now()")).
+		Set("deleted_by", by).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"d.id": id}).
+		Where("d.deleted_at IS NULL").
+		Where(sq.Expr(permSQL+" IN (?, ?)", append(permArgs, domain.PermCoOwner, domain.PermOwner)...))
 	sqlStr, args, _ := q.ToSql()
 	r.logSQL("DocDelete", sqlStr, args)
 
-	start := time.Now()
-	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	tag, err := tx.Exec(ctx, sqlStr, args...)
 	if err != nil {
 		r.logger.Printf("DocDelete exec error after %s: %v", time.Since(start), err)
 		return err
 	}
 	ra := tag.RowsAffected()
 	if ra == 0 {
-		r.logger.Printf("DocDelete no rows affected in %s (doc not found or not owner)", time.Since(start))
-		return sqlNoRowsErr("document not found or not owner")
+		r.logger.Printf("DocDelete no rows affected in %s (doc not found or no permission)", time.Since(start))
+		return sqlNoRowsErr("document not found or no permission")
+	}
+
+	tq := r.qb().Delete(fmt.Sprintf("%s.doc_transfers", r.schema)).Where(sq.Eq{"doc_id": id})
+	if err := r.execTx(ctx, tx, "DocDelete.transfers", tq); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("DocDelete commit error after %s: %v", time.Since(start), err)
+		return err
 	}
 	r.logger.Printf("DocDelete ok in %s rows=%d", time.Since(start), ra)
 	return nil
 }
 
-func sqlNoRowsErr(msg string) error { return fmt.Errorf(msg) }
+// permExpr — единственное место, где вычисляются права принципала на документ d:
+// владелец → owner; владелец папки документа → co_owner; иначе старшее из личного,
+// групповых прав и грантов на папку документа или её предков; иначе public → viewer;
+// иначе пустая строка (нет доступа). Аноним видит только public (viewer), системный
+// принципал — всё (owner). Используется как колонка и для проверки конкретного права.
+func (r *PGRepo) permExpr(p domain.Principal) (string, []any) {
+	switch p.Kind {
+	case domain.PrincipalSystem:
+		return `('owner')`, nil
+	case domain.PrincipalUser:
+		return `(CASE WHEN d.owner_id = ? THEN 'owner'
+		WHEN d.folder_id IN (SELECT f.id FROM ` + r.schema + `.folders f WHERE f.owner_id = ?) THEN 'co_owner'
+		ELSE COALESCE(
+		(SELECT g.p FROM (
+			SELECT s.permission AS p FROM ` + r.schema + `.doc_shares s WHERE s.doc_id = d.id AND s.user_id = ?
+			UNION ALL
+			SELECT gs.permission FROM ` + r.schema + `.doc_group_shares gs
+			JOIN ` + r.schema + `.group_members gm ON gm.group_id = gs.group_id
+			WHERE gs.doc_id = d.id AND gm.user_id = ?
+			UNION ALL
+			SELECT fs.permission FROM ` + r.schema + `.folder_paths fp
+			JOIN ` + r.schema + `.folder_shares fs ON fs.folder_id = fp.ancestor_id
+			WHERE fp.descendant_id = d.folder_id AND fs.user_id = ?
+		) g ORDER BY array_position(ARRAY['viewer', 'commenter', 'editor', 'co_owner'], g.p) DESC LIMIT 1),
+		CASE WHEN d.public THEN 'viewer' ELSE '' END) END)`, []any{p.UserID, p.UserID, p.UserID, p.UserID, p.UserID}
+	default:
+		return `(CASE WHEN d.public THEN 'viewer' ELSE '' END)`, nil
+	}
+}
+
+// visibleExpr — условие видимости, эквивалентное непустому permExpr, но в форме,
+// которую планировщик раскладывает по индексам (owner_id, public, doc_shares.user_id,
+// group_members.user_id, folder_id) вместо вычисления CASE по каждой строке documents.
+// public = TRUE — литералом, чтобы подходил частичный индекс idx_docs_public.
+// Документы в корзине не видны никому, включая системного принципала.
+func (r *PGRepo) visibleExpr(p domain.Principal) sq.Sqlizer {
+	notDeleted := sq.Expr("d.deleted_at IS NULL")
+	switch p.Kind {
+	case domain.PrincipalSystem:
+		return notDeleted
+	case domain.PrincipalUser:
+		return sq.And{notDeleted, sq.Or{
+			sq.Eq{"d.owner_id": p.UserID},
+			sq.Expr("d.public = TRUE"),
+			sq.Expr("d.id IN (SELECT s.doc_id FROM "+r.schema+".doc_shares s WHERE s.user_id = ?)", p.UserID),
+			sq.Expr("d.id IN (SELECT gs.doc_id FROM "+r.schema+".doc_group_shares gs JOIN "+r.schema+
+				".group_members gm ON gm.group_id = gs.group_id WHERE gm.user_id = ?)", p.UserID),
+			sq.Expr("d.folder_id IN (SELECT f.id FROM "+r.schema+".folders f WHERE f.owner_id = ?)", p.UserID),
+			sq.Expr("d.folder_id IN (SELECT fp.descendant_id FROM "+r.schema+".folder_paths fp JOIN "+r.schema+
+				".folder_shares fs ON fs.folder_id = fp.ancestor_id WHERE fs.user_id = ?)", p.UserID),
+		}}
+	default:
+		return sq.And{notDeleted, sq.Expr("d.public = TRUE")}
+	}
+}
+
+// DocPermission возвращает права пользователя на документ (PermNone — нет доступа или документа).
+func (r *PGRepo) DocPermission(ctx context.Context, id domain.DocID, p domain.Principal) (domain.Permission, error) {
+	permSQL, permArgs := r.permExpr(p)
+	q := r.qb().Select().Column(sq.Expr(permSQL, permArgs...)).
+		From(fmt.Sprintf("%s.documents d", r.schema)).
+		Where(sq.Eq{"d.id": id}).
+		Where("d.deleted_at IS NULL")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("DocPermission", sqlStr, args)
+
+	start := time.Now()
+	var perm domain.Permission
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&perm); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("DocPermission no doc in %s id=%s", time.Since(start), id)
+			return domain.PermNone, nil
+		}
+		r.logger.Printf("DocPermission scan error after %s: %v", time.Since(start), err)
+		return domain.PermNone, err
+	}
+	r.logger.Printf("DocPermission ok in %s id=%s perm=%q", time.Since(start), id, perm)
+	return perm, nil
+}
+
+func sqlNoRowsErr(msg string) error { return errors.New(msg) }
 
-// Выдаёт документы пользователя (свои + публичные + расшаренные)
+// Выдаёт документы, видимые принципалу (свои + публичные + расшаренные; анониму — публичные)
 // List implements domain.DocsRepo.
-func (r *PGRepo) DocsList(ctx context.Context, me domain.User, f domain.ListFilter) ([]domain.Document, error) {
+func (r *PGRepo) DocsList(ctx context.Context, p domain.Principal, f domain.ListFilter) ([]domain.Document, error) {
 	docs := fmt.Sprintf("%s.documents d", r.schema)
 	users := fmt.Sprintf("%s.users u", r.schema)
-	shares := fmt.Sprintf("%s.doc_shares s", r.schema)
+	permSQL, permArgs := r.permExpr(p)
 
 	sb := r.qb().Select(
 		"d.id", "d.owner_id", "d.name", "d.mime_type", "d.file", "d.public",
 		"d.size_bytes", "d.storage_key", "d.content_sha256",
-		"d.version", "d.created_at", "d.updated_at",
-	).From(docs).
+		"d.version", "d.created_at", "d.updated_at", "d.folder_id", "d.schema_id",
+	).Column(sq.Expr(permSQL+" AS permission", permArgs...)).
+		From(docs).
 		Join(users + " ON u.id = d.owner_id")
 
-	visible := sq.Or{
-		sq.Eq{"d.owner_id": me.ID},
-		sq.Eq{"d.public": true},
-		sq.Expr("EXISTS (SELECT 1 FROM "+shares+" WHERE s.doc_id = d.id AND s.user_id = ? AND s.can_read = TRUE)", me.ID),
-	}
-	sb = sb.Where(visible)
+	// видимость: есть хоть какое-то право
+	sb = sb.Where(r.visibleExpr(p))
 
 	// если задан login — показываем только документы этого пользователя (в рамках видимости)
 	if f.Login != "" {
 		sb = sb.Where(sq.Eq{"u.login": f.Login})
 	}
 
+	// папка: только корень, сама папка или всё её поддерево
+	switch {
+	case f.Root:
+		sb = sb.Where("d.folder_id IS NULL")
+	case f.Folder != nil && f.Recursive:
+		sb = sb.Where(sq.Expr("d.folder_id IN (SELECT descendant_id FROM "+r.schema+".folder_paths WHERE ancestor_id = ?)", *f.Folder))
+	case f.Folder != nil:
+		sb = sb.Where(sq.Eq{"d.folder_id": *f.Folder})
+	}
+
 	// фильтры key/value (белый список)
 	switch f.Key {
 	case "name":
@@ -174,6 +346,26 @@ func (r *PGRepo) DocsList(ctx context.Context, me domain.User, f domain.ListFilt
 		// неизвестный ключ — игнорируем
 	}
 
+	if f.Schema != nil {
+		sb = sb.Where(sq.Eq{"d.schema_id": *f.Schema})
+	}
+	if len(f.JSON) > 0 {
+		sb = sb.Where(r.jsonCond(f.JSON))
+	}
+
+	// теги и атрибуты: все условия должны выполняться
+	for _, t := range f.Tags {
+		sb = sb.Where(r.tagCond(t))
+	}
+	attrKeys := make([]string, 0, len(f.Attrs))
+	for k := range f.Attrs {
+		attrKeys = append(attrKeys, k)
+	}
+	sort.Strings(attrKeys)
+	for _, k := range attrKeys {
+		sb = sb.Where(r.attrCond(k, f.Attrs[k]))
+	}
+
 	switch f.Sort {
 	case domain.SortByNameAsc:
 		sb = sb.OrderBy("d.name ASC", "d.created_at DESC")
@@ -222,7 +414,7 @@ func (r *PGRepo) DocsList(ctx context.Context, me domain.User, f domain.ListFilt
 		if err := rows.Scan(
 			&d.ID, &d.OwnerID, &d.Name, &d.MIME, &d.File, &d.Public,
 			&d.SizeBytes, &d.StorageKey, &d.SHA256,
-			&d.Version, &d.CreatedAt, &d.UpdatedAt,
+			&d.Version, &d.CreatedAt, &d.UpdatedAt, &d.FolderID, &d.SchemaID, &d.Permission,
 		); err != nil {
 			r.logger.Printf("DocsList scan error: %v", err)
 			return nil, err
@@ -233,6 +425,11 @@ func (r *PGRepo) DocsList(ctx context.Context, me domain.User, f domain.ListFilt
 		r.logger.Printf("DocsList rows error: %v", err)
 		return nil, err
 	}
+	rows.Close()
+
+	if err := r.loadLabels(ctx, r.pool, res); err != nil {
+		return nil, err
+	}
 	r.logger.Printf("DocsList ok in %s count=%d", time.Since(start), len(res))
 	return res, nil
 }
@@ -258,59 +455,220 @@ func (r *PGRepo) Touch(ctx context.Context, id domain.DocID) error {
 	return nil
 }
 
+// UpdateDoc: версия в WHERE — параллельная правка с тем же If-Match не пройдёт.
+// Метаданные и JSON меняются в одной транзакции.
+func (r *PGRepo) UpdateDoc(ctx context.Context, id domain.DocID, version int64, u domain.DocUpdate) (domain.Document, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("UpdateDoc begin error: %v", err)
+		return domain.Document{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.qb().Update(fmt.Sprintf("%s.documents", r.schema)).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "version": version}).
+		Suffix("RETURNING id, owner_id, name, mime_type, file, public, size_bytes, storage_key, content_sha256, version, created_at, updated_at, folder_id, schema_id")
+	if u.Name != nil {
+		q = q.Set("name", *u.Name)
+	}
+	if u.MIME != nil {
+		q = q.Set("mime_type", *u.MIME)
+	}
+	if u.Public != nil {
+		q = q.Set("public", *u.Public)
+	}
+	if u.SetFolder {
+		q = q.Set("folder_id", u.FolderID)
+	}
+	if u.SetSchema {
+		q = q.Set("schema_id", u.SchemaID)
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpdateDoc", sqlStr, args)
+
+	var out domain.Document
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(
+		&out.ID, &out.OwnerID, &out.Name, &out.MIME, &out.File, &out.Public,
+		&out.SizeBytes, &out.StorageKey, &out.SHA256, &out.Version, &out.CreatedAt, &out.UpdatedAt, &out.FolderID, &out.SchemaID,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpdateDoc version mismatch in %s id=%s version=%d", time.Since(start), id, version)
+			return domain.Document{}, domain.ErrPrecondition
+		}
+		r.logger.Printf("UpdateDoc scan error after %s: %v", time.Since(start), err)
+		return domain.Document{}, err
+	}
+
+	// новое имя или новая папка — проверяем уникальность имён в папке
+	if (u.Name != nil || u.SetFolder) && out.FolderID != nil {
+		if err := r.lockFolderNames(ctx, tx, *out.FolderID); err != nil {
+			return domain.Document{}, err
+		}
+		taken, err := r.nameTaken(ctx, tx, *out.FolderID, out.Name, out.ID)
+		if err != nil {
+			return domain.Document{}, err
+		}
+		if taken {
+			r.logger.Printf("UpdateDoc name taken in %s id=%s folder=%s", time.Since(start), id, *out.FolderID)
+			return domain.Document{}, domain.ErrConflict
+		}
+	}
+
+	if u.SetJSON {
+		var qj sq.Sqlizer
+		if u.JSON == nil {
+			qj = r.qb().Delete(fmt.Sprintf("%s.doc_json", r.schema)).Where(sq.Eq{"doc_id": id})
+		} else {
+			payload, err := json.Marshal(u.JSON)
+			if err != nil {
+				r.logger.Printf("UpdateDoc marshal json error: %v", err)
+				return domain.Document{}, err
+			}
+			qj = r.qb().Insert(fmt.Sprintf("%s.doc_json", r.schema)).
+				Columns("doc_id", "body").Values(id, payload).
+				Suffix("ON CONFLICT (doc_id) DO UPDATE SET body = EXCLUDED.body")
+		}
+		sqlStr, args, _ = qj.ToSql()
+		r.logSQL("UpdateDoc.json", sqlStr, args)
+		if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+			r.logger.Printf("UpdateDoc.json exec error after %s: %v", time.Since(start), err)
+			return domain.Document{}, err
+		}
+	}
+
+	if u.SetTags {
+		if err := r.setTags(ctx, tx, id, u.Tags); err != nil {
+			return domain.Document{}, err
+		}
+	}
+	if u.SetAttrs {
+		if err := r.setAttrs(ctx, tx, id, u.Attrs); err != nil {
+			return domain.Document{}, err
+		}
+	}
+	one := []domain.Document{out}
+	if err := r.loadLabels(ctx, tx, one); err != nil {
+		return domain.Document{}, err
+	}
+	out = one[0]
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("UpdateDoc commit error after %s: %v", time.Since(start), err)
+		return domain.Document{}, err
+	}
+	r.logger.Printf("UpdateDoc ok in %s id=%s version=%d", time.Since(start), id, out.Version)
+	return out, nil
+}
+
 // ---------- SHARES ----------
 
-// Вставляет/обновляет грант чтения по логину пользователя (находит user_id по login).
-func (r *PGRepo) UpsertReadGrant(ctx context.Context, docID domain.DocID, login string, canRead bool) error {
+// Вставляет/обновляет грант по логину пользователя (находит user_id по login).
+// Нет такого логина — domain.ErrNotFound.
+func (r *PGRepo) UpsertGrant(ctx context.Context, docID domain.DocID, login string, perm domain.Permission) (domain.UserID, error) {
 	sub := r.qb().Select().
 		Column("? AS doc_id", docID).
 		Column("u.id AS user_id").
-		Column("? AS can_read", canRead).
+		Column("? AS permission", perm).
 		From(fmt.Sprintf("%s.users u", r.schema)).
 		Where(sq.Eq{"u.login": login})
 
 	q := r.qb().Insert(fmt.Sprintf("%s.doc_shares", r.schema)).
-		Columns("doc_id", "user_id", "can_read").
+		Columns("doc_id", "user_id", "permission").
 		Select(sub).
-		Suffix("ON CONFLICT (doc_id, user_id) DO UPDATE SET can_read = EXCLUDED.can_read")
+		Suffix("ON CONFLICT (doc_id, user_id) DO UPDATE SET permission = EXCLUDED.permission RETURNING user_id")
 
 	sqlStr, args, _ := q.ToSql()
-	r.logSQL("UpsertReadGrant", sqlStr, args)
+	r.logSQL("UpsertGrant", sqlStr, args)
 
 	start := time.Now()
-	_, err := r.pool.Exec(ctx, sqlStr, args...)
-	if err != nil {
-		r.logger.Printf("UpsertReadGrant exec error after %s: %v", time.Since(start), err)
-		return err
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpsertGrant unknown login in %s doc_id=%s login=%s", time.Since(start), docID, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
+		r.logger.Printf("UpsertGrant exec error after %s: %v", time.Since(start), err)
+		return uuid.Nil, err
 	}
-	r.logger.Printf("UpsertReadGrant ok in %s doc_id=%s login=%s", time.Since(start), docID, login)
-	return nil
+	r.logger.Printf("UpsertGrant ok in %s doc_id=%s login=%s perm=%s", time.Since(start), docID, login, perm)
+	return userID, nil
 }
 
-func (r *PGRepo) RemoveGrant(ctx context.Context, docID domain.DocID, login string) error {
+// Гранта не было — domain.ErrNotFound.
+func (r *PGRepo) RemoveGrant(ctx context.Context, docID domain.DocID, login string) (domain.UserID, error) {
 	q := r.qb().Delete(fmt.Sprintf("%s.doc_shares", r.schema)).
 		Where(sq.And{
 			sq.Eq{"doc_id": docID},
 			sq.Expr("user_id = (SELECT id FROM "+r.schema+".users WHERE login = ?)", login),
-		})
+		}).
+		Suffix("RETURNING user_id")
 	sqlStr, args, _ := q.ToSql()
 	r.logSQL("RemoveGrant", sqlStr, args)
 
 	start := time.Now()
-	_, err := r.pool.Exec(ctx, sqlStr, args...)
-	if err != nil {
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RemoveGrant no grant in %s doc_id=%s login=%s", time.Since(start), docID, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
 		r.logger.Printf("RemoveGrant exec error after %s: %v", time.Since(start), err)
-		return err
+		return uuid.Nil, err
 	}
 	r.logger.Printf("RemoveGrant ok in %s doc_id=%s login=%s", time.Since(start), docID, login)
-	return nil
+	return userID, nil
+}
+
+func (r *PGRepo) ListGrants(ctx context.Context, docID domain.DocID) ([]domain.DocShare, error) {
+	q := r.qb().Select("s.user_id", "u.login", "s.permission").
+		From(fmt.Sprintf("%s.doc_shares s", r.schema)).
+		Join(fmt.Sprintf("%s.users u ON u.id = s.user_id", r.schema)).
+		Where(sq.Eq{"s.doc_id": docID}).
+		OrderBy("u.login ASC")
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListGrants", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListGrants query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.DocShare{}
+	for rows.Next() {
+		sh := domain.DocShare{DocID: docID}
+		if err := rows.Scan(&sh.UserID, &sh.Login, &sh.Permission); err != nil {
+			r.logger.Printf("ListGrants scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListGrants rows error: %v", err)
+		return nil, err
+	}
+	rows.Close()
+
+	groups, err := r.listGroupGrants(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, groups...)
+	r.logger.Printf("ListGrants ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
 }
 
 func (r *PGRepo) ListGrantedLogins(ctx context.Context, docID domain.DocID) ([]string, error) {
 	q := r.qb().Select("u.login").
 		From(fmt.Sprintf("%s.doc_shares s", r.schema)).
 		Join(fmt.Sprintf("%s.users u ON u.id = s.user_id", r.schema)).
-		Where(sq.Eq{"s.doc_id": docID, "s.can_read": true}).
+		Where(sq.Eq{"s.doc_id": docID}).
 		OrderBy("u.login ASC")
 
 	sqlStr, args, _ := q.ToSql()
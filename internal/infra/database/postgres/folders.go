@@ -0,0 +1,612 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) folderCols() []string {
+	return []string{"f.id", "f.owner_id", "f.parent_id", "f.name", "f.unique_names", "f.created_at", "f.updated_at"}
+}
+
+func scanFolder(row pgx.Row, extra ...any) (domain.Folder, error) {
+	var f domain.Folder
+	dest := append([]any{&f.ID, &f.OwnerID, &f.ParentID, &f.Name, &f.UniqueNames, &f.CreatedAt, &f.UpdatedAt}, extra...)
+	err := row.Scan(dest...)
+	return f, err
+}
+
+// folderPermExpr — права пользователя на папку f: владелец → owner; иначе старший
+// грант на саму папку или её предка; иначе пустая строка.
+func (r *PGRepo) folderPermExpr(user domain.UserID) (string, []any) {
+	return `(CASE WHEN f.owner_id = ? THEN 'owner' ELSE COALESCE(
+		(SELECT fs.permission FROM ` + r.schema + `.folder_paths fp
+		JOIN ` + r.schema + `.folder_shares fs ON fs.folder_id = fp.ancestor_id
+		WHERE fp.descendant_id = f.id AND fs.user_id = ?
+		ORDER BY array_position(ARRAY['viewer', 'commenter', 'editor', 'co_owner'], fs.permission) DESC LIMIT 1),
+		'') END)`, []any{user, user}
+}
+
+// CreateFolder: папка и её строки замыкания (сама папка + предки родителя) — одной транзакцией.
+func (r *PGRepo) CreateFolder(ctx context.Context, f domain.Folder) (domain.Folder, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("CreateFolder begin error: %v", err)
+		return domain.Folder{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.qb().Insert(fmt.Sprintf("%s.folders", r.schema)).
+		Columns("owner_id", "parent_id", "name", "unique_names").
+		Values(f.OwnerID, f.ParentID, f.Name, f.UniqueNames).
+		Suffix("RETURNING id, owner_id, parent_id, name, unique_names, created_at, updated_at")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateFolder", sqlStr, args)
+
+	out, err := scanFolder(tx.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if isUniqueViolation(err) {
+			r.logger.Printf("CreateFolder name taken in %s name=%q", time.Since(start), f.Name)
+			return domain.Folder{}, domain.ErrConflict
+		}
+		r.logger.Printf("CreateFolder scan error after %s: %v", time.Since(start), err)
+		return domain.Folder{}, err
+	}
+
+	paths := fmt.Sprintf("%s.folder_paths", r.schema)
+	if out.ParentID != nil {
+		// предки родителя не должны поменяться до конца транзакции (см. UpdateFolder)
+		if err := r.lockFolderTree(ctx, tx, out.OwnerID); err != nil {
+			return domain.Folder{}, err
+		}
+	}
+	sq0 := r.qb().Insert(paths).
+		Columns("ancestor_id", "descendant_id", "depth").
+		Values(out.ID, out.ID, 0)
+	if err := r.execTx(ctx, tx, "CreateFolder.self", sq0); err != nil {
+		return domain.Folder{}, err
+	}
+	if out.ParentID != nil {
+		sub := r.qb().Select("ancestor_id").
+			Column("? AS descendant_id", out.ID).
+			Column("depth + 1").
+			From(paths).
+			Where(sq.Eq{"descendant_id": *out.ParentID})
+		pq := r.qb().Insert(paths).Columns("ancestor_id", "descendant_id", "depth").Select(sub)
+		if err := r.execTx(ctx, tx, "CreateFolder.ancestors", pq); err != nil {
+			return domain.Folder{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("CreateFolder commit error after %s: %v", time.Since(start), err)
+		return domain.Folder{}, err
+	}
+	r.logger.Printf("CreateFolder ok in %s id=%s name=%q", time.Since(start), out.ID, out.Name)
+	return out, nil
+}
+
+// lockFolderTree — advisory-блокировка дерева папок владельца до конца транзакции.
+func (r *PGRepo) lockFolderTree(ctx context.Context, tx pgx.Tx, owner domain.UserID) error {
+	q := r.qb().Select().Column(sq.Expr("pg_advisory_xact_lock(hashtextextended(?, 0))", "folders:"+owner.String()))
+	return r.execTx(ctx, tx, "lockFolderTree", q)
+}
+
+func (r *PGRepo) FolderByID(ctx context.Context, id uuid.UUID, user domain.UserID) (domain.Folder, error) {
+	permSQL, permArgs := r.folderPermExpr(user)
+	q := r.qb().Select(r.folderCols()...).
+		Column(sq.Expr(permSQL+" AS permission", permArgs...)).
+		From(fmt.Sprintf("%s.folders f", r.schema)).
+		Where(sq.Eq{"f.id": id}).
+		Where(sq.Expr(permSQL+" <> ''", permArgs...))
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("FolderByID", sqlStr, args)
+
+	start := time.Now()
+	var perm domain.Permission
+	f, err := scanFolder(r.pool.QueryRow(ctx, sqlStr, args...), &perm)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("FolderByID not found or no access in %s id=%s", time.Since(start), id)
+			return domain.Folder{}, domain.ErrNotFound
+		}
+		r.logger.Printf("FolderByID scan error after %s: %v", time.Since(start), err)
+		return domain.Folder{}, err
+	}
+	f.Permission = perm
+	r.logger.Printf("FolderByID ok in %s id=%s perm=%q", time.Since(start), id, perm)
+	return f, nil
+}
+
+// FolderByPath спускается от корня владельца по одному сегменту за запрос.
+func (r *PGRepo) FolderByPath(ctx context.Context, owner domain.UserID, names []string) (domain.Folder, error) {
+	start := time.Now()
+	var parent *uuid.UUID
+	for _, name := range names {
+		q := r.qb().Select("f.id").
+			From(fmt.Sprintf("%s.folders f", r.schema)).
+			Where(sq.Eq{"f.owner_id": owner, "f.name": name})
+		if parent == nil {
+			q = q.Where("f.parent_id IS NULL")
+		} else {
+			q = q.Where(sq.Eq{"f.parent_id": *parent})
+		}
+		sqlStr, args, _ := q.ToSql()
+		r.logSQL("FolderByPath", sqlStr, args)
+
+		var id uuid.UUID
+		if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				r.logger.Printf("FolderByPath not found in %s segment=%q", time.Since(start), name)
+				return domain.Folder{}, domain.ErrNotFound
+			}
+			r.logger.Printf("FolderByPath scan error after %s: %v", time.Since(start), err)
+			return domain.Folder{}, err
+		}
+		parent = &id
+	}
+	if parent == nil {
+		return domain.Folder{}, domain.ErrNotFound
+	}
+	return r.FolderByID(ctx, *parent, owner)
+}
+
+// FolderPath: права на папку наследуются вниз, поэтому доступные звенья пути —
+// всегда его хвост: у не-владельца путь начинается с верхней расшаренной папки.
+func (r *PGRepo) FolderPath(ctx context.Context, id uuid.UUID, user domain.UserID) ([]domain.FolderCrumb, error) {
+	q := r.qb().Select("f.id", "f.name").
+		From(fmt.Sprintf("%s.folder_paths fp", r.schema)).
+		Join(fmt.Sprintf("%s.folders f ON f.id = fp.ancestor_id", r.schema)).
+		Where(sq.Eq{"fp.descendant_id": id}).
+		Where(sq.Or{
+			sq.Eq{"f.owner_id": user},
+			sq.Expr("EXISTS (SELECT 1 FROM "+r.schema+".folder_paths up JOIN "+r.schema+
+				".folder_shares fs ON fs.folder_id = up.ancestor_id WHERE up.descendant_id = f.id AND fs.user_id = ?)", user),
+		}).
+		OrderBy("fp.depth DESC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("FolderPath", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("FolderPath query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.FolderCrumb{}
+	for rows.Next() {
+		var c domain.FolderCrumb
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			r.logger.Printf("FolderPath scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("FolderPath rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("FolderPath ok in %s id=%s depth=%d", time.Since(start), id, len(out))
+	return out, nil
+}
+
+func (r *PGRepo) ListFolders(ctx context.Context, user domain.UserID, parent *uuid.UUID) ([]domain.Folder, error) {
+	permSQL, permArgs := r.folderPermExpr(user)
+	q := r.qb().Select(r.folderCols()...).
+		Column(sq.Expr(permSQL+" AS permission", permArgs...)).
+		From(fmt.Sprintf("%s.folders f", r.schema)).
+		OrderBy("f.name ASC")
+	if parent != nil {
+		// доступ к родителю проверяет вызывающий; подпапки наследуют его
+		q = q.Where(sq.Eq{"f.parent_id": *parent})
+	} else {
+		q = q.Where(sq.Or{
+			sq.And{sq.Eq{"f.owner_id": user}, sq.Expr("f.parent_id IS NULL")},
+			sq.Expr("f.id IN (SELECT fs.folder_id FROM "+r.schema+".folder_shares fs WHERE fs.user_id = ?)", user),
+		})
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListFolders", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListFolders query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Folder{}
+	for rows.Next() {
+		var perm domain.Permission
+		f, err := scanFolder(rows, &perm)
+		if err != nil {
+			r.logger.Printf("ListFolders scan error: %v", err)
+			return nil, err
+		}
+		f.Permission = perm
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListFolders rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListFolders ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+// UpdateFolder. Перенос перестраивает замыкание поддерева: связи с прежними предками
+// удаляются, с новыми — добавляются. Переносы в одном дереве сериализуются
+// advisory-блокировкой владельца: два встречных переноса иначе могли бы дать цикл.
+func (r *PGRepo) UpdateFolder(ctx context.Context, id uuid.UUID, u domain.FolderUpdate) (domain.Folder, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("UpdateFolder begin error: %v", err)
+		return domain.Folder{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	folders := fmt.Sprintf("%s.folders", r.schema)
+	paths := fmt.Sprintf("%s.folder_paths", r.schema)
+
+	if u.Move {
+		oq := r.qb().Select("owner_id").From(folders).Where(sq.Eq{"id": id})
+		owner, err := r.queryIDs(ctx, tx, "UpdateFolder.owner", oq)
+		if err != nil {
+			return domain.Folder{}, err
+		}
+		if len(owner) == 0 {
+			r.logger.Printf("UpdateFolder not found in %s id=%s", time.Since(start), id)
+			return domain.Folder{}, domain.ErrNotFound
+		}
+		if err := r.lockFolderTree(ctx, tx, owner[0]); err != nil {
+			return domain.Folder{}, err
+		}
+		if u.ParentID != nil {
+			// новый родитель — сама папка или её потомок
+			cq := r.qb().Select("descendant_id").
+				From(paths).
+				Where(sq.Eq{"ancestor_id": id, "descendant_id": *u.ParentID})
+			inside, err := r.queryIDs(ctx, tx, "UpdateFolder.cycle", cq)
+			if err != nil {
+				return domain.Folder{}, err
+			}
+			if len(inside) > 0 {
+				r.logger.Printf("UpdateFolder move into own subtree in %s id=%s parent=%s", time.Since(start), id, *u.ParentID)
+				return domain.Folder{}, domain.ErrConflict
+			}
+		}
+	}
+
+	q := r.qb().Update(folders+" f").
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"f.id": id}).
+		Suffix("RETURNING f.id, f.owner_id, f.parent_id, f.name, f.unique_names, f.created_at, f.updated_at")
+	if u.Name != nil {
+		q = q.Set("name", *u.Name)
+	}
+	if u.UniqueNames != nil {
+		q = q.Set("unique_names", *u.UniqueNames)
+	}
+	if u.Move {
+		q = q.Set("parent_id", u.ParentID)
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpdateFolder", sqlStr, args)
+
+	out, err := scanFolder(tx.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpdateFolder not found in %s id=%s", time.Since(start), id)
+			return domain.Folder{}, domain.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			r.logger.Printf("UpdateFolder name taken in %s id=%s", time.Since(start), id)
+			return domain.Folder{}, domain.ErrConflict
+		}
+		r.logger.Printf("UpdateFolder scan error after %s: %v", time.Since(start), err)
+		return domain.Folder{}, err
+	}
+
+	if u.Move {
+		subtree := "SELECT descendant_id FROM " + paths + " WHERE ancestor_id = ?"
+		dq := r.qb().Delete(paths).
+			Where(sq.Expr("descendant_id IN ("+subtree+")", id)).
+			Where(sq.Expr("ancestor_id NOT IN ("+subtree+")", id))
+		if err := r.execTx(ctx, tx, "UpdateFolder.detach", dq); err != nil {
+			return domain.Folder{}, err
+		}
+		if u.ParentID != nil {
+			sub := r.qb().Select("p.ancestor_id", "c.descendant_id", "p.depth + c.depth + 1").
+				From(paths + " p").
+				CrossJoin(paths + " c").
+				Where(sq.Eq{"p.descendant_id": *u.ParentID, "c.ancestor_id": id})
+			aq := r.qb().Insert(paths).Columns("ancestor_id", "descendant_id", "depth").Select(sub)
+			if err := r.execTx(ctx, tx, "UpdateFolder.attach", aq); err != nil {
+				return domain.Folder{}, err
+			}
+		}
+	}
+
+	if u.UniqueNames != nil && *u.UniqueNames {
+		dup := r.qb().Select("d.name").
+			From(fmt.Sprintf("%s.documents d", r.schema)).
+			Where(sq.Eq{"d.folder_id": id}).
+			Where("d.deleted_at IS NULL").
+			GroupBy("d.name").
+			Having("count(*) > 1").
+			Limit(1)
+		dups, err := r.queryKeys(ctx, tx, "UpdateFolder.duplicates", dup)
+		if err != nil {
+			return domain.Folder{}, err
+		}
+		if len(dups) > 0 {
+			r.logger.Printf("UpdateFolder duplicate doc names in %s id=%s", time.Since(start), id)
+			return domain.Folder{}, domain.ErrConflict
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("UpdateFolder commit error after %s: %v", time.Since(start), err)
+		return domain.Folder{}, err
+	}
+	r.logger.Printf("UpdateFolder ok in %s id=%s", time.Since(start), id)
+	return out, nil
+}
+
+// DeleteFolder: подпапки, замыкание и гранты удаляются каскадом; документы
+// (в том числе уже лежащие в корзине) остаются без папки — в корне владельца.
+func (r *PGRepo) DeleteFolder(ctx context.Context, id uuid.UUID, recursive bool) ([]domain.Document, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("DeleteFolder begin error: %v", err)
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	subtree := sq.Expr("d.folder_id IN (SELECT descendant_id FROM "+r.schema+".folder_paths WHERE ancestor_id = ?)", id)
+	sel := r.qb().Select("d.id", "d.owner_id", "d.public").
+		From(fmt.Sprintf("%s.documents d", r.schema)).
+		Where(subtree).
+		Where("d.deleted_at IS NULL").
+		Suffix("FOR UPDATE")
+	sqlStr, args, _ := sel.ToSql()
+	r.logSQL("DeleteFolder.docs", sqlStr, args)
+
+	rows, err := tx.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("DeleteFolder.docs query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	docs := []domain.Document{}
+	ids := []domain.DocID{}
+	for rows.Next() {
+		var d domain.Document
+		if err := rows.Scan(&d.ID, &d.OwnerID, &d.Public); err != nil {
+			rows.Close()
+			r.logger.Printf("DeleteFolder.docs scan error: %v", err)
+			return nil, err
+		}
+		docs = append(docs, d)
+		ids = append(ids, d.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("DeleteFolder.docs rows error: %v", err)
+		return nil, err
+	}
+
+	if !recursive {
+		cq := r.qb().Select("descendant_id").
+			From(fmt.Sprintf("%s.folder_paths", r.schema)).
+			Where(sq.Eq{"ancestor_id": id}).
+			Where(sq.Gt{"depth": 0}).
+			Limit(1)
+		children, err := r.queryIDs(ctx, tx, "DeleteFolder.children", cq)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 0 || len(children) > 0 {
+			r.logger.Printf("DeleteFolder not empty in %s id=%s docs=%d", time.Since(start), id, len(docs))
+			return nil, domain.ErrConflict
+		}
+	}
+
+	if len(ids) > 0 {
+		// как DocDelete: версия растёт, запросы на передачу владения снимаются
+		uq := r.qb().Update(fmt.Sprintf("%s.documents", r.schema)).
+			Set("deleted_at", sq.Expr("now()")).
+			Set("deleted_by", sq.Expr("(SELECT owner_id FROM "+r.schema+".folders WHERE id = ?)", id)).
+			Set("version", sq.Expr("version + 1")).
+			Where(sq.Expr("id = ANY(?)", ids))
+		if err := r.execTx(ctx, tx, "DeleteFolder.trash", uq); err != nil {
+			return nil, err
+		}
+		tq := r.qb().Delete(fmt.Sprintf("%s.doc_transfers", r.schema)).Where(sq.Expr("doc_id = ANY(?)", ids))
+		if err := r.execTx(ctx, tx, "DeleteFolder.transfers", tq); err != nil {
+			return nil, err
+		}
+	}
+
+	fq := r.qb().Delete(fmt.Sprintf("%s.folders", r.schema)).Where(sq.Eq{"id": id})
+	sqlStr, args, _ = fq.ToSql()
+	r.logSQL("DeleteFolder", sqlStr, args)
+	tag, err := tx.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("DeleteFolder exec error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("DeleteFolder not found in %s id=%s", time.Since(start), id)
+		return nil, domain.ErrNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("DeleteFolder commit error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	r.logger.Printf("DeleteFolder ok in %s id=%s trashed=%d", time.Since(start), id, len(docs))
+	return docs, nil
+}
+
+// FolderAudience: владелец, гранты на папку, её предков и подпапки, владельцы
+// документов поддерева и получатели их грантов (лично и через группы).
+func (r *PGRepo) FolderAudience(ctx context.Context, id uuid.UUID) ([]domain.UserID, error) {
+	s := r.schema
+	q := r.qb().Select("f.owner_id").
+		From(fmt.Sprintf("%s.folders f", s)).
+		Where(sq.Eq{"f.id": id}).
+		Suffix(`UNION SELECT fs.user_id FROM `+s+`.folder_paths fp JOIN `+s+`.folder_shares fs
+			ON fs.folder_id = fp.ancestor_id WHERE fp.descendant_id = ?
+		UNION SELECT fs.user_id FROM `+s+`.folder_paths fp JOIN `+s+`.folder_shares fs
+			ON fs.folder_id = fp.descendant_id WHERE fp.ancestor_id = ?
+		UNION SELECT d.owner_id FROM `+s+`.documents d JOIN `+s+`.folder_paths fp
+			ON fp.descendant_id = d.folder_id WHERE fp.ancestor_id = ?
+		UNION SELECT ds.user_id FROM `+s+`.doc_shares ds JOIN `+s+`.documents d ON d.id = ds.doc_id
+			JOIN `+s+`.folder_paths fp ON fp.descendant_id = d.folder_id WHERE fp.ancestor_id = ?
+		UNION SELECT gm.user_id FROM `+s+`.doc_group_shares gs JOIN `+s+`.group_members gm
+			ON gm.group_id = gs.group_id JOIN `+s+`.documents d ON d.id = gs.doc_id
+			JOIN `+s+`.folder_paths fp ON fp.descendant_id = d.folder_id WHERE fp.ancestor_id = ?`,
+			id, id, id, id, id)
+	return r.queryIDs(ctx, r.pool, "FolderAudience", q)
+}
+
+// folderAudienceSQL — кому документы (выбранные условием docCond над d) видны через
+// папку: её владелец и получатели грантов на неё и её предков. Аргументы условия
+// передаются дважды.
+func (r *PGRepo) folderAudienceSQL(docCond string) string {
+	s := r.schema
+	return `SELECT f.owner_id FROM ` + s + `.documents d JOIN ` + s + `.folders f ON f.id = d.folder_id WHERE ` + docCond + `
+		UNION SELECT fs.user_id FROM ` + s + `.documents d JOIN ` + s + `.folder_paths fp ON fp.descendant_id = d.folder_id
+		JOIN ` + s + `.folder_shares fs ON fs.folder_id = fp.ancestor_id WHERE ` + docCond
+}
+
+// lockFolderNames блокирует строку папки с unique_names до конца транзакции: запись
+// документа в папку и проверка nameTaken идут под ней, поэтому две одновременные
+// загрузки (переносы, восстановления) с одним именем не пройдут обе. FOR NO KEY UPDATE
+// не конфликтует с FOR KEY SHARE от внешних ключей documents.folder_id.
+func (r *PGRepo) lockFolderNames(ctx context.Context, tx pgx.Tx, folderID uuid.UUID) error {
+	q := r.qb().Select("id").
+		From(fmt.Sprintf("%s.folders", r.schema)).
+		Where(sq.Eq{"id": folderID, "unique_names": true}).
+		Suffix("FOR NO KEY UPDATE")
+	_, err := r.queryIDs(ctx, tx, "lockFolderNames", q)
+	return err
+}
+
+// nameTaken: папка с unique_names уже содержит другой документ (не в корзине) с
+// таким именем. Вызывается в транзакции после lockFolderNames — иначе проверка
+// гонится с параллельной записью в ту же папку.
+func (r *PGRepo) nameTaken(ctx context.Context, db querier, folderID uuid.UUID, name string, except domain.DocID) (bool, error) {
+	q := r.qb().Select("d.id").
+		From(fmt.Sprintf("%s.documents d", r.schema)).
+		Join(fmt.Sprintf("%s.folders f ON f.id = d.folder_id", r.schema)).
+		Where(sq.Eq{"d.folder_id": folderID, "d.name": name, "f.unique_names": true}).
+		Where(sq.NotEq{"d.id": except}).
+		Where("d.deleted_at IS NULL").
+		Limit(1)
+	ids, err := r.queryIDs(ctx, db, "nameTaken", q)
+	return len(ids) > 0, err
+}
+
+// ---------- FOLDER SHARES ----------
+
+func (r *PGRepo) UpsertFolderGrant(ctx context.Context, id uuid.UUID, login string, perm domain.Permission) (domain.UserID, error) {
+	sub := r.qb().Select().
+		Column("? AS folder_id", id).
+		Column("u.id AS user_id").
+		Column("? AS permission", perm).
+		From(fmt.Sprintf("%s.users u", r.schema)).
+		Where(sq.Eq{"u.login": login})
+
+	q := r.qb().Insert(fmt.Sprintf("%s.folder_shares", r.schema)).
+		Columns("folder_id", "user_id", "permission").
+		Select(sub).
+		Suffix("ON CONFLICT (folder_id, user_id) DO UPDATE SET permission = EXCLUDED.permission RETURNING user_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpsertFolderGrant", sqlStr, args)
+
+	start := time.Now()
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpsertFolderGrant unknown login in %s folder_id=%s login=%s", time.Since(start), id, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
+		r.logger.Printf("UpsertFolderGrant exec error after %s: %v", time.Since(start), err)
+		return uuid.Nil, err
+	}
+	r.logger.Printf("UpsertFolderGrant ok in %s folder_id=%s login=%s perm=%s", time.Since(start), id, login, perm)
+	return userID, nil
+}
+
+func (r *PGRepo) RemoveFolderGrant(ctx context.Context, id uuid.UUID, login string) (domain.UserID, error) {
+	q := r.qb().Delete(fmt.Sprintf("%s.folder_shares", r.schema)).
+		Where(sq.And{
+			sq.Eq{"folder_id": id},
+			sq.Expr("user_id = (SELECT id FROM "+r.schema+".users WHERE login = ?)", login),
+		}).
+		Suffix("RETURNING user_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RemoveFolderGrant", sqlStr, args)
+
+	start := time.Now()
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RemoveFolderGrant no grant in %s folder_id=%s login=%s", time.Since(start), id, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
+		r.logger.Printf("RemoveFolderGrant exec error after %s: %v", time.Since(start), err)
+		return uuid.Nil, err
+	}
+	r.logger.Printf("RemoveFolderGrant ok in %s folder_id=%s login=%s", time.Since(start), id, login)
+	return userID, nil
+}
+
+func (r *PGRepo) ListFolderGrants(ctx context.Context, id uuid.UUID) ([]domain.FolderShare, error) {
+	q := r.qb().Select("s.user_id", "u.login", "s.permission").
+		From(fmt.Sprintf("%s.folder_shares s", r.schema)).
+		Join(fmt.Sprintf("%s.users u ON u.id = s.user_id", r.schema)).
+		Where(sq.Eq{"s.folder_id": id}).
+		OrderBy("u.login ASC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListFolderGrants", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListFolderGrants query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.FolderShare{}
+	for rows.Next() {
+		sh := domain.FolderShare{FolderID: id}
+		if err := rows.Scan(&sh.UserID, &sh.Login, &sh.Permission); err != nil {
+			r.logger.Printf("ListFolderGrants scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListFolderGrants rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListFolderGrants ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
@@ -0,0 +1,383 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) groupCols() []string {
+	return []string{
+		"g.id", "g.name", "g.created_by", "g.created_at",
+		"(SELECT count(*) FROM " + r.schema + ".group_members m WHERE m.group_id = g.id)",
+	}
+}
+
+func scanGroup(row pgx.Row) (domain.Group, error) {
+	var g domain.Group
+	err := row.Scan(&g.ID, &g.Name, &g.CreatedBy, &g.CreatedAt, &g.Members)
+	return g, err
+}
+
+// CreateGroup: группа и её первый администратор — в одной транзакции.
+func (r *PGRepo) CreateGroup(ctx context.Context, name string, creator domain.UserID) (domain.Group, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("CreateGroup begin error: %v", err)
+		return domain.Group{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.qb().Insert(fmt.Sprintf("%s.groups", r.schema)).
+		Columns("name", "created_by").
+		Values(name, creator).
+		Suffix("RETURNING id, name, created_by, created_at")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateGroup", sqlStr, args)
+
+	var g domain.Group
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(&g.ID, &g.Name, &g.CreatedBy, &g.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			r.logger.Printf("CreateGroup name taken in %s name=%q", time.Since(start), name)
+			return domain.Group{}, domain.ErrConflict
+		}
+		r.logger.Printf("CreateGroup scan error after %s: %v", time.Since(start), err)
+		return domain.Group{}, err
+	}
+
+	qm := r.qb().Insert(fmt.Sprintf("%s.group_members", r.schema)).
+		Columns("group_id", "user_id", "is_admin").
+		Values(g.ID, creator, true)
+	sqlStr, args, _ = qm.ToSql()
+	r.logSQL("CreateGroup.member", sqlStr, args)
+	if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("CreateGroup.member exec error after %s: %v", time.Since(start), err)
+		return domain.Group{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("CreateGroup commit error after %s: %v", time.Since(start), err)
+		return domain.Group{}, err
+	}
+	g.Members = 1
+	r.logger.Printf("CreateGroup ok in %s id=%s name=%q", time.Since(start), g.ID, g.Name)
+	return g, nil
+}
+
+func (r *PGRepo) GroupByID(ctx context.Context, id uuid.UUID) (domain.Group, error) {
+	q := r.qb().Select(r.groupCols()...).
+		From(fmt.Sprintf("%s.groups g", r.schema)).
+		Where(sq.Eq{"g.id": id})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("GroupByID", sqlStr, args)
+
+	start := time.Now()
+	g, err := scanGroup(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("GroupByID not found in %s id=%s", time.Since(start), id)
+			return domain.Group{}, domain.ErrNotFound
+		}
+		r.logger.Printf("GroupByID scan error after %s: %v", time.Since(start), err)
+		return domain.Group{}, err
+	}
+	r.logger.Printf("GroupByID ok in %s id=%s", time.Since(start), g.ID)
+	return g, nil
+}
+
+func (r *PGRepo) ListGroups(ctx context.Context, member *domain.UserID) ([]domain.Group, error) {
+	q := r.qb().Select(r.groupCols()...).
+		From(fmt.Sprintf("%s.groups g", r.schema)).
+		OrderBy("g.name ASC")
+	if member != nil {
+		q = q.Where(sq.Expr("g.id IN (SELECT group_id FROM "+r.schema+".group_members WHERE user_id = ?)", *member))
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListGroups", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListGroups query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			r.logger.Printf("ListGroups scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListGroups rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListGroups ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+// DeleteGroup: участники (и групповые гранты) удаляются каскадом, поэтому
+// состав читаем до удаления.
+func (r *PGRepo) DeleteGroup(ctx context.Context, id uuid.UUID) ([]domain.UserID, error) {
+	members, err := r.groupMemberIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.qb().Delete(fmt.Sprintf("%s.groups", r.schema)).Where(sq.Eq{"id": id})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("DeleteGroup", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("DeleteGroup exec error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("DeleteGroup no rows affected in %s id=%s", time.Since(start), id)
+		return nil, domain.ErrNotFound
+	}
+	r.logger.Printf("DeleteGroup ok in %s id=%s members=%d", time.Since(start), id, len(members))
+	return members, nil
+}
+
+func (r *PGRepo) GroupMembers(ctx context.Context, id uuid.UUID) ([]domain.GroupMember, error) {
+	q := r.qb().Select("m.user_id", "u.login", "m.is_admin", "m.added_at").
+		From(fmt.Sprintf("%s.group_members m", r.schema)).
+		Join(fmt.Sprintf("%s.users u ON u.id = m.user_id", r.schema)).
+		Where(sq.Eq{"m.group_id": id}).
+		OrderBy("u.login ASC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("GroupMembers", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("GroupMembers query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.GroupMember{}
+	for rows.Next() {
+		var m domain.GroupMember
+		if err := rows.Scan(&m.UserID, &m.Login, &m.Admin, &m.AddedAt); err != nil {
+			r.logger.Printf("GroupMembers scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("GroupMembers rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("GroupMembers ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) UpsertGroupMember(ctx context.Context, groupID uuid.UUID, login string, admin bool) (domain.UserID, error) {
+	sub := r.qb().Select().
+		Column("? AS group_id", groupID).
+		Column("u.id AS user_id").
+		Column("? AS is_admin", admin).
+		From(fmt.Sprintf("%s.users u", r.schema)).
+		Where(sq.Eq{"u.login": login})
+
+	q := r.qb().Insert(fmt.Sprintf("%s.group_members", r.schema)).
+		Columns("group_id", "user_id", "is_admin").
+		Select(sub).
+		Suffix("ON CONFLICT (group_id, user_id) DO UPDATE SET is_admin = EXCLUDED.is_admin RETURNING user_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpsertGroupMember", sqlStr, args)
+
+	start := time.Now()
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpsertGroupMember unknown login in %s group_id=%s login=%s", time.Since(start), groupID, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
+		r.logger.Printf("UpsertGroupMember exec error after %s: %v", time.Since(start), err)
+		return uuid.Nil, err
+	}
+	r.logger.Printf("UpsertGroupMember ok in %s group_id=%s login=%s admin=%v", time.Since(start), groupID, login, admin)
+	return userID, nil
+}
+
+func (r *PGRepo) RemoveGroupMember(ctx context.Context, groupID uuid.UUID, login string) (domain.UserID, error) {
+	q := r.qb().Delete(fmt.Sprintf("%s.group_members", r.schema)).
+		Where(sq.And{
+			sq.Eq{"group_id": groupID},
+			sq.Expr("user_id = (SELECT id FROM "+r.schema+".users WHERE login = ?)", login),
+		}).
+		Suffix("RETURNING user_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RemoveGroupMember", sqlStr, args)
+
+	start := time.Now()
+	var userID domain.UserID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RemoveGroupMember not a member in %s group_id=%s login=%s", time.Since(start), groupID, login)
+			return uuid.Nil, domain.ErrNotFound
+		}
+		r.logger.Printf("RemoveGroupMember exec error after %s: %v", time.Since(start), err)
+		return uuid.Nil, err
+	}
+	r.logger.Printf("RemoveGroupMember ok in %s group_id=%s login=%s", time.Since(start), groupID, login)
+	return userID, nil
+}
+
+// ---------- GROUP SHARES ----------
+
+func (r *PGRepo) UpsertGroupGrant(ctx context.Context, docID domain.DocID, group string, perm domain.Permission) ([]domain.UserID, error) {
+	sub := r.qb().Select().
+		Column("? AS doc_id", docID).
+		Column("g.id AS group_id").
+		Column("? AS permission", perm).
+		From(fmt.Sprintf("%s.groups g", r.schema)).
+		Where(sq.Eq{"g.name": group})
+
+	q := r.qb().Insert(fmt.Sprintf("%s.doc_group_shares", r.schema)).
+		Columns("doc_id", "group_id", "permission").
+		Select(sub).
+		Suffix("ON CONFLICT (doc_id, group_id) DO UPDATE SET permission = EXCLUDED.permission RETURNING group_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpsertGroupGrant", sqlStr, args)
+
+	start := time.Now()
+	var groupID uuid.UUID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&groupID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UpsertGroupGrant unknown group in %s doc_id=%s group=%s", time.Since(start), docID, group)
+			return nil, domain.ErrNotFound
+		}
+		r.logger.Printf("UpsertGroupGrant exec error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	r.logger.Printf("UpsertGroupGrant ok in %s doc_id=%s group=%s perm=%s", time.Since(start), docID, group, perm)
+	return r.groupMemberIDs(ctx, groupID)
+}
+
+func (r *PGRepo) RemoveGroupGrant(ctx context.Context, docID domain.DocID, group string) ([]domain.UserID, error) {
+	q := r.qb().Delete(fmt.Sprintf("%s.doc_group_shares", r.schema)).
+		Where(sq.And{
+			sq.Eq{"doc_id": docID},
+			sq.Expr("group_id = (SELECT id FROM "+r.schema+".groups WHERE name = ?)", group),
+		}).
+		Suffix("RETURNING group_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RemoveGroupGrant", sqlStr, args)
+
+	start := time.Now()
+	var groupID uuid.UUID
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&groupID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RemoveGroupGrant no grant in %s doc_id=%s group=%s", time.Since(start), docID, group)
+			return nil, domain.ErrNotFound
+		}
+		r.logger.Printf("RemoveGroupGrant exec error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	r.logger.Printf("RemoveGroupGrant ok in %s doc_id=%s group=%s", time.Since(start), docID, group)
+	return r.groupMemberIDs(ctx, groupID)
+}
+
+func (r *PGRepo) listGroupGrants(ctx context.Context, docID domain.DocID) ([]domain.DocShare, error) {
+	q := r.qb().Select("g.name", "gs.permission").
+		From(fmt.Sprintf("%s.doc_group_shares gs", r.schema)).
+		Join(fmt.Sprintf("%s.groups g ON g.id = gs.group_id", r.schema)).
+		Where(sq.Eq{"gs.doc_id": docID}).
+		OrderBy("g.name ASC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("listGroupGrants", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("listGroupGrants query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.DocShare
+	for rows.Next() {
+		sh := domain.DocShare{DocID: docID}
+		if err := rows.Scan(&sh.Group, &sh.Permission); err != nil {
+			r.logger.Printf("listGroupGrants scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("listGroupGrants rows error: %v", err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// DocAudience: личные гранты + участники групп с грантом + владелец папки документа
+// и гранты на неё и её предков.
+func (r *PGRepo) DocAudience(ctx context.Context, docID domain.DocID) ([]domain.UserID, error) {
+	q := r.qb().Select("s.user_id").
+		From(fmt.Sprintf("%s.doc_shares s", r.schema)).
+		Where(sq.Eq{"s.doc_id": docID}).
+		Suffix("UNION SELECT gm.user_id FROM "+r.schema+".doc_group_shares gs JOIN "+r.schema+
+			".group_members gm ON gm.group_id = gs.group_id WHERE gs.doc_id = ?"+
+			" UNION "+r.folderAudienceSQL("d.id = ?"), docID, docID, docID)
+	return r.queryIDs(ctx, r.pool, "DocAudience", q)
+}
+
+func (r *PGRepo) groupMemberIDs(ctx context.Context, groupID uuid.UUID) ([]domain.UserID, error) {
+	q := r.qb().Select("user_id").
+		From(fmt.Sprintf("%s.group_members", r.schema)).
+		Where(sq.Eq{"group_id": groupID})
+	return r.queryIDs(ctx, r.pool, "groupMemberIDs", q)
+}
+
+// querier: пул или транзакция.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *PGRepo) queryIDs(ctx context.Context, db querier, name string, q sq.SelectBuilder) ([]domain.UserID, error) {
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL(name, sqlStr, args)
+
+	start := time.Now()
+	rows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("%s query error after %s: %v", name, time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.UserID
+	for rows.Next() {
+		var id domain.UserID
+		if err := rows.Scan(&id); err != nil {
+			r.logger.Printf("%s scan error: %v", name, err)
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("%s rows error: %v", name, err)
+		return nil, err
+	}
+	r.logger.Printf("%s ok in %s count=%d", name, time.Since(start), len(out))
+	return out, nil
+}
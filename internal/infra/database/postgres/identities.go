@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) UserByExternalIdentity(ctx context.Context, issuer, subject string) (domain.User, error) {
+	q := r.qb().Select(userCols...).
+		From(fmt.Sprintf("%s.users", r.schema)).
+		Where(sq.Eq{"ext_issuer": issuer, "ext_subject": subject})
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UserByExternalIdentity", sqlStr, args)
+
+	start := time.Now()
+	u, err := scanUser(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UserByExternalIdentity not found in %s", time.Since(start))
+			return domain.User{}, domain.ErrNotFound
+		}
+		r.logger.Printf("UserByExternalIdentity scan error after %s: %v", time.Since(start), err)
+		return domain.User{}, err
+	}
+	r.logger.Printf("UserByExternalIdentity ok in %s id=%s", time.Since(start), u.ID)
+	return u, nil
+}
+
+// LinkExternalIdentity привязывает (или перепривязывает) внешнюю учётку к пользователю.
+// У пользователя может быть только одна внешняя учётка.
+func (r *PGRepo) LinkExternalIdentity(ctx context.Context, userID domain.UserID, ident domain.ExternalIdentity) error {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("ext_issuer", ident.Issuer).
+		Set("ext_subject", ident.Subject).
+		Set("ext_email", nullIfEmpty(ident.Email)).
+		Where(sq.Eq{"id": userID})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("LinkExternalIdentity", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			r.logger.Printf("LinkExternalIdentity identity already linked in %s", time.Since(start))
+			return domain.ErrBadParams
+		}
+		r.logger.Printf("LinkExternalIdentity exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("LinkExternalIdentity no rows affected in %s id=%s", time.Since(start), userID)
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("LinkExternalIdentity ok in %s id=%s issuer=%s", time.Since(start), userID, ident.Issuer)
+	return nil
+}
+
+// CreateExternalUser — пользователь без локального пароля (пустой хэш не проходит проверку).
+func (r *PGRepo) CreateExternalUser(ctx context.Context, login string, role domain.Role, ident domain.ExternalIdentity) (domain.User, error) {
+	if role == "" {
+		role = domain.RoleUser
+	}
+	q := r.qb().Insert(fmt.Sprintf("%s.users", r.schema)).
+		Columns("login", "pass_hash", "role", "ext_issuer", "ext_subject", "ext_email").
+		Values(login, []byte{}, role, ident.Issuer, ident.Subject, nullIfEmpty(ident.Email)).
+		Suffix("RETURNING " + joinCols(userCols))
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateExternalUser", sqlStr, args)
+
+	start := time.Now()
+	u, err := scanUser(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if isUniqueViolation(err) {
+			r.logger.Printf("CreateExternalUser conflict in %s login=%s", time.Since(start), login)
+			return domain.User{}, domain.ErrBadParams
+		}
+		r.logger.Printf("CreateExternalUser scan error after %s: %v", time.Since(start), err)
+		return domain.User{}, err
+	}
+	r.logger.Printf("CreateExternalUser ok in %s id=%s login=%s", time.Since(start), u.ID, u.Login)
+	return u, nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+var inviteCols = []string{
+	"id", "code_hash", "created_by", "role", "max_uses", "uses", "expires_at", "created_at", "revoked_at",
+}
+
+func scanInvite(row pgx.Row) (domain.Invite, error) {
+	var inv domain.Invite
+	err := row.Scan(
+		&inv.ID, &inv.CodeHash, &inv.CreatedBy, &inv.Role, &inv.MaxUses, &inv.Uses,
+		&inv.ExpiresAt, &inv.CreatedAt, &inv.RevokedAt,
+	)
+	return inv, err
+}
+
+func (r *PGRepo) CreateInvite(ctx context.Context, inv domain.Invite) (domain.Invite, error) {
+	q := r.qb().Insert(fmt.Sprintf("%s.invites", r.schema)).
+		Columns("code_hash", "created_by", "role", "max_uses", "expires_at").
+		Values(inv.CodeHash, inv.CreatedBy, inv.Role, inv.MaxUses, inv.ExpiresAt).
+		Suffix("RETURNING " + joinCols(inviteCols))
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateInvite", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanInvite(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("CreateInvite scan error after %s: %v", time.Since(start), err)
+		return domain.Invite{}, err
+	}
+	r.logger.Printf("CreateInvite ok in %s id=%s role=%s max_uses=%d", time.Since(start), out.ID, out.Role, out.MaxUses)
+	return out, nil
+}
+
+func (r *PGRepo) ListInvites(ctx context.Context, createdBy *domain.UserID) ([]domain.Invite, error) {
+	q := r.qb().Select(inviteCols...).
+		From(fmt.Sprintf("%s.invites", r.schema)).
+		OrderBy("created_at DESC")
+	if createdBy != nil {
+		q = q.Where(sq.Eq{"created_by": *createdBy})
+	}
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListInvites", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListInvites query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Invite{}
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			r.logger.Printf("ListInvites scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListInvites rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListInvites ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) RevokeInvite(ctx context.Context, id uuid.UUID, createdBy *domain.UserID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.invites", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "revoked_at": nil})
+	if createdBy != nil {
+		q = q.Where(sq.Eq{"created_by": *createdBy})
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeInvite", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokeInvite exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("RevokeInvite no rows affected in %s (not found, not owner or already revoked)", time.Since(start))
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("RevokeInvite ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+// RegisterWithInvite: UPDATE ... WHERE uses < max_uses атомарно гасит использование
+// (строка блокируется до конца транзакции), затем создаётся пользователь.
+// Если логин занят — транзакция откатывается и использование не тратится.
+func (r *PGRepo) RegisterWithInvite(ctx context.Context, codeHash []byte, login string, passHash []byte) (domain.User, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("RegisterWithInvite begin error: %v", err)
+		return domain.User{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	use := r.qb().Update(fmt.Sprintf("%s.invites", r.schema)).
+		Set("uses", sq.Expr("uses + 1")).
+		Where(sq.Eq{"code_hash": codeHash, "revoked_at": nil}).
+		Where("uses < max_uses").
+		Where(sq.Or{sq.Eq{"expires_at": nil}, sq.Expr("expires_at > now()")}).
+		Suffix("RETURNING id, role, created_by")
+	sqlStr, args, _ := use.ToSql()
+	r.logSQL("RegisterWithInvite.use", sqlStr, args)
+
+	var (
+		inviteID  uuid.UUID
+		role      string
+		invitedBy *domain.UserID
+	)
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(&inviteID, &role, &invitedBy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RegisterWithInvite invite not usable after %s", time.Since(start))
+			return domain.User{}, domain.ErrUnauth
+		}
+		r.logger.Printf("RegisterWithInvite use error after %s: %v", time.Since(start), err)
+		return domain.User{}, err
+	}
+
+	ins := r.qb().Insert(fmt.Sprintf("%s.users", r.schema)).
+		Columns("login", "pass_hash", "role", "invited_by", "invite_id").
+		Values(login, passHash, role, invitedBy, inviteID).
+		Suffix("RETURNING " + joinCols(userCols))
+	sqlStr, args, _ = ins.ToSql()
+	r.logSQL("RegisterWithInvite.user", sqlStr, args)
+	u, err := scanUser(tx.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("RegisterWithInvite insert user error after %s: %v", time.Since(start), err)
+		return domain.User{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("RegisterWithInvite commit error after %s: %v", time.Since(start), err)
+		return domain.User{}, err
+	}
+	r.logger.Printf("RegisterWithInvite ok in %s user_id=%s invite_id=%s", time.Since(start), u.ID, inviteID)
+	return u, nil
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// jsonCond: все условия на тело в одном подзапросе к doc_json — GIN-индекс
+// idx_doc_json_body обслуживает и @>, и @?. Значения и пути уходят параметрами.
+func (r *PGRepo) jsonCond(fs []domain.JSONFilter) sq.Sqlizer {
+	// плейсхолдеры подзапроса перенумерует внешний запрос; "??" — оператор @?
+	sub := sq.Select("j.doc_id").From(fmt.Sprintf("%s.doc_json j", r.schema))
+	for _, f := range fs {
+		switch f.Op {
+		case domain.JSONContains:
+			sub = sub.Where(sq.Expr("j.body @> ?::jsonb", string(f.Value)))
+		case domain.JSONEq:
+			sub = sub.Where(sq.Expr("j.body @?? ?::jsonpath", pgJSONPath(f.Path)+" ? (@ == "+string(f.Value)+")"))
+		case domain.JSONExists:
+			sub = sub.Where(sq.Expr("j.body @?? ?::jsonpath", pgJSONPath(f.Path)))
+		}
+	}
+	subSQL, subArgs, _ := sub.ToSql()
+	return sq.Expr("d.id IN ("+subSQL+")", subArgs...)
+}
+
+// pgJSONPath — путь в синтаксисе jsonpath Postgres (lax): ключи всегда в кавычках,
+// поэтому ни один символ ключа не становится частью выражения.
+func pgJSONPath(p domain.JSONPath) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range p {
+		if seg.Elem {
+			b.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+			continue
+		}
+		q, _ := json.Marshal(seg.Key)
+		b.WriteString("." + string(q))
+	}
+	return b.String()
+}
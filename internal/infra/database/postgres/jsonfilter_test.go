@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func mustPath(t *testing.T, s string) domain.JSONPath {
+	t.Helper()
+	p, ok := domain.ParseJSONPath(s)
+	if !ok {
+		t.Fatalf("bad test path %q", s)
+	}
+	return p
+}
+
+func TestJSONCond(t *testing.T) {
+	r := &PGRepo{schema: "docs"}
+	const prefix = "SELECT d.id FROM docs.documents d WHERE d.owner_id = $1 AND d.id IN (SELECT j.doc_id FROM docs.doc_json j WHERE "
+
+	tests := []struct {
+		name     string
+		filters  []domain.JSONFilter
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			"contains",
+			[]domain.JSONFilter{{Op: domain.JSONContains, Value: []byte(`{"a":1}`)}},
+			prefix + "j.body @> $2::jsonb) LIMIT 5",
+			[]any{7, `{"a":1}`},
+		},
+		{
+			"eq",
+			[]domain.JSONFilter{{Op: domain.JSONEq, Path: mustPath(t, "customer.name"), Value: []byte(`"ACME"`)}},
+			prefix + "j.body @? $2::jsonpath) LIMIT 5",
+			[]any{7, `$."customer"."name" ? (@ == "ACME")`},
+		},
+		{
+			"exists",
+			[]domain.JSONFilter{{Op: domain.JSONExists, Path: mustPath(t, "items[0]")}},
+			prefix + "j.body @? $2::jsonpath) LIMIT 5",
+			[]any{7, `$."items"[0]`},
+		},
+		{
+			// все условия — в одном подзапросе, плейсхолдеры продолжают нумерацию внешнего запроса
+			"несколько условий",
+			[]domain.JSONFilter{
+				{Op: domain.JSONContains, Value: []byte(`{"a":1}`)},
+				{Op: domain.JSONEq, Path: mustPath(t, "total"), Value: []byte(`10`)},
+				{Op: domain.JSONExists, Path: mustPath(t, `$["a\"b"][0]`)},
+			},
+			prefix + "j.body @> $2::jsonb AND j.body @? $3::jsonpath AND j.body @? $4::jsonpath) LIMIT 5",
+			[]any{7, `{"a":1}`, `$."total" ? (@ == 10)`, `$."a\"b"[0]`},
+		},
+		{
+			// ключ с кавычками и операторами jsonpath остаётся строкой внутри параметра
+			"ключ-инъекция",
+			[]domain.JSONFilter{{Op: domain.JSONExists, Path: mustPath(t, `$["x\" || @ == 1 || \"y"]`)}},
+			prefix + "j.body @? $2::jsonpath) LIMIT 5",
+			[]any{7, `$."x\" || @ == 1 || \"y"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlStr, args, err := r.qb().Select("d.id").From("docs.documents d").
+				Where(sq.Eq{"d.owner_id": 7}).
+				Where(r.jsonCond(tt.filters)).
+				Limit(5).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sqlStr != tt.wantSQL {
+				t.Errorf("sql =\n%s\nwant\n%s", sqlStr, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPgJSONPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a", `$."a"`},
+		{"a.b[2].c", `$."a"."b"[2]."c"`},
+		{"[0]", `$[0]`},
+		{`$["with space"]`, `$."with space"`},
+		{`$["a\\b"]`, `$."a\\b"`},
+		{"заказ", `$."заказ"`},
+		{"last", `$."last"`},
+	}
+	for _, tt := range tests {
+		if got := pgJSONPath(mustPath(t, tt.in)); got != tt.want {
+			t.Errorf("pgJSONPath(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// setTags заменяет теги документа целиком (теги уже нормализованы); только внутри
+// транзакции создания или изменения документа.
+func (r *PGRepo) setTags(ctx context.Context, tx pgx.Tx, id domain.DocID, tags []string) error {
+	del := r.qb().Delete(fmt.Sprintf("%s.doc_tags", r.schema)).Where(sq.Eq{"doc_id": id})
+	if err := r.execTx(ctx, tx, "setTags.delete", del); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	ins := r.qb().Insert(fmt.Sprintf("%s.doc_tags", r.schema)).Columns("doc_id", "tag")
+	for _, t := range tags {
+		ins = ins.Values(id, t)
+	}
+	return r.execTx(ctx, tx, "setTags.insert", ins)
+}
+
+// setAttrs заменяет атрибуты документа целиком (значения уже нормализованы).
+func (r *PGRepo) setAttrs(ctx context.Context, tx pgx.Tx, id domain.DocID, attrs map[string]domain.DocAttr) error {
+	del := r.qb().Delete(fmt.Sprintf("%s.doc_attrs", r.schema)).Where(sq.Eq{"doc_id": id})
+	if err := r.execTx(ctx, tx, "setAttrs.delete", del); err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ins := r.qb().Insert(fmt.Sprintf("%s.doc_attrs", r.schema)).
+		Columns("doc_id", "key", "type", "value", "num_value", "date_value")
+	for _, k := range keys {
+		a := attrs[k]
+		var num, date any // NULL для остальных типов
+		switch a.Type {
+		case domain.AttrNumber:
+			num = a.Value
+		case domain.AttrDate:
+			t, err := time.Parse(time.DateOnly, domain.AttrText(a))
+			if err != nil {
+				return err
+			}
+			date = t
+		}
+		ins = ins.Values(id, k, a.Type, domain.AttrText(a), num, date)
+	}
+	return r.execTx(ctx, tx, "setAttrs.insert", ins)
+}
+
+// loadLabels дозаполняет теги и атрибуты документов — двумя запросами на всю пачку.
+func (r *PGRepo) loadLabels(ctx context.Context, db querier, docs []domain.Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	ids := make([]domain.DocID, len(docs))
+	byID := make(map[domain.DocID]*domain.Document, len(docs))
+	for i := range docs {
+		ids[i] = docs[i].ID
+		byID[docs[i].ID] = &docs[i]
+	}
+
+	start := time.Now()
+	tq := r.qb().Select("doc_id", "tag").
+		From(fmt.Sprintf("%s.doc_tags", r.schema)).
+		Where(sq.Eq{"doc_id": ids}).
+		OrderBy("tag ASC")
+	sqlStr, args, _ := tq.ToSql()
+	r.logSQL("loadLabels.tags", sqlStr, args)
+
+	rows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("loadLabels.tags query error after %s: %v", time.Since(start), err)
+		return err
+	}
+	for rows.Next() {
+		var (
+			id  domain.DocID
+			tag string
+		)
+		if err := rows.Scan(&id, &tag); err != nil {
+			rows.Close()
+			r.logger.Printf("loadLabels.tags scan error: %v", err)
+			return err
+		}
+		d := byID[id]
+		d.Tags = append(d.Tags, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("loadLabels.tags rows error: %v", err)
+		return err
+	}
+
+	aq := r.qb().Select("doc_id", "key", "type", "value").
+		From(fmt.Sprintf("%s.doc_attrs", r.schema)).
+		Where(sq.Eq{"doc_id": ids})
+	sqlStr, args, _ = aq.ToSql()
+	r.logSQL("loadLabels.attrs", sqlStr, args)
+
+	rows, err = db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("loadLabels.attrs query error after %s: %v", time.Since(start), err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id             domain.DocID
+			key, typ, text string
+		)
+		if err := rows.Scan(&id, &key, &typ, &text); err != nil {
+			r.logger.Printf("loadLabels.attrs scan error: %v", err)
+			return err
+		}
+		a := domain.DocAttr{Type: typ, Value: text}
+		if typ == domain.AttrNumber {
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				r.logger.Printf("loadLabels.attrs bad number doc_id=%s key=%q: %v", id, key, err)
+				return err
+			}
+			a.Value = n
+		}
+		d := byID[id]
+		if d.Attrs == nil {
+			d.Attrs = map[string]domain.DocAttr{}
+		}
+		d.Attrs[key] = a
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("loadLabels.attrs rows error: %v", err)
+		return err
+	}
+	r.logger.Printf("loadLabels ok in %s docs=%d", time.Since(start), len(docs))
+	return nil
+}
+
+// tagCond: у документа есть тег.
+func (r *PGRepo) tagCond(tag string) sq.Sqlizer {
+	return sq.Expr("d.id IN (SELECT t.doc_id FROM "+r.schema+".doc_tags t WHERE t.tag = ?)", tag)
+}
+
+// attrCond: атрибут key равен значению. Значение сравнивается с канонической
+// записью, а если оно разбирается как число или дата — и с типизированной копией
+// (attr.amount=10.0 находит number 10).
+func (r *PGRepo) attrCond(key, val string) sq.Sqlizer {
+	match := sq.Or{sq.Eq{"a.value": val}}
+	if n, err := strconv.ParseFloat(val, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		match = append(match, sq.Eq{"a.num_value": n})
+	}
+	if t, err := time.Parse(time.DateOnly, val); err == nil {
+		match = append(match, sq.Eq{"a.date_value": t})
+	}
+	// плейсхолдеры подзапроса перенумерует внешний запрос
+	sub := sq.Select("a.doc_id").
+		From(fmt.Sprintf("%s.doc_attrs a", r.schema)).
+		Where(sq.Eq{"a.key": key}).
+		Where(match)
+	subSQL, subArgs, _ := sub.ToSql()
+	return sq.Expr("d.id IN ("+subSQL+")", subArgs...)
+}
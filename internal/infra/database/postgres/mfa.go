@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) TOTPState(ctx context.Context, userID domain.UserID) (domain.TOTPState, error) {
+	q := r.qb().Select("totp_secret", "totp_enabled", "totp_last_step").
+		From(fmt.Sprintf("%s.users", r.schema)).
+		Where(sq.Eq{"id": userID})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("TOTPState", sqlStr, args)
+
+	start := time.Now()
+	var st domain.TOTPState
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&st.Secret, &st.Enabled, &st.LastStep); err != nil {
+		r.logger.Printf("TOTPState scan error after %s: %v", time.Since(start), err)
+		return domain.TOTPState{}, err
+	}
+	r.logger.Printf("TOTPState ok in %s user_id=%s enabled=%t", time.Since(start), userID, st.Enabled)
+	return st, nil
+}
+
+func (r *PGRepo) SetTOTPSecret(ctx context.Context, userID domain.UserID, sealed []byte) error {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("totp_secret", sealed).
+		Set("totp_last_step", 0).
+		Where(sq.Eq{"id": userID, "totp_enabled": false})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("SetTOTPSecret", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("SetTOTPSecret exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// 2FA уже включена — секрет меняем только через выключение
+		r.logger.Printf("SetTOTPSecret no rows affected in %s user_id=%s", time.Since(start), userID)
+		return domain.ErrBadParams
+	}
+	r.logger.Printf("SetTOTPSecret ok in %s user_id=%s", time.Since(start), userID)
+	return nil
+}
+
+// EnableTOTP: в одной транзакции включает 2FA и заменяет коды восстановления.
+func (r *PGRepo) EnableTOTP(ctx context.Context, userID domain.UserID, step int64, recoveryHashes [][]byte) error {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("EnableTOTP begin error: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	upd := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("totp_enabled", true).
+		Set("totp_last_step", step).
+		Where(sq.Eq{"id": userID, "totp_enabled": false}).
+		Where(sq.NotEq{"totp_secret": nil})
+	sqlStr, args, _ := upd.ToSql()
+	r.logSQL("EnableTOTP.user", sqlStr, args)
+	tag, err := tx.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("EnableTOTP user error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("EnableTOTP nothing to enable user_id=%s", userID)
+		return domain.ErrBadParams
+	}
+
+	del := r.qb().Delete(fmt.Sprintf("%s.mfa_recovery_codes", r.schema)).Where(sq.Eq{"user_id": userID})
+	sqlStr, args, _ = del.ToSql()
+	r.logSQL("EnableTOTP.clear_codes", sqlStr, args)
+	if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("EnableTOTP clear codes error after %s: %v", time.Since(start), err)
+		return err
+	}
+
+	ins := r.qb().Insert(fmt.Sprintf("%s.mfa_recovery_codes", r.schema)).Columns("user_id", "code_hash")
+	for _, h := range recoveryHashes {
+		ins = ins.Values(userID, h)
+	}
+	sqlStr, args, _ = ins.ToSql()
+	r.logSQL("EnableTOTP.codes", sqlStr, args)
+	if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("EnableTOTP insert codes error after %s: %v", time.Since(start), err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("EnableTOTP commit error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("EnableTOTP ok in %s user_id=%s codes=%d", time.Since(start), userID, len(recoveryHashes))
+	return nil
+}
+
+func (r *PGRepo) DisableTOTP(ctx context.Context, userID domain.UserID) error {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("DisableTOTP begin error: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	upd := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("totp_enabled", false).
+		Set("totp_secret", nil).
+		Set("totp_last_step", 0).
+		Where(sq.Eq{"id": userID})
+	sqlStr, args, _ := upd.ToSql()
+	r.logSQL("DisableTOTP.user", sqlStr, args)
+	if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("DisableTOTP user error after %s: %v", time.Since(start), err)
+		return err
+	}
+
+	del := r.qb().Delete(fmt.Sprintf("%s.mfa_recovery_codes", r.schema)).Where(sq.Eq{"user_id": userID})
+	sqlStr, args, _ = del.ToSql()
+	r.logSQL("DisableTOTP.codes", sqlStr, args)
+	if _, err := tx.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("DisableTOTP codes error after %s: %v", time.Since(start), err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("DisableTOTP commit error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("DisableTOTP ok in %s user_id=%s", time.Since(start), userID)
+	return nil
+}
+
+func (r *PGRepo) UseTOTPStep(ctx context.Context, userID domain.UserID, step int64) (bool, error) {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("totp_last_step", step).
+		Where(sq.Eq{"id": userID}).
+		Where(sq.Lt{"totp_last_step": step})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UseTOTPStep", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("UseTOTPStep exec error after %s: %v", time.Since(start), err)
+		return false, err
+	}
+	r.logger.Printf("UseTOTPStep ok in %s user_id=%s accepted=%t", time.Since(start), userID, tag.RowsAffected() == 1)
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PGRepo) UseRecoveryCode(ctx context.Context, userID domain.UserID, hash []byte) (bool, error) {
+	q := r.qb().Update(fmt.Sprintf("%s.mfa_recovery_codes", r.schema)).
+		Set("used_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userID, "code_hash": hash, "used_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UseRecoveryCode", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("UseRecoveryCode exec error after %s: %v", time.Since(start), err)
+		return false, err
+	}
+	r.logger.Printf("UseRecoveryCode ok in %s user_id=%s accepted=%t", time.Since(start), userID, tag.RowsAffected() == 1)
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.refresh_tokens;
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS mydocs.refresh_tokens (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id           UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  family_id         UUID NOT NULL,
+  token_hash        BYTEA NOT NULL UNIQUE,
+  -- access-токен, выданный вместе с этим refresh (для блэклиста при отзыве семейства)
+  access_jti        TEXT NOT NULL,
+  access_expires_at TIMESTAMPTZ NOT NULL,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at        TIMESTAMPTZ NOT NULL,
+  rotated_at        TIMESTAMPTZ,
+  replaced_by       UUID,
+  revoked_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_family
+  ON mydocs.refresh_tokens(family_id);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_user
+  ON mydocs.refresh_tokens(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_access_jti
+  ON mydocs.refresh_tokens(access_jti);
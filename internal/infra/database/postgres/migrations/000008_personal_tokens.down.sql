@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.personal_tokens;
\ No newline at end of file
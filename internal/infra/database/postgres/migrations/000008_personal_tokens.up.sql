@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS mydocs.personal_tokens (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id      UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  name         TEXT NOT NULL,
+  token_hash   BYTEA NOT NULL UNIQUE,
+  scopes       TEXT[] NOT NULL DEFAULT '{}',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_tokens_user
+  ON mydocs.personal_tokens(user_id, created_at DESC);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.sessions;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- Сессия = один логин; id совпадает с family_id refresh-токенов.
+CREATE TABLE IF NOT EXISTS mydocs.sessions (
+  id           UUID PRIMARY KEY,
+  user_id      UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  current_jti  TEXT NOT NULL,
+  user_agent   TEXT NOT NULL DEFAULT '',
+  ip           TEXT NOT NULL DEFAULT '',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ NOT NULL,
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_active
+  ON mydocs.sessions(user_id, created_at DESC) WHERE revoked_at IS NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.login_attempts;
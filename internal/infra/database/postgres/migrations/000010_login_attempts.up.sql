@@ -0,0 +1,14 @@
+-- Аудит попыток входа (успешных и нет)
+CREATE TABLE IF NOT EXISTS mydocs.login_attempts (
+  id          BIGSERIAL PRIMARY KEY,
+  login       TEXT NOT NULL,
+  user_id     UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  ip          TEXT NOT NULL DEFAULT '',
+  user_agent  TEXT NOT NULL DEFAULT '',
+  success     BOOLEAN NOT NULL,
+  reason      TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_login ON mydocs.login_attempts(login, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON mydocs.login_attempts(ip, created_at DESC);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS mydocs.mfa_recovery_codes;
+
+ALTER TABLE mydocs.users
+  DROP COLUMN IF EXISTS totp_last_step,
+  DROP COLUMN IF EXISTS totp_enabled,
+  DROP COLUMN IF EXISTS totp_secret;
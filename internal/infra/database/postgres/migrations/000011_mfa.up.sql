@@ -0,0 +1,15 @@
+-- TOTP 2FA: секрет зашифрован приложением (AES-GCM), last_step — защита от повтора кода
+ALTER TABLE mydocs.users
+  ADD COLUMN IF NOT EXISTS totp_secret    BYTEA,
+  ADD COLUMN IF NOT EXISTS totp_enabled   BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- Одноразовые коды восстановления (храним только sha256)
+CREATE TABLE IF NOT EXISTS mydocs.mfa_recovery_codes (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id    UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  code_hash  BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  used_at    TIMESTAMPTZ,
+  UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS mydocs.idx_users_login_trgm;
+
+ALTER TABLE mydocs.users
+  DROP COLUMN IF EXISTS disabled_at,
+  DROP COLUMN IF EXISTS role;
@@ -0,0 +1,7 @@
+-- Роль пользователя и отключение аккаунта администратором
+ALTER TABLE mydocs.users
+  ADD COLUMN IF NOT EXISTS role        TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+  ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_login_trgm
+  ON mydocs.users USING gin (login gin_trgm_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE mydocs.users
+  DROP COLUMN IF EXISTS invite_id,
+  DROP COLUMN IF EXISTS invited_by;
+
+DROP TABLE IF EXISTS mydocs.invites;
@@ -0,0 +1,19 @@
+-- Приглашения для самостоятельной регистрации
+CREATE TABLE IF NOT EXISTS mydocs.invites (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  code_hash   BYTEA NOT NULL UNIQUE,
+  created_by  UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  role        TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+  max_uses    INT NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+  uses        INT NOT NULL DEFAULT 0,
+  expires_at  TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_invites_created_by ON mydocs.invites(created_by, created_at DESC);
+
+-- Кто кого пригласил
+ALTER TABLE mydocs.users
+  ADD COLUMN IF NOT EXISTS invited_by UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS invite_id  UUID REFERENCES mydocs.invites(id) ON DELETE SET NULL;
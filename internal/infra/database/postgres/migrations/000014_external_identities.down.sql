@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS mydocs.uq_users_ext_identity;
+
+ALTER TABLE mydocs.users
+  DROP COLUMN IF EXISTS ext_email,
+  DROP COLUMN IF EXISTS ext_subject,
+  DROP COLUMN IF EXISTS ext_issuer;
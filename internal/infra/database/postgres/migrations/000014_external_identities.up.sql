@@ -0,0 +1,9 @@
+-- Внешние учётные записи (OpenID Connect): вход по issuer + sub
+ALTER TABLE mydocs.users
+  ADD COLUMN IF NOT EXISTS ext_issuer  TEXT,
+  ADD COLUMN IF NOT EXISTS ext_subject TEXT,
+  ADD COLUMN IF NOT EXISTS ext_email   TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_users_ext_identity
+  ON mydocs.users(ext_issuer, ext_subject)
+  WHERE ext_subject IS NOT NULL;
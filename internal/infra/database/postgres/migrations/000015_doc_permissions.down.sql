@@ -0,0 +1,5 @@
+ALTER TABLE mydocs.doc_shares
+  ADD COLUMN IF NOT EXISTS can_read BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE mydocs.doc_shares
+  DROP COLUMN IF EXISTS permission;
@@ -0,0 +1,10 @@
+-- Градуированные права на документ вместо can_read
+ALTER TABLE mydocs.doc_shares
+  ADD COLUMN IF NOT EXISTS permission TEXT NOT NULL DEFAULT 'viewer'
+    CHECK (permission IN ('viewer', 'commenter', 'editor', 'co_owner'));
+
+-- can_read = FALSE означал отсутствие доступа
+DELETE FROM mydocs.doc_shares WHERE can_read = FALSE;
+
+ALTER TABLE mydocs.doc_shares
+  DROP COLUMN IF EXISTS can_read;
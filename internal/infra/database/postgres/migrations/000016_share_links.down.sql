@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.share_links;
@@ -0,0 +1,15 @@
+-- Анонимные ссылки на документ: храним только sha256 токена
+CREATE TABLE IF NOT EXISTS mydocs.share_links (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  doc_id         UUID NOT NULL REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  created_by     UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  token_hash     BYTEA NOT NULL UNIQUE,
+  pass_hash      TEXT,
+  expires_at     TIMESTAMPTZ,
+  max_downloads  INT CHECK (max_downloads > 0),
+  downloads      INT NOT NULL DEFAULT 0,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_doc ON mydocs.share_links(doc_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS mydocs.doc_group_shares;
+DROP TABLE IF EXISTS mydocs.group_members;
+DROP TABLE IF EXISTS mydocs.groups;
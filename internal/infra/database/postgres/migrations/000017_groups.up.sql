@@ -0,0 +1,30 @@
+-- Группы пользователей для шаринга командам
+CREATE TABLE IF NOT EXISTS mydocs.groups (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name        TEXT NOT NULL UNIQUE,
+  created_by  UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS mydocs.group_members (
+  group_id  UUID NOT NULL REFERENCES mydocs.groups(id) ON DELETE CASCADE,
+  user_id   UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  is_admin  BOOLEAN NOT NULL DEFAULT FALSE, -- управляет составом группы
+  added_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (group_id, user_id)
+);
+
+-- Группы пользователя (вычисление прав, список групп)
+CREATE INDEX IF NOT EXISTS idx_group_members_user ON mydocs.group_members(user_id);
+
+-- Права на документ, выданные группе
+CREATE TABLE IF NOT EXISTS mydocs.doc_group_shares (
+  doc_id      UUID NOT NULL REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  group_id    UUID NOT NULL REFERENCES mydocs.groups(id) ON DELETE CASCADE,
+  permission  TEXT NOT NULL DEFAULT 'viewer'
+    CHECK (permission IN ('viewer', 'commenter', 'editor', 'co_owner')),
+  PRIMARY KEY (doc_id, group_id)
+);
+
+-- Документы, расшаренные группе (видимость в списках)
+CREATE INDEX IF NOT EXISTS idx_group_shares_group ON mydocs.doc_group_shares(group_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mydocs.doc_transfers;
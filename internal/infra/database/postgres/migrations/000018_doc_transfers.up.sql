@@ -0,0 +1,16 @@
+-- Запросы на передачу владения документом: у документа не больше одного
+-- ожидающего запроса; принятый/отклонённый запрос удаляется.
+CREATE TABLE IF NOT EXISTS mydocs.doc_transfers (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  doc_id       UUID NOT NULL UNIQUE REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  from_user    UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  to_user      UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  keep_editor  BOOLEAN NOT NULL DEFAULT FALSE, -- прежнему владельцу остаётся право editor
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ NOT NULL,
+  CHECK (from_user <> to_user)
+);
+
+-- Входящие запросы пользователя
+CREATE INDEX IF NOT EXISTS idx_doc_transfers_to ON mydocs.doc_transfers(to_user);
+CREATE INDEX IF NOT EXISTS idx_doc_transfers_from ON mydocs.doc_transfers(from_user);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS mydocs.idx_docs_storage_key;
+DROP TABLE IF EXISTS mydocs.document_versions;
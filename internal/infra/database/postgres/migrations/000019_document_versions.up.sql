@@ -0,0 +1,26 @@
+-- Ревизии контента документа. Текущая ревизия — с наибольшим номером;
+-- восстановление старой создаёт новую ревизию с тем же storage_key.
+CREATE TABLE IF NOT EXISTS mydocs.document_versions (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  doc_id         UUID NOT NULL REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  revision       INT NOT NULL,
+  storage_key    TEXT NOT NULL,
+  size_bytes     BIGINT NOT NULL DEFAULT 0,
+  content_sha256 BYTEA NOT NULL,
+  mime_type      TEXT NOT NULL,
+  author_id      UUID REFERENCES mydocs.users(id) ON DELETE SET NULL,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (doc_id, revision)
+);
+
+-- Уже загруженные файлы становятся первой ревизией
+INSERT INTO mydocs.document_versions (doc_id, revision, storage_key, size_bytes, content_sha256, mime_type, author_id, created_at)
+SELECT id, 1, storage_key, size_bytes, content_sha256, mime_type, owner_id, updated_at
+FROM mydocs.documents
+WHERE file = TRUE
+ON CONFLICT (doc_id, revision) DO NOTHING;
+
+-- Ключи контентные и общие у разных документов/ревизий: перед удалением
+-- объекта из storage проверяем, что на него больше никто не ссылается
+CREATE INDEX IF NOT EXISTS idx_doc_versions_key ON mydocs.document_versions(storage_key);
+CREATE INDEX IF NOT EXISTS idx_docs_storage_key ON mydocs.documents(storage_key);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS mydocs.idx_docs_deleted;
+ALTER TABLE mydocs.documents
+  DROP COLUMN IF EXISTS deleted_by,
+  DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Корзина: удалённый документ скрыт из выдачи, но строка и контент остаются
+-- до окончательного удаления (вручную или фоновой чисткой по TRASH_RETENTION).
+ALTER TABLE mydocs.documents
+  ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES mydocs.users(id) ON DELETE SET NULL;
+
+-- Корзина и фоновая чистка выбирают только удалённые
+CREATE INDEX IF NOT EXISTS idx_docs_deleted
+  ON mydocs.documents(deleted_at) WHERE deleted_at IS NOT NULL;
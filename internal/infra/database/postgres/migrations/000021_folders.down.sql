@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS mydocs.idx_docs_folder;
+ALTER TABLE mydocs.documents DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS mydocs.folder_shares;
+DROP TABLE IF EXISTS mydocs.folder_paths;
+DROP TABLE IF EXISTS mydocs.folders;
@@ -0,0 +1,43 @@
+-- Папки: дерево папок одного владельца (подпапки принадлежат владельцу корня)
+CREATE TABLE IF NOT EXISTS mydocs.folders (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id      UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  parent_id     UUID REFERENCES mydocs.folders(id) ON DELETE CASCADE, -- NULL — корень владельца
+  name          TEXT NOT NULL,
+  unique_names  BOOLEAN NOT NULL DEFAULT FALSE, -- имена документов внутри папки уникальны
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (parent_id <> id)
+);
+
+-- Имена подпапок уникальны в пределах родителя (корневых — в пределах владельца)
+CREATE UNIQUE INDEX IF NOT EXISTS uq_folders_name
+  ON mydocs.folders(owner_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), name);
+
+-- Замыкание дерева: все пары предок → потомок (включая саму папку, depth = 0).
+-- Наследование прав, рекурсивный список и хлебные крошки — обычные JOIN.
+CREATE TABLE IF NOT EXISTS mydocs.folder_paths (
+  ancestor_id    UUID NOT NULL REFERENCES mydocs.folders(id) ON DELETE CASCADE,
+  descendant_id  UUID NOT NULL REFERENCES mydocs.folders(id) ON DELETE CASCADE,
+  depth          INT  NOT NULL,
+  PRIMARY KEY (ancestor_id, descendant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_paths_desc ON mydocs.folder_paths(descendant_id);
+
+-- Права на папку: действуют на все документы поддерева
+CREATE TABLE IF NOT EXISTS mydocs.folder_shares (
+  folder_id   UUID NOT NULL REFERENCES mydocs.folders(id) ON DELETE CASCADE,
+  user_id     UUID NOT NULL REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  permission  TEXT NOT NULL DEFAULT 'viewer'
+    CHECK (permission IN ('viewer', 'commenter', 'editor', 'co_owner')),
+  PRIMARY KEY (folder_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_shares_user ON mydocs.folder_shares(user_id);
+
+-- Документ лежит в папке или в корне (NULL). Удалённая папка оставляет документы в корне.
+ALTER TABLE mydocs.documents
+  ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES mydocs.folders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_docs_folder ON mydocs.documents(folder_id);
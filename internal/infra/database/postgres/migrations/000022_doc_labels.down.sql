@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mydocs.doc_attrs;
+DROP TABLE IF EXISTS mydocs.doc_tags;
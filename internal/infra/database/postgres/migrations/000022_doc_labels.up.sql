@@ -0,0 +1,26 @@
+-- Пользовательские теги документа (в нижнем регистре)
+CREATE TABLE IF NOT EXISTS mydocs.doc_tags (
+  doc_id  UUID NOT NULL REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  tag     TEXT NOT NULL,
+  PRIMARY KEY (doc_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_doc_tags_tag ON mydocs.doc_tags(tag, doc_id);
+
+-- Пользовательские атрибуты: value — каноническая текстовая запись (по ней
+-- фильтр attr.<key>=<value>), num_value/date_value — типизированные копии.
+CREATE TABLE IF NOT EXISTS mydocs.doc_attrs (
+  doc_id      UUID NOT NULL REFERENCES mydocs.documents(id) ON DELETE CASCADE,
+  key         TEXT NOT NULL,
+  type        TEXT NOT NULL CHECK (type IN ('string', 'number', 'date')),
+  value       TEXT NOT NULL,
+  num_value   NUMERIC,
+  date_value  DATE,
+  PRIMARY KEY (doc_id, key),
+  CHECK ((type = 'number') = (num_value IS NOT NULL)),
+  CHECK ((type = 'date') = (date_value IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_doc_attrs_value ON mydocs.doc_attrs(key, value);
+CREATE INDEX IF NOT EXISTS idx_doc_attrs_num   ON mydocs.doc_attrs(key, num_value) WHERE num_value IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_doc_attrs_date  ON mydocs.doc_attrs(key, date_value) WHERE date_value IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS mydocs.idx_docs_schema;
+ALTER TABLE mydocs.documents DROP COLUMN IF EXISTS schema_id;
+DROP TABLE IF EXISTS mydocs.json_schemas;
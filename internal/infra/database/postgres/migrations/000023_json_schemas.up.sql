@@ -0,0 +1,21 @@
+-- Именованные JSON Schema (draft 2020-12) для JSON-тел документов.
+-- owner_id IS NULL — глобальная схема (регистрирует администратор). Тело не меняется.
+CREATE TABLE IF NOT EXISTS mydocs.json_schemas (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id    UUID REFERENCES mydocs.users(id) ON DELETE CASCADE,
+  name        TEXT NOT NULL,
+  body        JSONB NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Имена уникальны у владельца и среди глобальных
+CREATE UNIQUE INDEX IF NOT EXISTS uq_json_schemas_name
+  ON mydocs.json_schemas(COALESCE(owner_id, '00000000-0000-0000-0000-000000000000'::uuid), name);
+
+-- Схема, которой обязано соответствовать JSON-тело документа. Удалить схему,
+-- к которой привязаны документы, нельзя (проверяет приложение); SET NULL — только
+-- при каскадном удалении владельца схемы.
+ALTER TABLE mydocs.documents
+  ADD COLUMN IF NOT EXISTS schema_id UUID REFERENCES mydocs.json_schemas(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_docs_schema ON mydocs.documents(schema_id) WHERE schema_id IS NOT NULL;
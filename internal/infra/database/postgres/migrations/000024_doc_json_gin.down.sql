@@ -0,0 +1 @@
+DROP INDEX IF EXISTS mydocs.idx_doc_json_body;
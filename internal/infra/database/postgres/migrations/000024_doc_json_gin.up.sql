@@ -0,0 +1,3 @@
+-- Фильтры по JSON-телу: @> (json.contains) и @? (json.eq, json.exists).
+-- jsonb_ops, а не jsonb_path_ops: по ключам индекс помогает и json.exists.
+CREATE INDEX IF NOT EXISTS idx_doc_json_body ON mydocs.doc_json USING GIN (body);
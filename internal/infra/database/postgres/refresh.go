@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+var refreshCols = []string{
+	"id", "user_id", "family_id", "token_hash", "access_jti", "access_expires_at",
+	"created_at", "expires_at", "rotated_at", "replaced_by", "revoked_at",
+}
+
+func scanRefresh(row pgx.Row) (domain.RefreshToken, error) {
+	var rt domain.RefreshToken
+	err := row.Scan(
+		&rt.ID, &rt.UserID, &rt.FamilyID, &rt.TokenHash, &rt.AccessJTI, &rt.AccessExpiresAt,
+		&rt.CreatedAt, &rt.ExpiresAt, &rt.RotatedAt, &rt.ReplacedBy, &rt.RevokedAt,
+	)
+	return rt, err
+}
+
+func (r *PGRepo) insertRefreshQuery(rt domain.RefreshToken) sq.InsertBuilder {
+	return r.qb().Insert(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Columns("user_id", "family_id", "token_hash", "access_jti", "access_expires_at", "expires_at").
+		Values(rt.UserID, rt.FamilyID, rt.TokenHash, rt.AccessJTI, rt.AccessExpiresAt, rt.ExpiresAt).
+		Suffix("RETURNING " + joinCols(refreshCols))
+}
+
+func (r *PGRepo) CreateRefreshToken(ctx context.Context, rt domain.RefreshToken) (domain.RefreshToken, error) {
+	sqlStr, args, _ := r.insertRefreshQuery(rt).ToSql()
+	r.logSQL("CreateRefreshToken", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanRefresh(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("CreateRefreshToken scan error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+	r.logger.Printf("CreateRefreshToken ok in %s id=%s family=%s", time.Since(start), out.ID, out.FamilyID)
+	return out, nil
+}
+
+func (r *PGRepo) RefreshTokenByHash(ctx context.Context, hash []byte) (domain.RefreshToken, error) {
+	q := r.qb().Select(refreshCols...).
+		From(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Where(sq.Eq{"token_hash": hash})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RefreshTokenByHash", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanRefresh(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("RefreshTokenByHash scan error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+	r.logger.Printf("RefreshTokenByHash ok in %s id=%s", time.Since(start), out.ID)
+	return out, nil
+}
+
+func (r *PGRepo) RefreshTokenByAccessJTI(ctx context.Context, jti string) (domain.RefreshToken, error) {
+	q := r.qb().Select(refreshCols...).
+		From(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Where(sq.Eq{"access_jti": jti}).
+		Limit(1)
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RefreshTokenByAccessJTI", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanRefresh(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("RefreshTokenByAccessJTI scan error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+	r.logger.Printf("RefreshTokenByAccessJTI ok in %s id=%s", time.Since(start), out.ID)
+	return out, nil
+}
+
+// RotateRefreshToken: в одной транзакции помечает старый токен обменянным
+// (только если он ещё активен) и вставляет новый в то же семейство.
+func (r *PGRepo) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next domain.RefreshToken) (domain.RefreshToken, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("RotateRefreshToken begin error: %v", err)
+		return domain.RefreshToken{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ins, args, _ := r.insertRefreshQuery(next).ToSql()
+	r.logSQL("RotateRefreshToken.insert", ins, args)
+	out, err := scanRefresh(tx.QueryRow(ctx, ins, args...))
+	if err != nil {
+		r.logger.Printf("RotateRefreshToken insert error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+
+	upd := r.qb().Update(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Set("rotated_at", sq.Expr("now()")).
+		Set("replaced_by", out.ID).
+		Where(sq.Eq{"id": oldID, "rotated_at": nil, "revoked_at": nil})
+	sqlStr, args, _ := upd.ToSql()
+	r.logSQL("RotateRefreshToken.mark", sqlStr, args)
+	tag, err := tx.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RotateRefreshToken mark error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		// кто-то уже обменял этот токен (или он отозван) — новый не сохраняем
+		r.logger.Printf("RotateRefreshToken reuse detected old_id=%s", oldID)
+		return domain.RefreshToken{}, domain.ErrTokenReused
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("RotateRefreshToken commit error after %s: %v", time.Since(start), err)
+		return domain.RefreshToken{}, err
+	}
+	r.logger.Printf("RotateRefreshToken ok in %s old_id=%s new_id=%s", time.Since(start), oldID, out.ID)
+	return out, nil
+}
+
+func (r *PGRepo) RevokeRefreshFamily(ctx context.Context, familyID uuid.UUID) ([]domain.RefreshToken, error) {
+	q := r.qb().Update(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"family_id": familyID, "revoked_at": nil}).
+		Suffix("RETURNING " + joinCols(refreshCols))
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeRefreshFamily", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokeRefreshFamily query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.RefreshToken
+	for rows.Next() {
+		rt, err := scanRefresh(rows)
+		if err != nil {
+			r.logger.Printf("RevokeRefreshFamily scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("RevokeRefreshFamily rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("RevokeRefreshFamily ok in %s family=%s revoked=%d", time.Since(start), familyID, len(out))
+	return out, nil
+}
+
+func (r *PGRepo) RevokeUserRefreshTokens(ctx context.Context, userID domain.UserID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.refresh_tokens", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeUserRefreshTokens", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokeUserRefreshTokens exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("RevokeUserRefreshTokens ok in %s user_id=%s rows=%d", time.Since(start), userID, tag.RowsAffected())
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
@@ -134,6 +135,20 @@ func safeArgs(args []any) []any {
 	return out
 }
 
+// список колонок для RETURNING / SELECT
+func joinCols(cols []string) string { return strings.Join(cols, ", ") }
+
+// isUniqueViolation — нарушение уникального ограничения (23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// escapeLike экранирует спецсимволы LIKE (\ — escape по умолчанию в Postgres).
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string { return likeEscaper.Replace(s) }
+
 func (r *PGRepo) logSQL(label, sqlStr string, args []any) {
 	sqlOneLine := strings.ReplaceAll(sqlStr, "\n", " ")
 	r.logger.Printf("%s sql=%q args=%v", label, sqlOneLine, safeArgs(args))
@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// Колонки ревизии; current — наибольший номер у документа.
+func (r *PGRepo) revisionCols() []string {
+	return []string{
+		"v.doc_id", "v.revision", "v.storage_key", "v.size_bytes", "v.content_sha256", "v.mime_type",
+		"v.author_id", "u.login", "v.created_at",
+		fmt.Sprintf("v.revision = (SELECT max(revision) FROM %s.document_versions WHERE doc_id = v.doc_id)", r.schema),
+	}
+}
+
+func scanRevision(row pgx.Row) (domain.DocRevision, error) {
+	var (
+		rv     domain.DocRevision
+		author *string
+	)
+	if err := row.Scan(
+		&rv.DocID, &rv.Revision, &rv.StorageKey, &rv.SizeBytes, &rv.SHA256, &rv.MIME,
+		&rv.AuthorID, &author, &rv.CreatedAt, &rv.Current,
+	); err != nil {
+		return domain.DocRevision{}, err
+	}
+	if author != nil {
+		rv.Author = *author
+	}
+	rv.Checksum = hex.EncodeToString(rv.SHA256)
+	return rv, nil
+}
+
+func (r *PGRepo) AddRevision(ctx context.Context, docID domain.DocID, version int64, rev domain.DocRevision) (domain.Document, domain.DocRevision, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("AddRevision begin error: %v", err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	d, out, err := r.addRevisionTx(ctx, tx, docID, version, rev)
+	if err != nil {
+		r.logger.Printf("AddRevision error after %s: %v", time.Since(start), err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("AddRevision commit error after %s: %v", time.Since(start), err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+	r.logger.Printf("AddRevision ok in %s doc_id=%s revision=%d", time.Since(start), docID, out.Revision)
+	return d, out, nil
+}
+
+func (r *PGRepo) RestoreRevision(ctx context.Context, docID domain.DocID, version int64, revision int, author domain.UserID) (domain.Document, domain.DocRevision, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("RestoreRevision begin error: %v", err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// FOR SHARE: параллельная чистка ревизий не удалит строку (и объект) до коммита
+	q := r.qb().Select("storage_key", "size_bytes", "content_sha256", "mime_type").
+		From(fmt.Sprintf("%s.document_versions", r.schema)).
+		Where(sq.Eq{"doc_id": docID, "revision": revision}).
+		Suffix("FOR SHARE")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RestoreRevision.select", sqlStr, args)
+
+	rev := domain.DocRevision{AuthorID: &author}
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(&rev.StorageKey, &rev.SizeBytes, &rev.SHA256, &rev.MIME); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RestoreRevision not found in %s doc_id=%s revision=%d", time.Since(start), docID, revision)
+			return domain.Document{}, domain.DocRevision{}, domain.ErrNotFound
+		}
+		r.logger.Printf("RestoreRevision scan error after %s: %v", time.Since(start), err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+
+	d, out, err := r.addRevisionTx(ctx, tx, docID, version, rev)
+	if err != nil {
+		r.logger.Printf("RestoreRevision error after %s: %v", time.Since(start), err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("RestoreRevision commit error after %s: %v", time.Since(start), err)
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+	r.logger.Printf("RestoreRevision ok in %s doc_id=%s from=%d revision=%d", time.Since(start), docID, revision, out.Revision)
+	return d, out, nil
+}
+
+// addRevisionTx: сначала документ (версия в WHERE и блокировка строки — номера
+// ревизий не гонятся), затем ревизия со следующим номером.
+func (r *PGRepo) addRevisionTx(ctx context.Context, tx pgx.Tx, docID domain.DocID, version int64, rev domain.DocRevision) (domain.Document, domain.DocRevision, error) {
+	uq := r.qb().Update(fmt.Sprintf("%s.documents", r.schema)).
+		Set("storage_key", rev.StorageKey).
+		Set("size_bytes", rev.SizeBytes).
+		Set("content_sha256", rev.SHA256).
+		Set("mime_type", rev.MIME).
+		Set("file", true).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": docID, "version": version}).
+		Suffix("RETURNING id, owner_id, name, mime_type, file, public, size_bytes, storage_key, content_sha256, version, created_at, updated_at, folder_id, schema_id")
+	sqlStr, args, _ := uq.ToSql()
+	r.logSQL("addRevision.doc", sqlStr, args)
+
+	var d domain.Document
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(
+		&d.ID, &d.OwnerID, &d.Name, &d.MIME, &d.File, &d.Public,
+		&d.SizeBytes, &d.StorageKey, &d.SHA256, &d.Version, &d.CreatedAt, &d.UpdatedAt, &d.FolderID, &d.SchemaID,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Document{}, domain.DocRevision{}, domain.ErrPrecondition
+		}
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+
+	versions := fmt.Sprintf("%s.document_versions", r.schema)
+	sub := r.qb().Select().
+		Column("? AS doc_id", docID).
+		Column("COALESCE(max(revision), 0) + 1 AS revision").
+		Column("? AS storage_key", rev.StorageKey).
+		Column("? AS size_bytes", rev.SizeBytes).
+		Column("? AS content_sha256", rev.SHA256).
+		Column("? AS mime_type", rev.MIME).
+		Column("?::uuid AS author_id", rev.AuthorID).
+		From(versions).
+		Where(sq.Eq{"doc_id": docID})
+	iq := r.qb().Insert(versions).
+		Columns("doc_id", "revision", "storage_key", "size_bytes", "content_sha256", "mime_type", "author_id").
+		Select(sub).
+		Suffix("RETURNING revision, created_at")
+	sqlStr, args, _ = iq.ToSql()
+	r.logSQL("addRevision.insert", sqlStr, args)
+
+	out := rev
+	out.DocID = docID
+	out.Current = true
+	out.Checksum = hex.EncodeToString(rev.SHA256)
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(&out.Revision, &out.CreatedAt); err != nil {
+		return domain.Document{}, domain.DocRevision{}, err
+	}
+	return d, out, nil
+}
+
+func (r *PGRepo) ListRevisions(ctx context.Context, docID domain.DocID) ([]domain.DocRevision, error) {
+	q := r.qb().Select(r.revisionCols()...).
+		From(fmt.Sprintf("%s.document_versions v", r.schema)).
+		LeftJoin(fmt.Sprintf("%s.users u ON u.id = v.author_id", r.schema)).
+		Where(sq.Eq{"v.doc_id": docID}).
+		OrderBy("v.revision DESC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListRevisions", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListRevisions query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.DocRevision{}
+	for rows.Next() {
+		rv, err := scanRevision(rows)
+		if err != nil {
+			r.logger.Printf("ListRevisions scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, rv)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListRevisions rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListRevisions ok in %s doc_id=%s count=%d", time.Since(start), docID, len(out))
+	return out, nil
+}
+
+func (r *PGRepo) RevisionByNumber(ctx context.Context, docID domain.DocID, revision int) (domain.DocRevision, error) {
+	q := r.qb().Select(r.revisionCols()...).
+		From(fmt.Sprintf("%s.document_versions v", r.schema)).
+		LeftJoin(fmt.Sprintf("%s.users u ON u.id = v.author_id", r.schema)).
+		Where(sq.Eq{"v.doc_id": docID, "v.revision": revision})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevisionByNumber", sqlStr, args)
+
+	start := time.Now()
+	rv, err := scanRevision(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RevisionByNumber not found in %s doc_id=%s revision=%d", time.Since(start), docID, revision)
+			return domain.DocRevision{}, domain.ErrNotFound
+		}
+		r.logger.Printf("RevisionByNumber scan error after %s: %v", time.Since(start), err)
+		return domain.DocRevision{}, err
+	}
+	r.logger.Printf("RevisionByNumber ok in %s doc_id=%s revision=%d", time.Since(start), docID, revision)
+	return rv, nil
+}
+
+// PruneRevisions: номера растут без пропусков сверху, поэтому "последние keep" —
+// это всё, что больше max - keep. keep <= 0 — без ограничения.
+func (r *PGRepo) PruneRevisions(ctx context.Context, docID domain.DocID, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	versions := fmt.Sprintf("%s.document_versions", r.schema)
+	q := r.qb().Delete(versions).
+		Where(sq.Eq{"doc_id": docID}).
+		Where(sq.Expr("revision <= (SELECT max(revision) - ? FROM "+versions+" WHERE doc_id = ?)", keep, docID)).
+		Suffix("RETURNING storage_key")
+	return r.queryKeys(ctx, r.pool, "PruneRevisions", q)
+}
+
+func (r *PGRepo) OwnerBlobKeys(ctx context.Context, owner domain.UserID) ([]string, error) {
+	q := r.qb().Select("storage_key").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Eq{"owner_id": owner}).
+		Where(sq.NotEq{"storage_key": ""}).
+		Suffix("UNION SELECT v.storage_key FROM "+r.schema+".document_versions v JOIN "+r.schema+
+			".documents d ON d.id = v.doc_id WHERE d.owner_id = ?", owner)
+	return r.queryKeys(ctx, r.pool, "OwnerBlobKeys", q)
+}
+
+func (r *PGRepo) UnreferencedBlobs(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	q := r.qb().Select("storage_key").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Expr("storage_key = ANY(?)", keys)).
+		Suffix("UNION SELECT storage_key FROM "+r.schema+".document_versions WHERE storage_key = ANY(?)", keys)
+	used, err := r.queryKeys(ctx, r.pool, "UnreferencedBlobs", q)
+	if err != nil {
+		return nil, err
+	}
+	return unreferenced(keys, used), nil
+}
+
+// unreferenced — ключи из keys, которых нет в used, без пустых и повторов,
+// в порядке первого появления.
+func unreferenced(keys, used []string) []string {
+	inUse := make(map[string]bool, len(used))
+	for _, k := range used {
+		inUse[k] = true
+	}
+	var out []string
+	for _, k := range keys {
+		if k != "" && !inUse[k] {
+			inUse[k] = true // дубликаты во входе
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func (r *PGRepo) queryKeys(ctx context.Context, db querier, name string, q sq.Sqlizer) ([]string, error) {
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL(name, sqlStr, args)
+
+	start := time.Now()
+	rows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("%s query error after %s: %v", name, time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			r.logger.Printf("%s scan error: %v", name, err)
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("%s rows error: %v", name, err)
+		return nil, err
+	}
+	r.logger.Printf("%s ok in %s count=%d", name, time.Since(start), len(out))
+	return out, nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnreferenced(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		used []string
+		want []string
+	}{
+		{"все свободны", []string{"a", "b"}, nil, []string{"a", "b"}},
+		{"все заняты", []string{"a", "b"}, []string{"b", "a"}, nil},
+		{"часть занята", []string{"a", "b", "c"}, []string{"b"}, []string{"a", "c"}},
+		{"повторы во входе", []string{"a", "b", "a", "b", "a"}, nil, []string{"a", "b"}},
+		{"повтор занятого", []string{"b", "a", "b"}, []string{"b"}, []string{"a"}},
+		{"пустые ключи", []string{"", "a", ""}, nil, []string{"a"}},
+		{"пустой вход", nil, []string{"a"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unreferenced(tt.keys, tt.used); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unreferenced(%v, %v) = %v, want %v", tt.keys, tt.used, got, tt.want)
+			}
+		})
+	}
+}
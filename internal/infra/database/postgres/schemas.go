@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+const schemaCols = "s.id, s.owner_id, s.name, s.body, s.created_at"
+
+func scanSchema(row pgx.Row) (domain.JSONSchema, error) {
+	var s domain.JSONSchema
+	err := row.Scan(&s.ID, &s.OwnerID, &s.Name, &s.Body, &s.CreatedAt)
+	return s, err
+}
+
+func (r *PGRepo) CreateSchema(ctx context.Context, s domain.JSONSchema) (domain.JSONSchema, error) {
+	q := r.qb().Insert(fmt.Sprintf("%s.json_schemas AS s", r.schema)).
+		Columns("owner_id", "name", "body").
+		Values(s.OwnerID, s.Name, s.Body).
+		Suffix("RETURNING " + schemaCols)
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateSchema", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanSchema(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if isUniqueViolation(err) {
+			r.logger.Printf("CreateSchema name taken in %s name=%q", time.Since(start), s.Name)
+			return domain.JSONSchema{}, domain.ErrConflict
+		}
+		r.logger.Printf("CreateSchema scan error after %s: %v", time.Since(start), err)
+		return domain.JSONSchema{}, err
+	}
+	r.logger.Printf("CreateSchema ok in %s id=%s name=%q global=%t", time.Since(start), out.ID, out.Name, out.Global())
+	return out, nil
+}
+
+func (r *PGRepo) SchemaByID(ctx context.Context, id uuid.UUID) (domain.JSONSchema, error) {
+	q := r.qb().Select(schemaCols).
+		From(fmt.Sprintf("%s.json_schemas s", r.schema)).
+		Where(sq.Eq{"s.id": id})
+	return r.getSchema(ctx, "SchemaByID", q)
+}
+
+// SchemaByName: своя схема важнее глобальной с тем же именем.
+func (r *PGRepo) SchemaByName(ctx context.Context, user domain.UserID, name string) (domain.JSONSchema, error) {
+	q := r.qb().Select(schemaCols).
+		From(fmt.Sprintf("%s.json_schemas s", r.schema)).
+		Where(sq.Eq{"s.name": name}).
+		Where(sq.Or{sq.Eq{"s.owner_id": user}, sq.Expr("s.owner_id IS NULL")}).
+		OrderBy("s.owner_id IS NULL ASC").
+		Limit(1)
+	return r.getSchema(ctx, "SchemaByName", q)
+}
+
+func (r *PGRepo) getSchema(ctx context.Context, name string, q sq.SelectBuilder) (domain.JSONSchema, error) {
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL(name, sqlStr, args)
+
+	start := time.Now()
+	s, err := scanSchema(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("%s not found in %s", name, time.Since(start))
+			return domain.JSONSchema{}, domain.ErrNotFound
+		}
+		r.logger.Printf("%s scan error after %s: %v", name, time.Since(start), err)
+		return domain.JSONSchema{}, err
+	}
+	r.logger.Printf("%s ok in %s id=%s", name, time.Since(start), s.ID)
+	return s, nil
+}
+
+func (r *PGRepo) ListSchemas(ctx context.Context, user domain.UserID) ([]domain.JSONSchema, error) {
+	q := r.qb().Select(schemaCols).
+		From(fmt.Sprintf("%s.json_schemas s", r.schema)).
+		Where(sq.Or{sq.Eq{"s.owner_id": user}, sq.Expr("s.owner_id IS NULL")}).
+		OrderBy("s.name ASC", "s.owner_id IS NULL ASC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListSchemas", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListSchemas query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.JSONSchema{}
+	for rows.Next() {
+		s, err := scanSchema(rows)
+		if err != nil {
+			r.logger.Printf("ListSchemas scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListSchemas rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListSchemas ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+// DeleteSchema: строка схемы блокируется — параллельная привязка документа
+// (внешний ключ) дождётся конца транзакции и увидит, что схемы уже нет.
+func (r *PGRepo) DeleteSchema(ctx context.Context, id uuid.UUID) error {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("DeleteSchema begin error: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	lq := r.qb().Select("id").
+		From(fmt.Sprintf("%s.json_schemas", r.schema)).
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE")
+	ids, err := r.queryIDs(ctx, tx, "DeleteSchema.lock", lq)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		r.logger.Printf("DeleteSchema not found in %s id=%s", time.Since(start), id)
+		return domain.ErrNotFound
+	}
+
+	// документы в корзине тоже держат схему: после восстановления тело должно ей соответствовать
+	uq := r.qb().Select("id").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Eq{"schema_id": id}).
+		Limit(1)
+	used, err := r.queryIDs(ctx, tx, "DeleteSchema.used", uq)
+	if err != nil {
+		return err
+	}
+	if len(used) > 0 {
+		r.logger.Printf("DeleteSchema in use in %s id=%s", time.Since(start), id)
+		return domain.ErrConflict
+	}
+
+	dq := r.qb().Delete(fmt.Sprintf("%s.json_schemas", r.schema)).Where(sq.Eq{"id": id})
+	if err := r.execTx(ctx, tx, "DeleteSchema", dq); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("DeleteSchema commit error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("DeleteSchema ok in %s id=%s", time.Since(start), id)
+	return nil
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+var sessionCols = []string{
+	"id", "user_id", "current_jti", "user_agent", "ip",
+	"created_at", "last_seen_at", "expires_at", "revoked_at",
+}
+
+func scanSession(row pgx.Row) (domain.Session, error) {
+	var s domain.Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.CurrentJTI, &s.UserAgent, &s.IP,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt,
+	)
+	return s, err
+}
+
+func (r *PGRepo) CreateSession(ctx context.Context, s domain.Session) error {
+	q := r.qb().Insert(fmt.Sprintf("%s.sessions", r.schema)).
+		Columns("id", "user_id", "current_jti", "user_agent", "ip", "expires_at").
+		Values(s.ID, s.UserID, s.CurrentJTI, s.UserAgent, s.IP, s.ExpiresAt)
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateSession", sqlStr, args)
+
+	start := time.Now()
+	if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("CreateSession exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("CreateSession ok in %s id=%s user_id=%s", time.Since(start), s.ID, s.UserID)
+	return nil
+}
+
+func (r *PGRepo) UpdateSessionToken(ctx context.Context, id uuid.UUID, jti string, expiresAt time.Time, ip, userAgent string) error {
+	q := r.qb().Update(fmt.Sprintf("%s.sessions", r.schema)).
+		SetMap(map[string]any{
+			"current_jti":  jti,
+			"expires_at":   expiresAt,
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_seen_at": sq.Expr("now()"),
+		}).
+		Where(sq.Eq{"id": id, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UpdateSessionToken", sqlStr, args)
+
+	start := time.Now()
+	if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("UpdateSessionToken exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("UpdateSessionToken ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+func (r *PGRepo) ListActiveSessions(ctx context.Context, userID domain.UserID) ([]domain.Session, error) {
+	q := r.qb().Select(sessionCols...).
+		From(fmt.Sprintf("%s.sessions", r.schema)).
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil}).
+		Where(sq.Expr("expires_at > now()")).
+		OrderBy("last_seen_at DESC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListActiveSessions", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListActiveSessions query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			r.logger.Printf("ListActiveSessions scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListActiveSessions rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListActiveSessions ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) SessionByID(ctx context.Context, id uuid.UUID, userID domain.UserID) (domain.Session, error) {
+	q := r.qb().Select(sessionCols...).
+		From(fmt.Sprintf("%s.sessions", r.schema)).
+		Where(sq.Eq{"id": id, "user_id": userID})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("SessionByID", sqlStr, args)
+
+	start := time.Now()
+	s, err := scanSession(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("SessionByID scan error after %s: %v", time.Since(start), err)
+		return domain.Session{}, err
+	}
+	r.logger.Printf("SessionByID ok in %s id=%s", time.Since(start), s.ID)
+	return s, nil
+}
+
+func (r *PGRepo) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.sessions", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeSession", sqlStr, args)
+
+	start := time.Now()
+	if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("RevokeSession exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("RevokeSession ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+func (r *PGRepo) RevokeAllSessions(ctx context.Context, userID domain.UserID) (int64, error) {
+	q := r.qb().Update(fmt.Sprintf("%s.sessions", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeAllSessions", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokeAllSessions exec error after %s: %v", time.Since(start), err)
+		return 0, err
+	}
+	r.logger.Printf("RevokeAllSessions ok in %s user_id=%s rows=%d", time.Since(start), userID, tag.RowsAffected())
+	return tag.RowsAffected(), nil
+}
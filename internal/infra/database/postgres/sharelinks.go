@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+var shareLinkCols = []string{
+	"id", "doc_id", "created_by", "token_hash", "COALESCE(pass_hash, '')", "expires_at",
+	"max_downloads", "downloads", "created_at", "revoked_at",
+}
+
+func scanShareLink(row pgx.Row) (domain.ShareLink, error) {
+	var l domain.ShareLink
+	err := row.Scan(
+		&l.ID, &l.DocID, &l.CreatedBy, &l.TokenHash, &l.PassHash, &l.ExpiresAt,
+		&l.MaxDownloads, &l.Downloads, &l.CreatedAt, &l.RevokedAt,
+	)
+	l.HasPassword = l.PassHash != ""
+	return l, err
+}
+
+// activeLink: не отозвана, не истекла, лимит скачиваний не исчерпан.
+var activeLink = sq.And{
+	sq.Eq{"revoked_at": nil},
+	sq.Expr("(expires_at IS NULL OR expires_at > now())"),
+	sq.Expr("(max_downloads IS NULL OR downloads < max_downloads)"),
+}
+
+func (r *PGRepo) CreateShareLink(ctx context.Context, l domain.ShareLink) (domain.ShareLink, error) {
+	q := r.qb().Insert(fmt.Sprintf("%s.share_links", r.schema)).
+		Columns("doc_id", "created_by", "token_hash", "pass_hash", "expires_at", "max_downloads").
+		Values(l.DocID, l.CreatedBy, l.TokenHash, nullIfEmpty(l.PassHash), l.ExpiresAt, l.MaxDownloads).
+		Suffix("RETURNING " + joinCols(shareLinkCols))
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateShareLink", sqlStr, args)
+
+	start := time.Now()
+	out, err := scanShareLink(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("CreateShareLink scan error after %s: %v", time.Since(start), err)
+		return domain.ShareLink{}, err
+	}
+	r.logger.Printf("CreateShareLink ok in %s id=%s doc_id=%s", time.Since(start), out.ID, out.DocID)
+	return out, nil
+}
+
+func (r *PGRepo) ListShareLinks(ctx context.Context, docID domain.DocID) ([]domain.ShareLink, error) {
+	q := r.qb().Select(shareLinkCols...).
+		From(fmt.Sprintf("%s.share_links", r.schema)).
+		Where(sq.Eq{"doc_id": docID}).
+		OrderBy("created_at DESC")
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListShareLinks", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListShareLinks query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.ShareLink{}
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			r.logger.Printf("ListShareLinks scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListShareLinks rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListShareLinks ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) RevokeShareLink(ctx context.Context, docID domain.DocID, id uuid.UUID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.share_links", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "doc_id": docID, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokeShareLink", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokeShareLink exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("RevokeShareLink no rows affected in %s (not found or already revoked)", time.Since(start))
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("RevokeShareLink ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+func (r *PGRepo) ShareLinkByHash(ctx context.Context, tokenHash []byte) (domain.ShareLink, error) {
+	q := r.qb().Select(shareLinkCols...).
+		From(fmt.Sprintf("%s.share_links", r.schema)).
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(activeLink)
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ShareLinkByHash", sqlStr, args)
+
+	start := time.Now()
+	l, err := scanShareLink(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("ShareLinkByHash not found/inactive in %s", time.Since(start))
+			return domain.ShareLink{}, domain.ErrNotFound
+		}
+		r.logger.Printf("ShareLinkByHash scan error after %s: %v", time.Since(start), err)
+		return domain.ShareLink{}, err
+	}
+	r.logger.Printf("ShareLinkByHash ok in %s id=%s doc_id=%s", time.Since(start), l.ID, l.DocID)
+	return l, nil
+}
+
+// CountShareDownload: условие activeLink в UPDATE — параллельные запросы
+// не превысят max_downloads.
+func (r *PGRepo) CountShareDownload(ctx context.Context, id uuid.UUID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.share_links", r.schema)).
+		Set("downloads", sq.Expr("downloads + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(activeLink)
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CountShareDownload", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("CountShareDownload exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("CountShareDownload no rows affected in %s (link inactive)", time.Since(start))
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("CountShareDownload ok in %s id=%s", time.Since(start), id)
+	return nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+var patCols = []string{
+	"t.id", "t.user_id", "u.login", "u.role", "t.name", "t.token_hash", "t.scopes",
+	"t.created_at", "t.expires_at", "t.last_used_at", "t.revoked_at",
+}
+
+func scanPAT(row pgx.Row) (domain.PersonalToken, error) {
+	var t domain.PersonalToken
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.Login, &t.Role, &t.Name, &t.TokenHash, &t.Scopes,
+		&t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt,
+	)
+	return t, err
+}
+
+func (r *PGRepo) CreatePersonalToken(ctx context.Context, t domain.PersonalToken) (domain.PersonalToken, error) {
+	q := r.qb().Insert(fmt.Sprintf("%s.personal_tokens", r.schema)).
+		Columns("user_id", "name", "token_hash", "scopes", "expires_at").
+		Values(t.UserID, t.Name, t.TokenHash, t.Scopes, t.ExpiresAt).
+		Suffix("RETURNING id, created_at")
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreatePersonalToken", sqlStr, args)
+
+	start := time.Now()
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&t.ID, &t.CreatedAt); err != nil {
+		r.logger.Printf("CreatePersonalToken scan error after %s: %v", time.Since(start), err)
+		return domain.PersonalToken{}, err
+	}
+	r.logger.Printf("CreatePersonalToken ok in %s id=%s user_id=%s", time.Since(start), t.ID, t.UserID)
+	return t, nil
+}
+
+func (r *PGRepo) ListPersonalTokens(ctx context.Context, userID domain.UserID) ([]domain.PersonalToken, error) {
+	q := r.qb().Select(patCols...).
+		From(fmt.Sprintf("%s.personal_tokens t", r.schema)).
+		Join(fmt.Sprintf("%s.users u ON u.id = t.user_id", r.schema)).
+		Where(sq.Eq{"t.user_id": userID}).
+		OrderBy("t.created_at DESC")
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListPersonalTokens", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListPersonalTokens query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.PersonalToken{}
+	for rows.Next() {
+		t, err := scanPAT(rows)
+		if err != nil {
+			r.logger.Printf("ListPersonalTokens scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListPersonalTokens rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListPersonalTokens ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) PersonalTokenByHash(ctx context.Context, hash []byte) (domain.PersonalToken, error) {
+	q := r.qb().Select(patCols...).
+		From(fmt.Sprintf("%s.personal_tokens t", r.schema)).
+		Join(fmt.Sprintf("%s.users u ON u.id = t.user_id", r.schema)).
+		Where(sq.Eq{"t.token_hash": hash, "t.revoked_at": nil, "u.disabled_at": nil}).
+		Where(sq.Or{sq.Eq{"t.expires_at": nil}, sq.Expr("t.expires_at > now()")})
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("PersonalTokenByHash", sqlStr, args)
+
+	start := time.Now()
+	t, err := scanPAT(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		r.logger.Printf("PersonalTokenByHash scan error after %s: %v", time.Since(start), err)
+		return domain.PersonalToken{}, err
+	}
+	r.logger.Printf("PersonalTokenByHash ok in %s id=%s", time.Since(start), t.ID)
+	return t, nil
+}
+
+func (r *PGRepo) RevokePersonalToken(ctx context.Context, id uuid.UUID, userID domain.UserID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.personal_tokens", r.schema)).
+		Set("revoked_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "user_id": userID, "revoked_at": nil})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RevokePersonalToken", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("RevokePersonalToken exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("RevokePersonalToken no rows affected in %s (not found, not owner or already revoked)", time.Since(start))
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("RevokePersonalToken ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+// TouchPersonalToken обновляет last_used_at не чаще раза в минуту,
+// чтобы каждый запрос от CI не превращался в запись в БД.
+func (r *PGRepo) TouchPersonalToken(ctx context.Context, id uuid.UUID) error {
+	q := r.qb().Update(fmt.Sprintf("%s.personal_tokens", r.schema)).
+		Set("last_used_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Or{sq.Eq{"last_used_at": nil}, sq.Expr("last_used_at < now() - interval '1 minute'")})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("TouchPersonalToken", sqlStr, args)
+
+	start := time.Now()
+	if _, err := r.pool.Exec(ctx, sqlStr, args...); err != nil {
+		r.logger.Printf("TouchPersonalToken exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("TouchPersonalToken ok in %s id=%s", time.Since(start), id)
+	return nil
+}
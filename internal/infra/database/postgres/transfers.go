@@ -0,0 +1,271 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) CreateTransfer(ctx context.Context, t domain.DocTransfer) (domain.DocTransfer, error) {
+	q := r.qb().Insert(fmt.Sprintf("%s.doc_transfers", r.schema)).
+		Columns("doc_id", "from_user", "to_user", "keep_editor", "expires_at").
+		Values(t.DocID, t.FromUserID, t.ToUserID, t.KeepEditor, t.ExpiresAt).
+		Suffix("ON CONFLICT (doc_id) DO UPDATE SET from_user = EXCLUDED.from_user, to_user = EXCLUDED.to_user, " +
+			"keep_editor = EXCLUDED.keep_editor, created_at = now(), expires_at = EXCLUDED.expires_at " +
+			"RETURNING id, created_at")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CreateTransfer", sqlStr, args)
+
+	start := time.Now()
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&t.ID, &t.CreatedAt); err != nil {
+		r.logger.Printf("CreateTransfer scan error after %s: %v", time.Since(start), err)
+		return domain.DocTransfer{}, err
+	}
+	r.logger.Printf("CreateTransfer ok in %s id=%s doc_id=%s", time.Since(start), t.ID, t.DocID)
+	return t, nil
+}
+
+func (r *PGRepo) ListTransfers(ctx context.Context, user domain.UserID) ([]domain.DocTransfer, error) {
+	q := r.qb().Select(
+		"t.id", "t.doc_id", "d.name", "t.from_user", "uf.login", "t.to_user", "ut.login",
+		"t.keep_editor", "t.created_at", "t.expires_at",
+	).
+		From(fmt.Sprintf("%s.doc_transfers t", r.schema)).
+		Join(fmt.Sprintf("%s.documents d ON d.id = t.doc_id", r.schema)).
+		Join(fmt.Sprintf("%s.users uf ON uf.id = t.from_user", r.schema)).
+		Join(fmt.Sprintf("%s.users ut ON ut.id = t.to_user", r.schema)).
+		Where(sq.Or{sq.Eq{"t.from_user": user}, sq.Eq{"t.to_user": user}}).
+		Where("t.expires_at > now()").
+		Where("d.deleted_at IS NULL").
+		OrderBy("t.created_at DESC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListTransfers", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListTransfers query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.DocTransfer{}
+	for rows.Next() {
+		var t domain.DocTransfer
+		if err := rows.Scan(
+			&t.ID, &t.DocID, &t.DocName, &t.FromUserID, &t.From, &t.ToUserID, &t.To,
+			&t.KeepEditor, &t.CreatedAt, &t.ExpiresAt,
+		); err != nil {
+			r.logger.Printf("ListTransfers scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListTransfers rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListTransfers ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) CancelTransfer(ctx context.Context, id uuid.UUID, by domain.UserID) error {
+	q := r.qb().Delete(fmt.Sprintf("%s.doc_transfers", r.schema)).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Or{sq.Eq{"from_user": by}, sq.Eq{"to_user": by}})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("CancelTransfer", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("CancelTransfer exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("CancelTransfer no rows affected in %s id=%s", time.Since(start), id)
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("CancelTransfer ok in %s id=%s", time.Since(start), id)
+	return nil
+}
+
+func (r *PGRepo) AcceptTransfer(ctx context.Context, id uuid.UUID, to domain.UserID) (domain.OwnershipMove, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("AcceptTransfer begin error: %v", err)
+		return domain.OwnershipMove{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.qb().Select("doc_id", "from_user", "keep_editor").
+		From(fmt.Sprintf("%s.doc_transfers", r.schema)).
+		Where(sq.Eq{"id": id, "to_user": to}).
+		Where("expires_at > now()").
+		Suffix("FOR UPDATE")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("AcceptTransfer", sqlStr, args)
+
+	var (
+		docID      domain.DocID
+		from       domain.UserID
+		keepEditor bool
+	)
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(&docID, &from, &keepEditor); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("AcceptTransfer not found/expired in %s id=%s", time.Since(start), id)
+			return domain.OwnershipMove{}, domain.ErrNotFound
+		}
+		r.logger.Printf("AcceptTransfer scan error after %s: %v", time.Since(start), err)
+		return domain.OwnershipMove{}, err
+	}
+
+	// owner_id в условии: если документ уже передан иначе — ничего не двигаем
+	mv, err := r.moveOwnership(ctx, tx, sq.Eq{"id": docID, "owner_id": from}, from, to, keepEditor)
+	if err != nil {
+		r.logger.Printf("AcceptTransfer move error after %s: %v", time.Since(start), err)
+		return domain.OwnershipMove{}, err
+	}
+	if len(mv.Docs) == 0 {
+		r.logger.Printf("AcceptTransfer owner changed in %s id=%s doc_id=%s", time.Since(start), id, docID)
+		return domain.OwnershipMove{}, domain.ErrConflict
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("AcceptTransfer commit error after %s: %v", time.Since(start), err)
+		return domain.OwnershipMove{}, err
+	}
+	r.logger.Printf("AcceptTransfer ok in %s id=%s doc_id=%s", time.Since(start), id, docID)
+	return mv, nil
+}
+
+func (r *PGRepo) TransferAllDocs(ctx context.Context, from, to domain.UserID, keepEditor bool) (domain.OwnershipMove, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("TransferAllDocs begin error: %v", err)
+		return domain.OwnershipMove{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	mv, err := r.moveOwnership(ctx, tx, sq.Eq{"owner_id": from}, from, to, keepEditor)
+	if err != nil {
+		r.logger.Printf("TransferAllDocs move error after %s: %v", time.Since(start), err)
+		return domain.OwnershipMove{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("TransferAllDocs commit error after %s: %v", time.Since(start), err)
+		return domain.OwnershipMove{}, err
+	}
+	r.logger.Printf("TransferAllDocs ok in %s from=%s to=%s docs=%d", time.Since(start), from, to, len(mv.Docs))
+	return mv, nil
+}
+
+// moveOwnership переносит документы, выбранные условием where, от from к to.
+// Порядок важен: гранты и запросы чистим, пока where ещё выбирает документы
+// (условие может зависеть от owner_id), владельца меняем последним.
+func (r *PGRepo) moveOwnership(ctx context.Context, tx pgx.Tx, where sq.Sqlizer, from, to domain.UserID, keepEditor bool) (domain.OwnershipMove, error) {
+	docs := fmt.Sprintf("%s.documents", r.schema)
+	subSQL, subArgs, err := sq.Select("id").From(docs).Where(where).ToSql()
+	if err != nil {
+		return domain.OwnershipMove{}, err
+	}
+	inDocs := sq.Expr("doc_id IN ("+subSQL+")", subArgs...)
+
+	// кому документы видны через гранты — до того, как гранты поменяются
+	aq := r.qb().Select("user_id").
+		From(fmt.Sprintf("%s.doc_shares", r.schema)).
+		Where(inDocs).
+		Suffix("UNION SELECT gm.user_id FROM "+r.schema+".doc_group_shares gs JOIN "+r.schema+
+			".group_members gm ON gm.group_id = gs.group_id WHERE gs.doc_id IN ("+subSQL+")"+
+			" UNION "+r.folderAudienceSQL("d.id IN ("+subSQL+")"), append(append(append([]any{}, subArgs...), subArgs...), subArgs...)...)
+	audience, err := r.queryIDs(ctx, tx, "moveOwnership.audience", aq)
+	if err != nil {
+		return domain.OwnershipMove{}, err
+	}
+
+	// личный грант нового владельца больше не нужен
+	dq := r.qb().Delete(fmt.Sprintf("%s.doc_shares", r.schema)).
+		Where(sq.Eq{"user_id": to}).
+		Where(inDocs)
+	if err := r.execTx(ctx, tx, "moveOwnership.drop_grant", dq); err != nil {
+		return domain.OwnershipMove{}, err
+	}
+
+	if keepEditor {
+		sub := r.qb().Select().
+			Column("id AS doc_id").
+			Column("? AS user_id", from).
+			Column("? AS permission", domain.PermEditor).
+			From(docs).
+			Where(where)
+		iq := r.qb().Insert(fmt.Sprintf("%s.doc_shares", r.schema)).
+			Columns("doc_id", "user_id", "permission").
+			Select(sub).
+			Suffix("ON CONFLICT (doc_id, user_id) DO UPDATE SET permission = EXCLUDED.permission")
+		if err := r.execTx(ctx, tx, "moveOwnership.keep_editor", iq); err != nil {
+			return domain.OwnershipMove{}, err
+		}
+	}
+
+	// ожидающие запросы по этим документам теряют смысл
+	tq := r.qb().Delete(fmt.Sprintf("%s.doc_transfers", r.schema)).Where(inDocs)
+	if err := r.execTx(ctx, tx, "moveOwnership.drop_transfers", tq); err != nil {
+		return domain.OwnershipMove{}, err
+	}
+
+	// версия растёт — меняются ETag и закешированные метаданные;
+	// документ уходит из папок прежнего владельца в корень нового
+	uq := r.qb().Update(docs).
+		Set("owner_id", to).
+		Set("folder_id", nil).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", sq.Expr("now()")).
+		Where(where).
+		Suffix("RETURNING id")
+	sqlStr, args, _ := uq.ToSql()
+	r.logSQL("moveOwnership.update", sqlStr, args)
+	rows, err := tx.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("moveOwnership.update query error: %v", err)
+		return domain.OwnershipMove{}, err
+	}
+	defer rows.Close()
+
+	mv := domain.OwnershipMove{From: from, To: to, Audience: audience}
+	for rows.Next() {
+		var id domain.DocID
+		if err := rows.Scan(&id); err != nil {
+			r.logger.Printf("moveOwnership.update scan error: %v", err)
+			return domain.OwnershipMove{}, err
+		}
+		mv.Docs = append(mv.Docs, id)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("moveOwnership.update rows error: %v", err)
+		return domain.OwnershipMove{}, err
+	}
+	return mv, nil
+}
+
+func (r *PGRepo) execTx(ctx context.Context, tx pgx.Tx, name string, q sq.Sqlizer) error {
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL(name, sqlStr, args)
+	start := time.Now()
+	tag, err := tx.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("%s exec error after %s: %v", name, time.Since(start), err)
+		return err
+	}
+	r.logger.Printf("%s ok in %s rows=%d", name, time.Since(start), tag.RowsAffected())
+	return nil
+}
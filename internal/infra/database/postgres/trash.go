@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// trashManage — право управлять корзиной: co_owner и выше (как удаление).
+func (r *PGRepo) trashManage(by domain.UserID) sq.Sqlizer {
+	permSQL, permArgs := r.permExpr(domain.Principal{Kind: domain.PrincipalUser, UserID: by})
+	return sq.Expr(permSQL+" IN (?, ?)", append(permArgs, domain.PermCoOwner, domain.PermOwner)...)
+}
+
+func (r *PGRepo) ListTrash(ctx context.Context, by domain.UserID) ([]domain.Document, error) {
+	permSQL, permArgs := r.permExpr(domain.Principal{Kind: domain.PrincipalUser, UserID: by})
+	q := r.qb().Select(
+		"d.id", "d.owner_id", "d.name", "d.mime_type", "d.file", "d.public",
+		"d.size_bytes", "d.version", "d.created_at", "d.updated_at", "d.deleted_at", "d.folder_id", "d.schema_id",
+	).Column(sq.Expr(permSQL+" AS permission", permArgs...)).
+		From(fmt.Sprintf("%s.documents d", r.schema)).
+		Where("d.deleted_at IS NOT NULL").
+		Where(r.trashManage(by)).
+		OrderBy("d.deleted_at DESC")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListTrash", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListTrash query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Document{}
+	for rows.Next() {
+		var d domain.Document
+		if err := rows.Scan(
+			&d.ID, &d.OwnerID, &d.Name, &d.MIME, &d.File, &d.Public,
+			&d.SizeBytes, &d.Version, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt, &d.FolderID, &d.SchemaID, &d.Permission,
+		); err != nil {
+			r.logger.Printf("ListTrash scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListTrash rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListTrash ok in %s user_id=%s count=%d", time.Since(start), by, len(out))
+	return out, nil
+}
+
+// RestoreDoc: версия растёт — закешированные до удаления ETag не совпадут. Папка с
+// unique_names могла за это время получить документ с тем же именем — тогда ErrConflict.
+func (r *PGRepo) RestoreDoc(ctx context.Context, id domain.DocID, by domain.UserID) (domain.Document, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("RestoreDoc begin error: %v", err)
+		return domain.Document{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.qb().Update(fmt.Sprintf("%s.documents d", r.schema)).
+		Set("deleted_at", nil).
+		Set("deleted_by", nil).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"d.id": id}).
+		Where("d.deleted_at IS NOT NULL").
+		Where(r.trashManage(by)).
+		Suffix("RETURNING d.id, d.owner_id, d.name, d.mime_type, d.file, d.public, d.size_bytes, d.storage_key, d.content_sha256, d.version, d.created_at, d.updated_at, d.folder_id, d.schema_id")
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("RestoreDoc", sqlStr, args)
+
+	var d domain.Document
+	if err := tx.QueryRow(ctx, sqlStr, args...).Scan(
+		&d.ID, &d.OwnerID, &d.Name, &d.MIME, &d.File, &d.Public,
+		&d.SizeBytes, &d.StorageKey, &d.SHA256, &d.Version, &d.CreatedAt, &d.UpdatedAt, &d.FolderID, &d.SchemaID,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("RestoreDoc not in trash or no permission in %s id=%s", time.Since(start), id)
+			return domain.Document{}, domain.ErrNotFound
+		}
+		r.logger.Printf("RestoreDoc scan error after %s: %v", time.Since(start), err)
+		return domain.Document{}, err
+	}
+
+	if d.FolderID != nil {
+		if err := r.lockFolderNames(ctx, tx, *d.FolderID); err != nil {
+			return domain.Document{}, err
+		}
+		taken, err := r.nameTaken(ctx, tx, *d.FolderID, d.Name, d.ID)
+		if err != nil {
+			return domain.Document{}, err
+		}
+		if taken {
+			r.logger.Printf("RestoreDoc name taken in %s id=%s folder=%s", time.Since(start), id, *d.FolderID)
+			return domain.Document{}, domain.ErrConflict
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("RestoreDoc commit error after %s: %v", time.Since(start), err)
+		return domain.Document{}, err
+	}
+	r.logger.Printf("RestoreDoc ok in %s id=%s", time.Since(start), id)
+	return d, nil
+}
+
+func (r *PGRepo) PurgeDoc(ctx context.Context, id domain.DocID, by domain.UserID) ([]string, error) {
+	n, keys, err := r.purge(ctx, "PurgeDoc", sq.And{
+		sq.Eq{"d.id": id},
+		sq.Expr("d.deleted_at IS NOT NULL"),
+		r.trashManage(by),
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return keys, nil
+}
+
+func (r *PGRepo) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, []string, error) {
+	return r.purge(ctx, "PurgeExpired", sq.And{
+		sq.Expr("d.deleted_at IS NOT NULL"),
+		sq.Lt{"d.deleted_at": before},
+	}, limit)
+}
+
+// purge окончательно удаляет документы из корзины (гранты, JSON, ревизии — каскадом)
+// и возвращает их blob-ключи, собранные до удаления. SKIP LOCKED — несколько реплик
+// с фоновой чисткой не ждут друг друга.
+func (r *PGRepo) purge(ctx context.Context, name string, where sq.Sqlizer, limit int) (int, []string, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("%s begin error: %v", name, err)
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sel := r.qb().Select("d.id").
+		From(fmt.Sprintf("%s.documents d", r.schema)).
+		Where(where).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	if limit > 0 {
+		sel = sel.OrderBy("d.deleted_at").Limit(uint64(limit))
+	}
+	ids, err := r.queryIDs(ctx, tx, name+".select", sel)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		r.logger.Printf("%s nothing to purge in %s", name, time.Since(start))
+		return 0, nil, nil
+	}
+
+	kq := r.qb().Select("storage_key").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Expr("id = ANY(?)", ids)).
+		Where(sq.NotEq{"storage_key": ""}).
+		Suffix("UNION SELECT storage_key FROM "+r.schema+".document_versions WHERE doc_id = ANY(?)", ids)
+	keys, err := r.queryKeys(ctx, tx, name+".keys", kq)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	dq := r.qb().Delete(fmt.Sprintf("%s.documents", r.schema)).Where(sq.Expr("id = ANY(?)", ids))
+	if err := r.execTx(ctx, tx, name+".delete", dq); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("%s commit error after %s: %v", name, time.Since(start), err)
+		return 0, nil, err
+	}
+	r.logger.Printf("%s ok in %s docs=%d keys=%d", name, time.Since(start), len(ids), len(keys))
+	return len(ids), keys, nil
+}
@@ -2,26 +2,38 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/EgorLis/my-docs/internal/domain"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
-func (r *PGRepo) CreateUser(ctx context.Context, login string, passHash []byte) (domain.User, error) {
+var userCols = []string{"id", "login", "pass_hash", "created_at", "totp_enabled", "role", "disabled_at", "invited_by"}
+
+func scanUser(row pgx.Row) (domain.User, error) {
+	var u domain.User
+	err := row.Scan(&u.ID, &u.Login, &u.PassHash, &u.CreatedAt, &u.MFAEnabled, &u.Role, &u.DisabledAt, &u.InvitedBy)
+	return u, err
+}
+
+func (r *PGRepo) CreateUser(ctx context.Context, login string, passHash []byte, role domain.Role) (domain.User, error) {
+	if role == "" {
+		role = domain.RoleUser
+	}
 	q := r.qb().Insert(fmt.Sprintf("%s.users", r.schema)).
-		Columns("login", "pass_hash").
-		Values(login, passHash).
-		Suffix("RETURNING id, login, pass_hash, created_at")
+		Columns("login", "pass_hash", "role").
+		Values(login, passHash, role).
+		Suffix("RETURNING " + joinCols(userCols))
 
 	sqlStr, args, _ := q.ToSql()
 	r.logSQL("CreateUser", sqlStr, args)
 
 	start := time.Now()
-	row := r.pool.QueryRow(ctx, sqlStr, args...)
-	var u domain.User
-	if err := row.Scan(&u.ID, &u.Login, &u.PassHash, &u.CreatedAt); err != nil {
+	u, err := scanUser(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
 		r.logger.Printf("CreateUser scan error after %s: %v", time.Since(start), err)
 		return domain.User{}, err
 	}
@@ -30,7 +42,7 @@ func (r *PGRepo) CreateUser(ctx context.Context, login string, passHash []byte)
 }
 
 func (r *PGRepo) UserByLogin(ctx context.Context, login string) (domain.User, error) {
-	q := r.qb().Select("id", "login", "pass_hash", "created_at").
+	q := r.qb().Select(userCols...).
 		From(fmt.Sprintf("%s.users", r.schema)).
 		Where(sq.Eq{"login": login})
 
@@ -38,9 +50,8 @@ func (r *PGRepo) UserByLogin(ctx context.Context, login string) (domain.User, er
 	r.logSQL("UserByLogin", sqlStr, args)
 
 	start := time.Now()
-	row := r.pool.QueryRow(ctx, sqlStr, args...)
-	var u domain.User
-	if err := row.Scan(&u.ID, &u.Login, &u.PassHash, &u.CreatedAt); err != nil {
+	u, err := scanUser(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
 		r.logger.Printf("UserByLogin scan error after %s: %v", time.Since(start), err)
 		return domain.User{}, err
 	}
@@ -49,7 +60,7 @@ func (r *PGRepo) UserByLogin(ctx context.Context, login string) (domain.User, er
 }
 
 func (r *PGRepo) UserByID(ctx context.Context, id domain.UserID) (domain.User, error) {
-	q := r.qb().Select("id", "login", "pass_hash", "created_at").
+	q := r.qb().Select(userCols...).
 		From(fmt.Sprintf("%s.users", r.schema)).
 		Where(sq.Eq{"id": id})
 
@@ -57,12 +68,37 @@ func (r *PGRepo) UserByID(ctx context.Context, id domain.UserID) (domain.User, e
 	r.logSQL("UserByID", sqlStr, args)
 
 	start := time.Now()
-	row := r.pool.QueryRow(ctx, sqlStr, args...)
-	var u domain.User
-	if err := row.Scan(&u.ID, &u.Login, &u.PassHash, &u.CreatedAt); err != nil {
+	u, err := scanUser(r.pool.QueryRow(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("UserByID not found in %s id=%s", time.Since(start), id)
+			return domain.User{}, domain.ErrNotFound
+		}
 		r.logger.Printf("UserByID scan error after %s: %v", time.Since(start), err)
 		return domain.User{}, err
 	}
 	r.logger.Printf("UserByID ok in %s id=%s", time.Since(start), u.ID)
 	return u, nil
 }
+
+func (r *PGRepo) SetPasswordHash(ctx context.Context, id domain.UserID, passHash []byte) error {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("pass_hash", passHash).
+		Where(sq.Eq{"id": id})
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("SetPasswordHash", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("SetPasswordHash exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("SetPasswordHash no rows affected in %s id=%s", time.Since(start), id)
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("SetPasswordHash ok in %s id=%s", time.Since(start), id)
+	return nil
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func (r *PGRepo) ListUsers(ctx context.Context, f domain.UserFilter) ([]domain.User, error) {
+	q := r.qb().Select(userCols...).
+		From(fmt.Sprintf("%s.users", r.schema)).
+		OrderBy("login ASC")
+
+	if f.Query != "" {
+		q = q.Where(sq.ILike{"login": "%" + escapeLike(f.Query) + "%"})
+	}
+	if f.Role != "" {
+		q = q.Where(sq.Eq{"role": f.Role})
+	}
+	if f.Disabled != nil {
+		if *f.Disabled {
+			q = q.Where(sq.NotEq{"disabled_at": nil})
+		} else {
+			q = q.Where(sq.Eq{"disabled_at": nil})
+		}
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	q = q.Limit(uint64(limit))
+	if f.Offset > 0 {
+		q = q.Offset(uint64(f.Offset))
+	}
+
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("ListUsers", sqlStr, args)
+
+	start := time.Now()
+	rows, err := r.pool.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("ListUsers query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			r.logger.Printf("ListUsers scan error: %v", err)
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("ListUsers rows error: %v", err)
+		return nil, err
+	}
+	r.logger.Printf("ListUsers ok in %s count=%d", time.Since(start), len(out))
+	return out, nil
+}
+
+func (r *PGRepo) SetUserDisabled(ctx context.Context, id domain.UserID, disabled bool) error {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).Where(sq.Eq{"id": id})
+	if disabled {
+		q = q.Set("disabled_at", sq.Expr("COALESCE(disabled_at, now())"))
+	} else {
+		q = q.Set("disabled_at", nil)
+	}
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("SetUserDisabled", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("SetUserDisabled exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("SetUserDisabled no rows affected in %s id=%s", time.Since(start), id)
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("SetUserDisabled ok in %s id=%s disabled=%t", time.Since(start), id, disabled)
+	return nil
+}
+
+func (r *PGRepo) SetUserRole(ctx context.Context, id domain.UserID, role domain.Role) error {
+	q := r.qb().Update(fmt.Sprintf("%s.users", r.schema)).
+		Set("role", role).
+		Where(sq.Eq{"id": id})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("SetUserRole", sqlStr, args)
+
+	start := time.Now()
+	tag, err := r.pool.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("SetUserRole exec error after %s: %v", time.Since(start), err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("SetUserRole no rows affected in %s id=%s", time.Since(start), id)
+		return domain.ErrNotFound
+	}
+	r.logger.Printf("SetUserRole ok in %s id=%s role=%s", time.Since(start), id, role)
+	return nil
+}
+
+// DeleteUser: документы, шаринги, токены и сессии удаляются каскадом;
+// наружу отдаём id/storage_key документов для чистки storage и кеша.
+func (r *PGRepo) DeleteUser(ctx context.Context, id domain.UserID) ([]domain.Document, error) {
+	start := time.Now()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Printf("DeleteUser begin error: %v", err)
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sel := r.qb().Select("id", "storage_key").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Eq{"owner_id": id})
+	sqlStr, args, _ := sel.ToSql()
+	r.logSQL("DeleteUser.docs", sqlStr, args)
+	rows, err := tx.Query(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("DeleteUser docs query error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	docs := []domain.Document{}
+	for rows.Next() {
+		var d domain.Document
+		if err := rows.Scan(&d.ID, &d.StorageKey); err != nil {
+			rows.Close()
+			r.logger.Printf("DeleteUser docs scan error: %v", err)
+			return nil, err
+		}
+		d.OwnerID = id
+		docs = append(docs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Printf("DeleteUser docs rows error: %v", err)
+		return nil, err
+	}
+
+	del := r.qb().Delete(fmt.Sprintf("%s.users", r.schema)).Where(sq.Eq{"id": id})
+	sqlStr, args, _ = del.ToSql()
+	r.logSQL("DeleteUser.user", sqlStr, args)
+	tag, err := tx.Exec(ctx, sqlStr, args...)
+	if err != nil {
+		r.logger.Printf("DeleteUser exec error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		r.logger.Printf("DeleteUser no rows affected in %s id=%s", time.Since(start), id)
+		return nil, domain.ErrNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Printf("DeleteUser commit error after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+	r.logger.Printf("DeleteUser ok in %s id=%s docs=%d", time.Since(start), id, len(docs))
+	return docs, nil
+}
+
+func (r *PGRepo) UserStorageUsage(ctx context.Context, id domain.UserID) (domain.StorageUsage, error) {
+	q := r.qb().Select("count(*)", "COALESCE(sum(size_bytes), 0)").
+		From(fmt.Sprintf("%s.documents", r.schema)).
+		Where(sq.Eq{"owner_id": id})
+	sqlStr, args, _ := q.ToSql()
+	r.logSQL("UserStorageUsage", sqlStr, args)
+
+	start := time.Now()
+	var u domain.StorageUsage
+	if err := r.pool.QueryRow(ctx, sqlStr, args...).Scan(&u.Docs, &u.Bytes); err != nil {
+		r.logger.Printf("UserStorageUsage scan error after %s: %v", time.Since(start), err)
+		return domain.StorageUsage{}, err
+	}
+	r.logger.Printf("UserStorageUsage ok in %s id=%s docs=%d bytes=%d", time.Since(start), id, u.Docs, u.Bytes)
+	return u, nil
+}
@@ -0,0 +1,153 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	jsv "github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+const (
+	draft2020 = "https://json-schema.org/draft/2020-12/schema"
+	// адрес схемы внутри компилятора; наружу не ходит
+	resourceURL = "mem:///schema.json"
+	// больше подробностей клиенту не нужно — хватит, чтобы исправить первые ошибки
+	maxDetails = 50
+)
+
+// Validator компилирует схемы draft 2020-12 и держит скомпилированные в памяти:
+// тело схемы неизменно, поэтому ключ — её id.
+type Validator struct {
+	mu       sync.Mutex
+	compiled map[uuid.UUID]*jsv.Schema
+	max      int
+}
+
+var _ domain.SchemaValidator = (*Validator)(nil)
+
+func New(maxCached int) *Validator {
+	if maxCached <= 0 {
+		maxCached = 256
+	}
+	return &Validator{compiled: map[uuid.UUID]*jsv.Schema{}, max: maxCached}
+}
+
+// noLoader: по умолчанию компилятор читает file:// — $ref наружу запрещены.
+type noLoader struct{}
+
+func (noLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("external $ref is not allowed: %s", url)
+}
+
+func compile(body json.RawMessage) (*jsv.Schema, error) {
+	doc, err := jsv.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil, invalid("", "schema is not valid JSON")
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, invalid("", "schema must be a JSON object")
+	}
+	if v, ok := obj["$schema"]; ok && v != draft2020 && v != draft2020+"#" {
+		return nil, invalid("/$schema", "only draft 2020-12 is supported")
+	}
+
+	c := jsv.NewCompiler()
+	c.DefaultDraft(jsv.Draft2020)
+	c.AssertFormat()
+	c.UseLoader(noLoader{})
+	if err := c.AddResource(resourceURL, doc); err != nil {
+		return nil, invalid("", err.Error())
+	}
+	sch, err := c.Compile(resourceURL)
+	if err != nil {
+		var se *jsv.SchemaValidationError
+		var ve *jsv.ValidationError
+		if errors.As(err, &se) && errors.As(se.Err, &ve) {
+			return nil, &domain.ValidationError{Base: domain.ErrBadParams, Details: details(ve)}
+		}
+		return nil, invalid("", err.Error())
+	}
+	return sch, nil
+}
+
+func (v *Validator) Check(body json.RawMessage) error {
+	_, err := compile(body)
+	return err
+}
+
+func (v *Validator) Validate(s domain.JSONSchema, body domain.DocJSON) error {
+	sch, err := v.schema(s)
+	if err != nil {
+		return err
+	}
+	inst := map[string]any(body)
+	if inst == nil {
+		inst = map[string]any{}
+	}
+	if err := sch.Validate(inst); err != nil {
+		var ve *jsv.ValidationError
+		if errors.As(err, &ve) {
+			return &domain.ValidationError{Base: domain.ErrSchemaViolation, Details: details(ve)}
+		}
+		return err
+	}
+	return nil
+}
+
+func (v *Validator) schema(s domain.JSONSchema) (*jsv.Schema, error) {
+	v.mu.Lock()
+	sch, ok := v.compiled[s.ID]
+	v.mu.Unlock()
+	if ok {
+		return sch, nil
+	}
+
+	// схема проверялась при регистрации; ошибка здесь — испорченная запись в БД
+	sch, err := compile(s.Body)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema %s: %w", s.ID, err)
+	}
+	v.mu.Lock()
+	if len(v.compiled) >= v.max {
+		clear(v.compiled)
+	}
+	v.compiled[s.ID] = sch
+	v.mu.Unlock()
+	return sch, nil
+}
+
+// details — листья дерева ошибок: промежуточные узлы ($ref, allOf) только
+// повторяют «validation failed».
+func details(ve *jsv.ValidationError) []domain.ErrorDetail {
+	var out []domain.ErrorDetail
+	var walk func(u jsv.OutputUnit)
+	walk = func(u jsv.OutputUnit) {
+		if len(out) >= maxDetails {
+			return
+		}
+		if len(u.Errors) == 0 {
+			msg := "validation failed"
+			if u.Error != nil {
+				msg = u.Error.String()
+			}
+			out = append(out, domain.ErrorDetail{Path: u.InstanceLocation, Keyword: u.KeywordLocation, Message: msg})
+			return
+		}
+		for _, c := range u.Errors {
+			walk(c)
+		}
+	}
+	walk(*ve.DetailedOutput())
+	return out
+}
+
+func invalid(path, msg string) error {
+	return &domain.ValidationError{Base: domain.ErrBadParams, Details: []domain.ErrorDetail{{Path: path, Message: msg}}}
+}
@@ -0,0 +1,131 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+const invoiceSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["customer", "total"],
+	"properties": {
+		"customer": {"$ref": "#/$defs/name"},
+		"total": {"type": "number", "minimum": 0},
+		"items": {"type": "array", "items": {"type": "string"}}
+	},
+	"$defs": {"name": {"type": "string", "minLength": 1}}
+}`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		wantErr  bool
+		wantPath string
+	}{
+		{"корректная схема", invoiceSchema, false, ""},
+		{"без $schema", `{"type":"object"}`, false, ""},
+		{"не JSON", `{"type":`, true, ""},
+		{"не объект", `[1,2]`, true, ""},
+		{"другой draft", `{"$schema":"http://json-schema.org/draft-07/schema#"}`, true, "/$schema"},
+		{"внешний $ref", `{"$ref":"file:///etc/passwd"}`, true, ""},
+		{"http $ref", `{"$ref":"https://example.com/s.json"}`, true, ""},
+		{"неверный тип ключевого слова", `{"type":5}`, true, "/type"},
+	}
+	v := New(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Check(json.RawMessage(tt.schema))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var ve *domain.ValidationError
+			if !errors.As(err, &ve) || !errors.Is(err, domain.ErrBadParams) {
+				t.Fatalf("Check err = %v, want ValidationError(ErrBadParams)", err)
+			}
+			if len(ve.Details) == 0 {
+				t.Fatal("no details")
+			}
+			if tt.wantPath != "" && ve.Details[0].Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", ve.Details[0].Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestValidateDetails(t *testing.T) {
+	s := domain.JSONSchema{ID: uuid.New(), Body: json.RawMessage(invoiceSchema)}
+	tests := []struct {
+		name      string
+		body      string
+		wantPaths []string
+	}{
+		{"валидное тело", `{"customer":"ACME","total":10,"items":["a"]}`, nil},
+		{"нет обязательного поля", `{"customer":"ACME"}`, []string{""}},
+		{"ошибка через $ref", `{"customer":"","total":1}`, []string{"/customer"}},
+		{"несколько ошибок", `{"customer":"ACME","total":-1,"items":["a",2]}`, []string{"/items/1", "/total"}},
+	}
+	v := New(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body domain.DocJSON
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			err := v.Validate(s, body)
+			if tt.wantPaths == nil {
+				if err != nil {
+					t.Fatalf("Validate err = %v", err)
+				}
+				return
+			}
+			var ve *domain.ValidationError
+			if !errors.As(err, &ve) || !errors.Is(err, domain.ErrSchemaViolation) {
+				t.Fatalf("Validate err = %v, want ValidationError(ErrSchemaViolation)", err)
+			}
+			var paths []string
+			for _, d := range ve.Details {
+				// только листья: промежуточные «validation failed» не попадают
+				if d.Message == "" || d.Message == "validation failed" || d.Keyword == "" {
+					t.Errorf("non-leaf detail %+v", d)
+				}
+				paths = append(paths, d.Path)
+			}
+			sort.Strings(paths)
+			if strings.Join(paths, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("paths = %q, want %q", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestDetailsLimit(t *testing.T) {
+	props := make([]string, 0, maxDetails+10)
+	for i := range maxDetails + 10 {
+		props = append(props, fmt.Sprintf(`"p%d":{"type":"string"}`, i))
+	}
+	s := domain.JSONSchema{ID: uuid.New(), Body: json.RawMessage(`{"properties":{` + strings.Join(props, ",") + `}}`)}
+	body := domain.DocJSON{}
+	for i := range maxDetails + 10 {
+		body[fmt.Sprintf("p%d", i)] = float64(i)
+	}
+	var ve *domain.ValidationError
+	if err := New(0).Validate(s, body); !errors.As(err, &ve) {
+		t.Fatalf("Validate err = %v, want ValidationError", err)
+	}
+	if len(ve.Details) != maxDetails {
+		t.Errorf("details = %d, want %d", len(ve.Details), maxDetails)
+	}
+}
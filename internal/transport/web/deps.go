@@ -1,15 +1,49 @@
 package web
 
-import "github.com/EgorLis/my-docs/internal/domain"
+import (
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+)
 
 type Repos struct {
-	Users  domain.UsersRepo
-	Docs   domain.DocsRepo
-	Shares domain.SharesRepo
+	Users      domain.UsersRepo
+	Docs       domain.DocsRepo
+	Shares     domain.SharesRepo
+	ShareLinks domain.ShareLinksRepo
+	Groups     domain.GroupsRepo
+	Transfers  domain.TransfersRepo
+	Revisions  domain.RevisionsRepo
+	Trash      domain.TrashRepo
+	Folders    domain.FoldersRepo
+	Schemas    domain.SchemasRepo
+	// Проверка JSON-тел документов по схемам из Schemas
+	SchemaValidator domain.SchemaValidator
+	RefreshTokens   domain.RefreshTokensRepo
+	PersonalTokens  domain.PersonalTokensRepo
+	Sessions        domain.SessionsRepo
+	LoginAttempts   domain.LoginAttemptsRepo
+	MFA             domain.MFARepo
+	UserAdmin       domain.UserAdminRepo
+	Invites         domain.InvitesRepo
+	Identities      domain.ExternalIdentityRepo
 }
 
 type AuthDeps struct {
 	Hasher    domain.PasswordHasher
 	Tokens    domain.TokenManager
 	Blacklist domain.TokenBlacklist
+	Keys      domain.KeySetProvider
+	Epochs    domain.TokenEpochs
+	Disabled  domain.DisabledUsers
+	Throttle  domain.LoginThrottle
+	// 2FA: Box == nil — ключ не настроен, подключение TOTP недоступно
+	Box        domain.SecretBox
+	MFAPending domain.MFAPendingStore
+	// OIDC: nil — провайдер не настроен
+	OIDC       domain.OIDCProvider
+	OIDCStates domain.OIDCStateStore
+	// Браузерные cookie-сессии и политика токенов в query string
+	Cookies     *mw.Cookies
+	QueryTokens mw.QueryTokenMode
+	Tickets     domain.DownloadTickets
 }
@@ -3,72 +3,252 @@ package mw
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/EgorLis/my-docs/internal/auth/token"
 	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/google/uuid"
 )
 
-const userKey ctxKey = "auth_user"
+const (
+	userKey ctxKey = "auth_user"
+	infoKey ctxKey = "auth_info"
+)
+
+// Способ, которым аутентифицирован запрос
+type AuthKind string
+
+const (
+	AuthJWT    AuthKind = "jwt"
+	AuthPAT    AuthKind = "pat"
+	AuthTicket AuthKind = "ticket" // одноразовая ссылка на скачивание
+)
+
+// Что принимаем в ?token= (токен в URL попадает в логи, историю браузера и Referer)
+type QueryTokenMode string
+
+const (
+	QueryTokenAny      QueryTokenMode = "any"      // JWT/PAT и одноразовые ссылки (по умолчанию, как раньше)
+	QueryTokenDownload QueryTokenMode = "download" // только одноразовые ссылки mdt_...
+	QueryTokenOff      QueryTokenMode = "off"      // query string не аутентифицирует вовсе
+)
+
+// ParseQueryTokenMode: пусто и неизвестные значения — any.
+func ParseQueryTokenMode(s string) QueryTokenMode {
+	switch m := QueryTokenMode(strings.ToLower(s)); m {
+	case QueryTokenDownload, QueryTokenOff:
+		return m
+	default:
+		return QueryTokenAny
+	}
+}
+
+// AllowsBearer — можно ли передать JWT/PAT в query string.
+func (m QueryTokenMode) AllowsBearer() bool { return m == "" || m == QueryTokenAny }
+
+// AuthInfo — детали аутентификации текущего запроса.
+type AuthInfo struct {
+	Kind      AuthKind
+	JTI       string    // JWT
+	ExpiresAt time.Time // JWT
+	TokenID   uuid.UUID // PAT
+	Scopes    []string  // PAT и билет; у JWT-сессии все скоупы
+	ViaCookie bool      // JWT пришёл в cookie — изменяющие запросы требуют CSRF-токен
+}
 
 type AuthDeps struct {
 	Tokens    domain.TokenManager
 	Blacklist domain.TokenBlacklist
+	PATs      domain.PersonalTokensRepo
+	Epochs    domain.TokenEpochs
+	// users.disabled_at с кешем: без него пропавший ключ эпохи пропустил бы
+	// токен отключённого пользователя
+	Disabled domain.DisabledUsers
+	// Браузерные сессии в cookie (nil/выключено — только Bearer)
+	Cookies     *Cookies
+	QueryTokens QueryTokenMode
+	Tickets     domain.DownloadTickets
 }
 
+// OptionalAuth пропускает запрос без учётных данных анонимом. Если учётные данные
+// переданы, но не приняты — 401, как в RequireAuth: клиент с протухшим токеном
+// должен узнать об этом, а не молча получить анонимный ответ.
 func OptionalAuth(deps AuthDeps, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		raw := extractTokenAny(r) // <-- ТУТ
-		if raw == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-		claims, err := deps.Tokens.Parse(r.Context(), raw)
-		if err != nil {
+		u, info, ok := authenticate(deps, r)
+		if !ok {
+			if hasCredentials(deps, r) {
+				http.Error(w, `{"error":{"code":1001,"text":"unauthorized"}}`, http.StatusUnauthorized)
+				return
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
-		if revoked, _ := deps.Blacklist.IsRevoked(r.Context(), claims.JTI); revoked {
-			next.ServeHTTP(w, r)
+		if !csrfOK(deps, r, info) {
+			writeCSRFError(w)
 			return
 		}
-		u := domain.User{ID: claims.UserID, Login: claims.Login}
-		ctx := context.WithValue(r.Context(), userKey, u)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withAuth(r.Context(), u, info)))
 	})
 }
 
+// hasCredentials: запрос несёт хоть какие-то учётные данные (см. authenticate).
+func hasCredentials(deps AuthDeps, r *http.Request) bool {
+	if bearerToken(r) != "" || deps.Cookies.Access(r) != "" {
+		return true
+	}
+	return deps.QueryTokens != QueryTokenOff && r.URL.Query().Get("token") != ""
+}
+
 func RequireAuth(deps AuthDeps, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		raw := extractTokenAny(r) // <-- ТУТ
-		if raw == "" {
+		u, info, ok := authenticate(deps, r)
+		if !ok {
 			http.Error(w, `{"error":{"code":1001,"text":"unauthorized"}}`, http.StatusUnauthorized)
 			return
 		}
-		claims, err := deps.Tokens.Parse(r.Context(), raw)
-		if err != nil {
-			http.Error(w, `{"error":{"code":1001,"text":"unauthorized"}}`, http.StatusUnauthorized)
+		if !csrfOK(deps, r, info) {
+			writeCSRFError(w)
 			return
 		}
-		if revoked, _ := deps.Blacklist.IsRevoked(r.Context(), claims.JTI); revoked {
-			http.Error(w, `{"error":{"code":1001,"text":"unauthorized"}}`, http.StatusUnauthorized)
+		next.ServeHTTP(w, r.WithContext(withAuth(r.Context(), u, info)))
+	})
+}
+
+// authenticate принимает, по порядку: Authorization: Bearer (JWT или PAT mdp_...),
+// cookie-сессию и ?token= — в зависимости от QueryTokens.
+func authenticate(deps AuthDeps, r *http.Request) (domain.User, AuthInfo, bool) {
+	if raw := bearerToken(r); raw != "" {
+		return authenticateToken(deps, r, raw)
+	}
+	if raw := deps.Cookies.Access(r); raw != "" {
+		u, info, ok := authenticateJWT(deps, r, raw)
+		info.ViaCookie = true
+		return u, info, ok
+	}
+
+	raw := r.URL.Query().Get("token") // не трогаем тело (без ParseForm), безопасно для multipart
+	switch {
+	case raw == "" || deps.QueryTokens == QueryTokenOff:
+		return domain.User{}, AuthInfo{}, false
+	case strings.HasPrefix(raw, domain.DownloadTicketPrefix):
+		return authenticateTicket(deps, r, raw)
+	case deps.QueryTokens.AllowsBearer():
+		return authenticateToken(deps, r, raw)
+	default:
+		return domain.User{}, AuthInfo{}, false
+	}
+}
+
+func authenticateToken(deps AuthDeps, r *http.Request, raw string) (domain.User, AuthInfo, bool) {
+	if strings.HasPrefix(raw, domain.PersonalTokenPrefix) {
+		if deps.PATs == nil {
+			return domain.User{}, AuthInfo{}, false
+		}
+		pat, err := deps.PATs.PersonalTokenByHash(r.Context(), token.HashOpaque(raw))
+		if err != nil {
+			return domain.User{}, AuthInfo{}, false
+		}
+		_ = deps.PATs.TouchPersonalToken(r.Context(), pat.ID)
+		u := domain.User{ID: pat.UserID, Login: pat.Login, Role: pat.Role}
+		return u, AuthInfo{Kind: AuthPAT, TokenID: pat.ID, Scopes: pat.Scopes}, true
+	}
+	return authenticateJWT(deps, r, raw)
+}
+
+func authenticateJWT(deps AuthDeps, r *http.Request, raw string) (domain.User, AuthInfo, bool) {
+	claims, err := deps.Tokens.Parse(r.Context(), raw)
+	if err != nil {
+		return domain.User{}, AuthInfo{}, false
+	}
+	if revoked, _ := deps.Blacklist.IsRevoked(r.Context(), claims.JTI); revoked {
+		return domain.User{}, AuthInfo{}, false
+	}
+	// "выйти везде" / отключение / смена роли: токены, выданные до эпохи пользователя,
+	// недействительны. Кеш недоступен — отказ: иначе отозванный токен прошёл бы
+	if deps.Epochs != nil {
+		ep, err := deps.Epochs.Epoch(r.Context(), claims.UserID)
+		if err != nil || (!ep.IsZero() && claims.IssuedAt.Before(ep)) {
+			return domain.User{}, AuthInfo{}, false
+		}
+	}
+	// ключ эпохи мог быть вытеснен или истечь — отключение проверяется и по БД
+	if deps.Disabled != nil {
+		if disabled, err := deps.Disabled.IsDisabled(r.Context(), claims.UserID); err != nil || disabled {
+			return domain.User{}, AuthInfo{}, false
+		}
+	}
+	u := domain.User{ID: claims.UserID, Login: claims.Login, Role: claims.Role}
+	return u, AuthInfo{Kind: AuthJWT, JTI: claims.JTI, ExpiresAt: claims.ExpiresAt}, true
+}
+
+// authenticateTicket: одноразовая ссылка годится только для GET/HEAD того пути,
+// на который выпущена, и даёт лишь право чтения.
+func authenticateTicket(deps AuthDeps, r *http.Request, raw string) (domain.User, AuthInfo, bool) {
+	if deps.Tickets == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return domain.User{}, AuthInfo{}, false
+	}
+	t, err := deps.Tickets.Take(r.Context(), raw)
+	if err != nil || t.Path != r.URL.Path {
+		return domain.User{}, AuthInfo{}, false
+	}
+	u := domain.User{ID: t.UserID, Login: t.Login, Role: t.Role}
+	return u, AuthInfo{Kind: AuthTicket, Scopes: []string{domain.ScopeDocsRead}}, true
+}
+
+func csrfOK(deps AuthDeps, r *http.Request, info AuthInfo) bool {
+	return !info.ViaCookie || deps.Cookies.ValidCSRF(r, info.JTI)
+}
+
+func writeCSRFError(w http.ResponseWriter) {
+	http.Error(w, `{"error":{"code":1003,"text":"csrf token missing or invalid"}}`, http.StatusForbidden)
+}
+
+// RequireAdmin — только администратор из интерактивной JWT-сессии (PAT не даёт админских прав).
+// Ставится после RequireAuth.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := UserFromCtx(r.Context())
+		info, _ := AuthInfoFromCtx(r.Context())
+		if info.Kind != AuthJWT || !u.IsAdmin() {
+			http.Error(w, `{"error":{"code":1003,"text":"forbidden"}}`, http.StatusForbidden)
 			return
 		}
-		u := domain.User{ID: claims.UserID, Login: claims.Login}
-		ctx := context.WithValue(r.Context(), userKey, u)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
+func withAuth(ctx context.Context, u domain.User, info AuthInfo) context.Context {
+	ctx = context.WithValue(ctx, userKey, u)
+	return context.WithValue(ctx, infoKey, info)
+}
+
 func UserFromCtx(ctx context.Context) (domain.User, bool) {
 	u, ok := ctx.Value(userKey).(domain.User)
 	return u, ok
 }
 
-// Берём токен из query (?token=...), а если его нет — из Authorization: Bearer ...
-func extractTokenAny(r *http.Request) string {
-	if t := r.URL.Query().Get("token"); t != "" { // не трогаем тело (без ParseForm), безопасно для multipart
-		return t
+func AuthInfoFromCtx(ctx context.Context) (AuthInfo, bool) {
+	info, ok := ctx.Value(infoKey).(AuthInfo)
+	return info, ok
+}
+
+// HasScope: JWT-сессия может всё, PAT — только то, что в его скоупах.
+func HasScope(ctx context.Context, scope string) bool {
+	info, ok := AuthInfoFromCtx(ctx)
+	if !ok {
+		return false
 	}
+	if info.Kind == AuthJWT {
+		return true
+	}
+	return slices.Contains(info.Scopes, scope)
+}
+
+// bearerToken — Authorization: Bearer ...
+func bearerToken(r *http.Request) string {
 	h := r.Header.Get("Authorization")
 	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
 		return strings.TrimSpace(h[7:])
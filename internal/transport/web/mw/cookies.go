@@ -0,0 +1,120 @@
+package mw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookie-сессия для браузеров: токены в HttpOnly cookie (недоступны JS),
+// CSRF — подписанный double-submit: mydocs_csrf = HMAC(key, jti) читается JS
+// и возвращается в заголовке X-CSRF-Token на изменяющих запросах.
+const (
+	CookieAccess  = "mydocs_access"
+	CookieRefresh = "mydocs_refresh"
+	CookieCSRF    = "mydocs_csrf"
+
+	HeaderCSRF = "X-CSRF-Token"
+	// X-Session-Mode: cookie — выдать сессию в cookie вместо тела ответа
+	HeaderSessionMode = "X-Session-Mode"
+)
+
+// refresh-cookie нужна только ручкам /api/auth/* (refresh, logout)
+const refreshCookiePath = "/api/auth"
+
+type Cookies struct {
+	Enabled    bool
+	Secure     bool
+	Domain     string
+	SameSite   http.SameSite
+	CSRFKey    []byte
+	RefreshTTL time.Duration
+}
+
+// ParseSameSite: lax (по умолчанию) | strict | none
+func ParseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+func (c *Cookies) On() bool { return c != nil && c.Enabled }
+
+// Wanted — клиент просит cookie-сессию.
+func (c *Cookies) Wanted(r *http.Request) bool {
+	return c.On() && strings.EqualFold(r.Header.Get(HeaderSessionMode), "cookie")
+}
+
+// CSRFToken привязан к access-токену: подменить его, подбросив cookie с поддомена, нельзя.
+func (c *Cookies) CSRFToken(jti string) string {
+	m := hmac.New(sha256.New, c.CSRFKey)
+	m.Write([]byte(jti))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// ValidCSRF: безопасные методы не проверяются, остальные требуют X-CSRF-Token.
+func (c *Cookies) ValidCSRF(r *http.Request, jti string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	got := r.Header.Get(HeaderCSRF)
+	return got != "" && hmac.Equal([]byte(got), []byte(c.CSRFToken(jti)))
+}
+
+// SetSession кладёт пару токенов в cookie и возвращает CSRF-токен.
+func (c *Cookies) SetSession(w http.ResponseWriter, access string, accessExp time.Time, refresh, jti string) string {
+	csrf := c.CSRFToken(jti)
+	http.SetCookie(w, c.cookie(CookieAccess, access, "/", time.Until(accessExp), true))
+	if refresh != "" {
+		http.SetCookie(w, c.cookie(CookieRefresh, refresh, refreshCookiePath, c.RefreshTTL, true))
+	}
+	http.SetCookie(w, c.cookie(CookieCSRF, csrf, "/", c.RefreshTTL, false))
+	return csrf
+}
+
+// Clear удаляет cookie сессии (logout).
+func (c *Cookies) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, c.cookie(CookieAccess, "", "/", -1, true))
+	http.SetCookie(w, c.cookie(CookieRefresh, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, c.cookie(CookieCSRF, "", "/", -1, false))
+}
+
+func (c *Cookies) Access(r *http.Request) string  { return c.value(r, CookieAccess) }
+func (c *Cookies) Refresh(r *http.Request) string { return c.value(r, CookieRefresh) }
+
+func (c *Cookies) value(r *http.Request, name string) string {
+	if !c.On() {
+		return ""
+	}
+	ck, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return ck.Value
+}
+
+func (c *Cookies) cookie(name, val, path string, ttl time.Duration, httpOnly bool) *http.Cookie {
+	ck := &http.Cookie{
+		Name:     name,
+		Value:    val,
+		Path:     path,
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+		MaxAge:   int(ttl.Seconds()),
+	}
+	if ttl < 0 {
+		ck.MaxAge = -1
+	}
+	return ck
+}
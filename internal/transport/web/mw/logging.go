@@ -3,6 +3,7 @@ package mw
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -19,7 +20,15 @@ func Logging(l *log.Logger) func(http.Handler) http.Handler {
 
 			dur := time.Since(start)
 			l.Printf("lvl=info req_id=%s method=%s path=%q status=%d size=%d duration_ms=%d",
-				reqID, r.Method, r.URL.Path, mw.status, mw.size, dur.Milliseconds())
+				reqID, r.Method, logPath(r.URL.Path), mw.status, mw.size, dur.Milliseconds())
 		})
 	}
 }
+
+// logPath скрывает токен анонимной ссылки (/s/{token}) — по логам ссылку не восстановить.
+func logPath(p string) string {
+	if strings.HasPrefix(p, "/s/") {
+		return "/s/…"
+	}
+	return p
+}
@@ -0,0 +1,39 @@
+package mw
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP — если приложение стоит за доверенным прокси, подменяет r.RemoteAddr
+// адресом клиента из X-Forwarded-For (первый адрес) или X-Real-IP.
+// Без доверия заголовки игнорируются: их может подделать кто угодно.
+func RealIP(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !trustProxy {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ""
+			if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+				ip = strings.TrimSpace(strings.Split(xff, ",")[0])
+			} else if xr := r.Header.Get("X-Real-IP"); xr != "" {
+				ip = strings.TrimSpace(xr)
+			}
+			if net.ParseIP(ip) != nil {
+				r.RemoteAddr = net.JoinHostPort(ip, "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP — IP клиента без порта (с учётом RealIP).
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -4,19 +4,28 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	_ "github.com/EgorLis/my-docs/internal/docs"
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/EgorLis/my-docs/internal/transport/web/mw"
 	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/admin"
 	"github.com/EgorLis/my-docs/internal/transport/web/v1/auth"
 	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/folder"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/group"
 	"github.com/EgorLis/my-docs/internal/transport/web/v1/health"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/invite"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/link"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/pat"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/schema"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 func newRouter(s *Server) http.Handler {
 	healthLog := log.New(s.logger.Writer(), s.logger.Prefix()+"[health] ", s.logger.Flags())
+	adminLog := log.New(s.logger.Writer(), s.logger.Prefix()+"[admin] ", s.logger.Flags())
 	authLog := log.New(s.logger.Writer(), s.logger.Prefix()+"[auth] ", s.logger.Flags())
 	docsLog := log.New(s.logger.Writer(), s.logger.Prefix()+"[docs] ", s.logger.Flags())
 
@@ -32,30 +41,198 @@ func newRouter(s *Server) http.Handler {
 		Users:      s.repos.Users,
 		Hasher:     s.auth.Hasher,
 		AdminToken: s.cfg.AdminToken,
+		Throttle:   s.auth.Throttle,
+		Invites:    s.repos.Invites,
+	}
+
+	resetH := &auth.HandlerPasswordReset{
+		Log:           authLog,
+		AdminToken:    s.cfg.AdminToken,
+		Throttle:      s.auth.Throttle,
+		Users:         s.repos.Users,
+		Hasher:        s.auth.Hasher,
+		Epochs:        s.auth.Epochs,
+		RefreshTokens: s.repos.RefreshTokens,
+		Sessions:      s.repos.Sessions,
+	}
+
+	unlockH := &auth.HandlerUnlock{
+		Log:        authLog,
+		AdminToken: s.cfg.AdminToken,
+		Throttle:   s.auth.Throttle,
+	}
+
+	refreshTTL := s.cfg.AuthRefreshTTL
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+
+	sessions := &auth.SessionIssuer{
+		Tokens:        s.auth.Tokens,
+		RefreshTokens: s.repos.RefreshTokens,
+		Sessions:      s.repos.Sessions,
+		RefreshTTL:    refreshTTL,
+		Cookies:       s.auth.Cookies,
 	}
 
 	loginH := &auth.HandlerLogin{
+		Log:      authLog,
+		Users:    s.repos.Users,
+		Hasher:   s.auth.Hasher,
+		Sessions: sessions,
+		Throttle: s.auth.Throttle,
+		Attempts: s.repos.LoginAttempts,
+		Pending:  s.auth.MFAPending,
+	}
+
+	oidcH := &auth.HandlerOIDC{
+		Log:           authLog,
+		Provider:      s.auth.OIDC,
+		States:        s.auth.OIDCStates,
+		Identities:    s.repos.Identities,
+		Sessions:      sessions,
+		Attempts:      s.repos.LoginAttempts,
+		AutoProvision: s.cfg.OIDCAutoProvision,
+		StateTTL:      s.cfg.OIDCStateTTL,
+	}
+
+	mfaH := &auth.HandlerMFA{
+		Log:      authLog,
+		Users:    s.repos.Users,
+		MFA:      s.repos.MFA,
+		Box:      s.auth.Box,
+		Pending:  s.auth.MFAPending,
+		Sessions: sessions,
+		Throttle: s.auth.Throttle,
+		Attempts: s.repos.LoginAttempts,
+	}
+
+	passwordH := &auth.HandlerPassword{
+		Log:           authLog,
+		Users:         s.repos.Users,
+		Hasher:        s.auth.Hasher,
+		Epochs:        s.auth.Epochs,
+		RefreshTokens: s.repos.RefreshTokens,
+		SessionsRepo:  s.repos.Sessions,
+		Sessions:      sessions,
+	}
+
+	totpH := &auth.HandlerTOTP{
 		Log:    authLog,
-		Users:  s.repos.Users,
-		Hasher: s.auth.Hasher,
-		Tokens: s.auth.Tokens,
+		MFA:    s.repos.MFA,
+		Box:    s.auth.Box,
+		Issuer: s.cfg.AuthIssuer,
+	}
+
+	refreshH := &auth.HandlerRefresh{
+		Log:           authLog,
+		Users:         s.repos.Users,
+		Tokens:        s.auth.Tokens,
+		RefreshTokens: s.repos.RefreshTokens,
+		Sessions:      s.repos.Sessions,
+		Blacklist:     s.auth.Blacklist,
+		RefreshTTL:    refreshTTL,
+		Cookies:       s.auth.Cookies,
 	}
 
 	logoutH := &auth.HandlerLogout{
-		Log:       authLog,
-		Tokens:    s.auth.Tokens,
-		Blacklist: s.auth.Blacklist,
+		Log:           authLog,
+		Tokens:        s.auth.Tokens,
+		Blacklist:     s.auth.Blacklist,
+		RefreshTokens: s.repos.RefreshTokens,
+		Sessions:      s.repos.Sessions,
+		Cookies:       s.auth.Cookies,
+		QueryTokens:   s.auth.QueryTokens,
+	}
+
+	downloadH := &auth.HandlerDownloadLink{
+		Log:     authLog,
+		Tickets: s.auth.Tickets,
+	}
+
+	sessionsH := &auth.HandlerSessions{
+		Log:           authLog,
+		Sessions:      s.repos.Sessions,
+		RefreshTokens: s.repos.RefreshTokens,
+		Blacklist:     s.auth.Blacklist,
+		Epochs:        s.auth.Epochs,
+	}
+
+	jwksH := &auth.HandlerJWKS{
+		Log:  authLog,
+		Keys: s.auth.Keys,
+	}
+
+	adminH := &admin.Handler{
+		Log:           adminLog,
+		Users:         s.repos.Users,
+		Admin:         s.repos.UserAdmin,
+		Epochs:        s.auth.Epochs,
+		RefreshTokens: s.repos.RefreshTokens,
+		Sessions:      s.repos.Sessions,
+		Transfers:     s.repos.Transfers,
+		Revisions:     s.repos.Revisions,
+		Storage:       s.store,
+		Cache:         s.cache,
+	}
+
+	inviteH := &invite.Handler{
+		Log:        authLog,
+		Invites:    s.repos.Invites,
+		AllowUsers: s.cfg.InvitesAllowUsers,
+	}
+
+	patH := &pat.Handler{
+		Log:    authLog,
+		Tokens: s.repos.PersonalTokens,
 	}
 
 	dh := &doc.Handler{
+		Log:       docsLog,
+		Users:     s.repos.Users,
+		Docs:      s.repos.Docs,
+		Shares:    s.repos.Shares,
+		Transfers: s.repos.Transfers,
+		Revisions: s.repos.Revisions,
+		Trash:     s.repos.Trash,
+		Folders:   s.repos.Folders,
+		Schemas:   s.repos.Schemas,
+		Validator: s.repos.SchemaValidator,
+		Storage:   s.store,
+		Cache:     s.cache,
+		ListTTL:   60, // сек
+		DocTTL:    60,
+
+		PublicMaxAge:  300,
+		KeepRevisions: s.cfg.DocVersionsKeep,
+	}
+
+	linkH := &link.Handler{
+		Log:      docsLog,
+		Links:    s.repos.ShareLinks,
+		Docs:     s.repos.Docs,
+		Storage:  s.store,
+		Hasher:   s.auth.Hasher,
+		Throttle: s.auth.Throttle,
+		Cache:    s.cache,
+	}
+
+	groupH := &group.Handler{
+		Log:    docsLog,
+		Groups: s.repos.Groups,
+		Cache:  s.cache,
+	}
+
+	folderH := &folder.Handler{
 		Log:     docsLog,
-		Users:   s.repos.Users,
-		Docs:    s.repos.Docs,
-		Shares:  s.repos.Shares,
-		Storage: s.store,
+		Folders: s.repos.Folders,
 		Cache:   s.cache,
-		ListTTL: 60, // сек
-		DocTTL:  60,
+	}
+
+	schemaH := &schema.Handler{
+		Log:       docsLog,
+		Schemas:   s.repos.Schemas,
+		Validator: s.repos.SchemaValidator,
 	}
 
 	mux := http.NewServeMux()
@@ -67,11 +244,24 @@ func newRouter(s *Server) http.Handler {
 	// auth
 	mux.HandleFunc("POST /api/register", reg.Register)
 	mux.HandleFunc("POST /api/auth", loginH.Login)
+	mux.HandleFunc("POST /api/auth/refresh", refreshH.Refresh)
+	mux.HandleFunc("POST /api/auth/mfa", mfaH.Verify)
+	mux.HandleFunc("GET /api/auth/oidc/login", oidcH.Login)
+	mux.HandleFunc("GET /api/auth/oidc/callback", oidcH.Callback)
 	mux.HandleFunc("DELETE /api/auth/", logoutH.Logout) // DELETE /api/auth/{token}
+	mux.HandleFunc("GET /.well-known/jwks.json", jwksH.JWKS)
+	mux.HandleFunc("POST /api/admin/unlock", unlockH.Unlock)
+	mux.HandleFunc("POST /api/admin/password-reset", resetH.Reset)
 
 	// защищаем Bearer-ом приватные ручки:
 	// Upload, List, GetOne, Delete
-	protected := mw.RequireAuth(mw.AuthDeps{Tokens: s.auth.Tokens, Blacklist: s.auth.Blacklist}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	authDeps := mw.AuthDeps{Tokens: s.auth.Tokens, Blacklist: s.auth.Blacklist, PATs: s.repos.PersonalTokens, Epochs: s.auth.Epochs,
+		Disabled: s.auth.Disabled, Cookies: s.auth.Cookies, QueryTokens: s.auth.QueryTokens, Tickets: s.auth.Tickets}
+	requireAuth := func(h http.HandlerFunc) http.Handler { return mw.RequireAuth(authDeps, h) }
+	requireAdmin := func(h http.HandlerFunc) http.Handler { return mw.RequireAuth(authDeps, mw.RequireAdmin(h)) }
+	optionalAuth := func(h http.HandlerFunc) http.Handler { return mw.OptionalAuth(authDeps, h) }
+
+	protected := mw.RequireAuth(authDeps, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case r.Method == http.MethodPost && r.URL.Path == "/api/docs":
 			limitBody(1<<30, dh.Upload)(w, r) // Ограничение на 1ГБ
@@ -88,11 +278,113 @@ func newRouter(s *Server) http.Handler {
 	mux.Handle("/api/docs", protected)
 	mux.Handle("/api/docs/", protected)
 
+	// чтение: анонимам — публичные документы (GET покрывает и HEAD)
+	mux.Handle("GET /api/docs", optionalAuth(dh.List))
+	mux.Handle("GET /api/docs/{id}", optionalAuth(dh.GetOne))
+	mux.Handle("GET /api/docs/{id}/meta", optionalAuth(dh.Meta))
+
+	// правка метаданных и JSON (JSON Merge Patch, If-Match обязателен)
+	mux.Handle("PATCH /api/docs/{id}", requireAuth(limitBody(64<<20, dh.Patch)))
+
+	// ревизии контента: замена файла (If-Match обязателен), история, скачивание, откат
+	mux.Handle("PUT /api/docs/{id}/content", requireAuth(limitBody(1<<30, dh.PutContent)))
+	mux.Handle("GET /api/docs/{id}/versions", requireAuth(dh.ListVersions))
+	mux.Handle("GET /api/docs/{id}/versions/{rev}", requireAuth(dh.GetVersion))
+	mux.Handle("POST /api/docs/{id}/versions/{rev}/restore", requireAuth(dh.RestoreVersion))
+
+	// корзина: DELETE /api/docs/{id} переносит сюда, окончательно — вручную или по TRASH_RETENTION
+	mux.Handle("GET /api/trash", requireAuth(dh.ListTrash))
+	mux.Handle("POST /api/trash/{id}/restore", requireAuth(dh.RestoreTrash))
+	mux.Handle("DELETE /api/trash/{id}", requireAuth(dh.PurgeTrash))
+
+	// управление доступом к документу (владелец и co_owner)
+	mux.Handle("GET /api/docs/{id}/shares", requireAuth(dh.ListShares))
+	mux.Handle("POST /api/docs/{id}/shares", requireAuth(dh.AddShares))
+	mux.Handle("DELETE /api/docs/{id}/shares/{login}", requireAuth(dh.RevokeShare))
+	mux.Handle("DELETE /api/docs/{id}/shares/groups/{group}", requireAuth(dh.RevokeGroupShare))
+
+	// папки: дерево владельца; грант на папку действует на всё её содержимое
+	mux.Handle("POST /api/folders", requireAuth(folderH.Create))
+	mux.Handle("GET /api/folders", requireAuth(folderH.List))
+	mux.Handle("GET /api/folders/lookup", requireAuth(folderH.Lookup))
+	mux.Handle("GET /api/folders/{id}", requireAuth(folderH.Get))
+	mux.Handle("PATCH /api/folders/{id}", requireAuth(folderH.Update))
+	mux.Handle("DELETE /api/folders/{id}", requireAuth(folderH.Delete))
+	mux.Handle("GET /api/folders/{id}/shares", requireAuth(folderH.ListShares))
+	mux.Handle("POST /api/folders/{id}/shares", requireAuth(folderH.AddShare))
+	mux.Handle("DELETE /api/folders/{id}/shares/{login}", requireAuth(folderH.RevokeShare))
+
+	// JSON-схемы (draft 2020-12): свои и глобальные (регистрирует администратор)
+	mux.Handle("POST /api/schemas", requireAuth(limitBody(1<<20, schemaH.Create)))
+	mux.Handle("GET /api/schemas", requireAuth(schemaH.List))
+	mux.Handle("GET /api/schemas/{id}", requireAuth(schemaH.Get))
+	mux.Handle("DELETE /api/schemas/{id}", requireAuth(schemaH.Delete))
+
+	// группы пользователей (состав меняют администраторы группы)
+	mux.Handle("POST /api/groups", requireAuth(groupH.Create))
+	mux.Handle("GET /api/groups", requireAuth(groupH.List))
+	mux.Handle("GET /api/groups/{id}", requireAuth(groupH.Get))
+	mux.Handle("DELETE /api/groups/{id}", requireAuth(groupH.Delete))
+	mux.Handle("POST /api/groups/{id}/members", requireAuth(groupH.AddMember))
+	mux.Handle("DELETE /api/groups/{id}/members/{login}", requireAuth(groupH.RemoveMember))
+
+	// передача владения: запрос владельца → согласие получателя
+	mux.Handle("POST /api/docs/{id}/transfer", requireAuth(dh.RequestTransfer))
+	mux.Handle("GET /api/transfers", requireAuth(dh.ListTransfers))
+	mux.Handle("POST /api/transfers/{id}/accept", requireAuth(dh.AcceptTransfer))
+	mux.Handle("DELETE /api/transfers/{id}", requireAuth(dh.CancelTransfer))
+
+	// анонимные ссылки: управление (владелец и co_owner) и открытие без аутентификации
+	mux.Handle("POST /api/docs/{id}/links", requireAuth(linkH.Create))
+	mux.Handle("GET /api/docs/{id}/links", requireAuth(linkH.List))
+	mux.Handle("DELETE /api/docs/{id}/links/{linkID}", requireAuth(linkH.Revoke))
+	mux.HandleFunc("GET /s/{token}", linkH.Open) // GET покрывает и HEAD
+
+	// персональные токены (только из JWT-сессии)
+	mux.Handle("POST /api/tokens", requireAuth(patH.Create))
+	mux.Handle("GET /api/tokens", requireAuth(patH.List))
+	mux.Handle("DELETE /api/tokens/{id}", requireAuth(patH.Revoke))
+
+	// приглашения (только из JWT-сессии; обычным пользователям — при INVITES_ALLOW_USERS)
+	mux.Handle("POST /api/invites", requireAuth(inviteH.Create))
+	mux.Handle("GET /api/invites", requireAuth(inviteH.List))
+	mux.Handle("DELETE /api/invites/{id}", requireAuth(inviteH.Revoke))
+
+	// сессии пользователя (только из JWT-сессии)
+	mux.Handle("GET /api/auth/sessions", requireAuth(sessionsH.List))
+	mux.Handle("DELETE /api/auth/sessions", requireAuth(sessionsH.RevokeAll))
+	mux.Handle("DELETE /api/auth/sessions/{id}", requireAuth(sessionsH.Revoke))
+
+	// одноразовые ссылки на скачивание (вместо токена в URL)
+	mux.Handle("POST /api/auth/download-link", requireAuth(downloadH.Create))
+
+	// привязка внешней учётки OIDC (только из JWT-сессии)
+	mux.Handle("POST /api/auth/oidc/link", requireAuth(oidcH.Link))
+
+	// смена пароля (только из JWT-сессии)
+	mux.Handle("POST /api/auth/password", requireAuth(passwordH.Change))
+
+	// TOTP 2FA (только из JWT-сессии)
+	mux.Handle("POST /api/auth/mfa/totp", requireAuth(totpH.Enroll))
+	mux.Handle("POST /api/auth/mfa/totp/confirm", requireAuth(totpH.Confirm))
+	mux.Handle("DELETE /api/auth/mfa/totp", requireAuth(totpH.Disable))
+
+	// управление пользователями (роль admin)
+	mux.Handle("GET /api/admin/users", requireAdmin(adminH.List))
+	mux.Handle("GET /api/admin/users/{id}", requireAdmin(adminH.Get))
+	mux.Handle("GET /api/admin/users/{id}/usage", requireAdmin(adminH.Usage))
+	mux.Handle("POST /api/admin/users/{id}/disable", requireAdmin(adminH.Disable))
+	mux.Handle("POST /api/admin/users/{id}/enable", requireAdmin(adminH.Enable))
+	mux.Handle("PUT /api/admin/users/{id}/role", requireAdmin(adminH.SetRole))
+	mux.Handle("POST /api/admin/users/{id}/logout", requireAdmin(adminH.Logout))
+	mux.Handle("POST /api/admin/users/{id}/transfer", requireAdmin(adminH.TransferDocs))
+	mux.Handle("DELETE /api/admin/users/{id}", requireAdmin(adminH.Delete))
+
 	// swagger
 	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
 
 	// 🔗 middleware
-	return mw.WithRequestID(mw.Logging(s.logger)(mux))
+	return mw.RealIP(s.cfg.HTTPTrustProxy)(mw.WithRequestID(mw.Logging(s.logger)(mux)))
 }
 
 func limitBody(n int64, h http.HandlerFunc) http.HandlerFunc {
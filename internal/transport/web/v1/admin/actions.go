@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/auth"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+)
+
+// notSelf: отключить, удалить или понизить самого себя нельзя —
+// иначе легко остаться без администраторов.
+func notSelf(r *http.Request, target domain.User) error {
+	me, _ := mw.UserFromCtx(r.Context())
+	if me.ID == target.ID {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// Disable godoc
+// @Summary     Disable user
+// @Description Запрещает вход и завершает все сессии; PAT пользователя перестают приниматься (только admin).
+// @Tags        admin
+// @Produce     json
+// @Param       id path string true "user id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/disable [post]
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, "admin.users.disable", true)
+}
+
+// Enable godoc
+// @Summary     Re-enable user
+// @Tags        admin
+// @Produce     json
+// @Param       id path string true "user id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/enable [post]
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, "admin.users.enable", false)
+}
+
+func (h *Handler) setDisabled(w http.ResponseWriter, r *http.Request, op string, disabled bool) {
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if err := notSelf(r, u); err != nil {
+		logx.Error(h.Log, reqID, op, "self action", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	if err := h.Admin.SetUserDisabled(r.Context(), u.ID, disabled); err != nil {
+		logx.Error(h.Log, reqID, op, "db update failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if disabled {
+		// уже выданные JWT отсекаются эпохой в mw.RequireAuth
+		if _, err := auth.RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.Sessions, u.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "revoke sessions failed", err, "user_id", u.ID)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "disabled", disabled)
+	v1.WriteOKResponse(w, r, map[string]bool{"disabled": disabled})
+}
+
+type roleRequest struct {
+	Role string `json:"role"` // user | admin
+}
+
+// SetRole godoc
+// @Summary     Change user role
+// @Description Меняет роль; токены пользователя со старой ролью перестают приниматься (только admin).
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id path string true "user id"
+// @Param       request body roleRequest true "role"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/role [put]
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.role"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !domain.ValidRole(req.Role) {
+		logx.Error(h.Log, reqID, op, "bad role", domain.ErrBadParams, "role", req.Role)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if err := notSelf(r, u); err != nil {
+		logx.Error(h.Log, reqID, op, "self action", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if u.Role == req.Role {
+		logx.Info(h.Log, reqID, op, "ok (unchanged)", "user_id", u.ID, "role", u.Role)
+		v1.WriteOKResponse(w, r, map[string]string{"role": u.Role})
+		return
+	}
+
+	if err := h.Admin.SetUserRole(r.Context(), u.ID, req.Role); err != nil {
+		logx.Error(h.Log, reqID, op, "db update failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	// роль зашита в JWT: старые токены должны умереть
+	if _, err := auth.RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.Sessions, u.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke sessions failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "role", req.Role)
+	v1.WriteOKResponse(w, r, map[string]string{"role": req.Role})
+}
+
+// Logout godoc
+// @Summary     Force logout user
+// @Description Завершает все сессии пользователя (только admin).
+// @Tags        admin
+// @Produce     json
+// @Param       id path string true "user id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/logout [post]
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.logout"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	n, err := auth.RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.Sessions, u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "revoke sessions failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "revoked", n)
+	v1.WriteOKResponse(w, r, map[string]int64{"revoked": n})
+}
+
+// Delete godoc
+// @Summary     Delete user
+// @Description Удаляет пользователя вместе с его документами и файлами (только admin).
+// @Description С transfer_to документы сначала переходят к указанному пользователю и не удаляются.
+// @Tags        admin
+// @Produce     json
+// @Param       id          path  string true  "user id"
+// @Param       transfer_to query string false "login of the new owner of user's documents"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.delete"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if err := notSelf(r, u); err != nil {
+		logx.Error(h.Log, reqID, op, "self action", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	// документы уходящего сотрудника можно сохранить, передав другому
+	transferred := 0
+	if login := r.URL.Query().Get("transfer_to"); login != "" {
+		to, err := h.recipient(r.Context(), login, u)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "bad recipient", err, "transfer_to", login)
+			v1.WriteDomainError(w, r, err)
+			return
+		}
+		if transferred, err = h.transferAll(r.Context(), u, to, false); err != nil {
+			logx.Error(h.Log, reqID, op, "db transfer failed", err, "user_id", u.ID, "to", to.ID)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		logx.Info(h.Log, reqID, op, "docs transferred", "user_id", u.ID, "to", to.Login, "docs", transferred)
+	}
+
+	// ключи контента и ревизий — до удаления (документы удалятся каскадом)
+	keys, err := h.Revisions.OwnerBlobKeys(r.Context(), u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db blob keys failed", err, "user_id", u.ID)
+	}
+
+	docs, err := h.Admin.DeleteUser(r.Context(), u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db delete failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// живые JWT удалённого пользователя отсекаем эпохой
+	if _, err := h.Epochs.Bump(r.Context(), u.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "bump epoch failed", err, "user_id", u.ID)
+	}
+
+	// файлы и кеш — после коммита; удаляем только объекты без ссылок (ключи контентные)
+	for _, d := range docs {
+		keys = append(keys, d.StorageKey)
+		_ = h.Cache.Del(r.Context(), domain.CacheKeyDocMeta(d.ID), domain.CacheKeyDocJSON(d.ID))
+	}
+	if _, err := doc.DropBlobs(r.Context(), h.Revisions, h.Storage, keys); err != nil {
+		logx.Error(h.Log, reqID, op, "drop blobs failed", err, "user_id", u.ID)
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login, "docs", len(docs), "transferred", transferred)
+	v1.WriteOKResponse(w, r, map[string]int{"deleted_docs": len(docs), "transferred_docs": transferred})
+}
@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Handler — управление пользователями. Доступ ограничивает mw.RequireAdmin.
+type Handler struct {
+	Log           *log.Logger
+	Users         domain.UsersRepo
+	Admin         domain.UserAdminRepo
+	Epochs        domain.TokenEpochs
+	RefreshTokens domain.RefreshTokensRepo
+	Sessions      domain.SessionsRepo
+	Transfers     domain.TransfersRepo
+	Revisions     domain.RevisionsRepo
+	Storage       domain.BlobStorage
+	Cache         domain.Cache
+}
+
+// userView — пользователь в админке (с местом в хранилище для карточки)
+type userView struct {
+	domain.User
+	Usage *domain.StorageUsage `json:"usage,omitempty"`
+}
+
+// targetUser — пользователь из {id} в пути.
+func (h *Handler) targetUser(r *http.Request) (domain.User, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, domain.ErrBadParams
+	}
+	u, err := h.Users.UserByID(r.Context(), id)
+	if err != nil {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return u, nil
+}
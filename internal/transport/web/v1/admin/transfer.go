@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+)
+
+type transferDocsRequest struct {
+	To         string `json:"to"`                    // логин нового владельца
+	KeepEditor bool   `json:"keep_editor,omitempty"` // оставить прежнему владельцу editor
+}
+
+// TransferDocs godoc
+// @Summary     Transfer all documents of user
+// @Description Все документы пользователя {id} переходят к пользователю to без его согласия (только admin).
+// @Description Файлы и учёт места переходят вместе с документами; ожидающие запросы на передачу снимаются.
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id      path string              true "user id"
+// @Param       request body transferDocsRequest true "to, keep_editor"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/transfer [post]
+func (h *Handler) TransferDocs(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.transfer"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req transferDocsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	to, err := h.recipient(r.Context(), req.To, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad recipient", err, "to", req.To)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	n, err := h.transferAll(r.Context(), u, to, req.KeepEditor)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db transfer failed", err, "user_id", u.ID, "to", to.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "to", to.Login, "docs", n, "keep_editor", req.KeepEditor)
+	v1.WriteOKResponse(w, r, map[string]int{"transferred_docs": n})
+}
+
+// recipient: новый владелец — существующий, активный и не сам from.
+func (h *Handler) recipient(ctx context.Context, login string, from domain.User) (domain.User, error) {
+	login = strings.TrimSpace(login)
+	if login == "" || login == from.Login {
+		return domain.User{}, domain.ErrBadParams
+	}
+	to, err := h.Users.UserByLogin(ctx, login)
+	if err != nil {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if to.Disabled() {
+		return domain.User{}, domain.ErrConflict
+	}
+	return to, nil
+}
+
+// transferAll переносит документы и сбрасывает кеш; возвращает число документов.
+func (h *Handler) transferAll(ctx context.Context, from, to domain.User, keepEditor bool) (int, error) {
+	mv, err := h.Transfers.TransferAllDocs(ctx, from.ID, to.ID, keepEditor)
+	if err != nil {
+		return 0, err
+	}
+	if err := doc.InvalidateOwnership(ctx, h.Cache, mv); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "admin.users.transfer", "cache invalidation failed", err, "user_id", from.ID)
+	}
+	return len(mv.Docs), nil
+}
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// List godoc
+// @Summary     List users
+// @Description Поиск пользователей по подстроке логина, роли и статусу (только admin).
+// @Tags        admin
+// @Produce     json
+// @Param       q        query string false "подстрока логина"
+// @Param       role     query string false "user | admin"
+// @Param       disabled query bool   false "true — только отключённые, false — только активные"
+// @Param       limit    query int    false "по умолчанию 50, максимум 200"
+// @Param       offset   query int    false "смещение"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.User}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/admin/users [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	q := r.URL.Query()
+	f := domain.UserFilter{Query: q.Get("q"), Role: q.Get("role")}
+	if f.Role != "" && !domain.ValidRole(f.Role) {
+		logx.Error(h.Log, reqID, op, "bad role", domain.ErrBadParams, "role", f.Role)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if s := q.Get("disabled"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "bad disabled", err, "disabled", s)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+		f.Disabled = &b
+	}
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	f.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	list, err := h.Admin.ListUsers(r.Context(), f)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "count", len(list))
+	v1.WriteOKData(w, r, list)
+}
+
+// Get godoc
+// @Summary     Get user
+// @Description Карточка пользователя с занятым местом (только admin).
+// @Tags        admin
+// @Produce     json
+// @Param       id path string true "user id"
+// @Success     200 {object} domain.APIEnvelope{data=userView}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.get"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	usage, err := h.Admin.UserStorageUsage(r.Context(), u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "usage failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID)
+	v1.WriteOKData(w, r, userView{User: u, Usage: &usage})
+}
+
+// Usage godoc
+// @Summary     User storage usage
+// @Description Количество документов и суммарный размер (только admin).
+// @Tags        admin
+// @Produce     json
+// @Param       id path string true "user id"
+// @Success     200 {object} domain.APIEnvelope{data=domain.StorageUsage}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/admin/users/{id}/usage [get]
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	const op = "admin.users.usage"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	u, err := h.targetUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target user", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	usage, err := h.Admin.UserStorageUsage(r.Context(), u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "usage failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "docs", usage.Docs, "bytes", usage.Bytes)
+	v1.WriteOKData(w, r, usage)
+}
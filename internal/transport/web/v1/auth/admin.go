@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/auth/password"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// checkAdminToken сверяет admin-токен; попытки считаются по IP клиента,
+// чтобы токен нельзя было перебирать. false — ответ уже записан.
+func checkAdminToken(w http.ResponseWriter, r *http.Request, lg *log.Logger, reqID, op string,
+	th domain.LoginThrottle, want, got string) bool {
+	ip := mw.ClientIP(r)
+
+	st, err := th.Attempt(r.Context(), "", ip)
+	if err != nil {
+		logx.Error(lg, reqID, op, "throttle attempt failed", err, "ip", ip)
+	}
+	if st.Blocked() {
+		logx.Error(lg, reqID, op, "throttled", st.Err(), "ip", ip, "retry_after", st.RetryAfter)
+		v1.WriteThrottled(w, r, st.Err(), st.RetryAfter)
+		return false
+	}
+
+	if want == "" || got == "" || subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+		logx.Error(lg, reqID, op, "bad admin token", domain.ErrUnauth, "ip", ip)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return false
+	}
+	succeed(r, lg, reqID, op, th, "", ip)
+	return true
+}
+
+// HandlerUnlock — снятие backoff/блокировки логина администратором.
+type HandlerUnlock struct {
+	Log        *log.Logger
+	AdminToken string
+	Throttle   domain.LoginThrottle
+}
+
+type unlockRequest struct {
+	Token string `json:"token"` // админ-токен (из конфига)
+	Login string `json:"login"`
+}
+
+// Unlock godoc
+// @Summary     Unlock account
+// @Description Снимает backoff и временную блокировку входа для логина (только admin-token).
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body unlockRequest true "token, login"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     429 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/admin/unlock [post]
+func (h *HandlerUnlock) Unlock(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.unlock"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	var req unlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	if !checkAdminToken(w, r, h.Log, reqID, op, h.Throttle, h.AdminToken, req.Token) {
+		return
+	}
+
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" {
+		logx.Error(h.Log, reqID, op, "empty login", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	if err := h.Throttle.Reset(r.Context(), req.Login); err != nil {
+		logx.Error(h.Log, reqID, op, "throttle reset failed", err, "login", req.Login)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "login", req.Login)
+	v1.WriteOKResponse(w, r, map[string]bool{req.Login: true})
+}
+
+// HandlerPasswordReset — сброс пароля администратором: все сессии пользователя
+// завершаются, блокировка входа снимается.
+type HandlerPasswordReset struct {
+	Log           *log.Logger
+	AdminToken    string
+	Throttle      domain.LoginThrottle
+	Users         domain.UsersRepo
+	Hasher        domain.PasswordHasher
+	Epochs        domain.TokenEpochs
+	RefreshTokens domain.RefreshTokensRepo
+	Sessions      domain.SessionsRepo
+}
+
+type passwordResetRequest struct {
+	Token   string `json:"token"` // админ-токен (из конфига)
+	Login   string `json:"login"`
+	NewPswd string `json:"new_pswd,omitempty"` // пусто — сгенерировать временный
+}
+
+type passwordResetResponse struct {
+	Login   string `json:"login"`
+	NewPswd string `json:"new_pswd,omitempty"` // только если сгенерирован сервером
+}
+
+// Reset godoc
+// @Summary     Reset user password
+// @Description Устанавливает новый пароль (или генерирует временный) и завершает все сессии пользователя (только admin-token).
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body passwordResetRequest true "token, login, new_pswd"
+// @Success     200 {object} domain.APIEnvelope{response=passwordResetResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     429 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/admin/password-reset [post]
+func (h *HandlerPasswordReset) Reset(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.password.reset"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	if !checkAdminToken(w, r, h.Log, reqID, op, h.Throttle, h.AdminToken, req.Token) {
+		return
+	}
+
+	var resp passwordResetResponse
+	if req.NewPswd == "" {
+		gen, err := password.Generate(16)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "generate password failed", err)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		req.NewPswd, resp.NewPswd = gen, gen
+	}
+	if !domain.ValidPassword(req.NewPswd) {
+		logx.Error(h.Log, reqID, op, "password validation failed", domain.ErrBadParams, "login", req.Login)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	u, err := h.Users.UserByLogin(r.Context(), strings.TrimSpace(req.Login))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "user not found", err, "login", req.Login)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+
+	hashStr, err := h.Hasher.Hash(req.NewPswd)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "hash failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.Users.SetPasswordHash(r.Context(), u.ID, []byte(hashStr)); err != nil {
+		logx.Error(h.Log, reqID, op, "store password failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	n, err := RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.Sessions, u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "revoke sessions failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.Throttle.Reset(r.Context(), u.Login); err != nil {
+		logx.Error(h.Log, reqID, op, "throttle reset failed", err, "login", u.Login)
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login, "revoked_sessions", n)
+	resp.Login = u.Login
+	v1.WriteOKResponse(w, r, resp)
+}
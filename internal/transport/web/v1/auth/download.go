@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// Одноразовые ссылки выдаются только на чтение документов
+const downloadPathPrefix = "/api/docs/"
+
+// HandlerDownloadLink выпускает одноразовые ссылки на скачивание: вместо
+// долгоживущего токена в URL (<img src>, <a href>) — билет на один GET.
+type HandlerDownloadLink struct {
+	Log     *log.Logger
+	Tickets domain.DownloadTickets
+}
+
+type downloadLinkRequest struct {
+	Path string `json:"path"` // напр. /api/docs/<id>
+}
+
+type downloadLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Create godoc
+// @Summary     Create one-time download link
+// @Description Возвращает URL с одноразовым ?token=mdt_... для GET указанного документа.
+// @Description Ссылка живёт AUTH_DOWNLOAD_LINK_TTL, работает один раз и даёт только чтение.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body downloadLinkRequest true "path"
+// @Success     200 {object} domain.APIEnvelope{response=downloadLinkResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/download-link [post]
+func (h *HandlerDownloadLink) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.download_link"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "no user in ctx", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	info, _ := mw.AuthInfoFromCtx(r.Context())
+	// билет не должен порождать билеты, а PAT без docs:read — давать чтение
+	if info.Kind == mw.AuthTicket || !mw.HasScope(r.Context(), domain.ScopeDocsRead) {
+		logx.Error(h.Log, reqID, op, "not allowed", domain.ErrForbidden, "user_id", me.ID, "kind", info.Kind)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	var req downloadLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	// только чистый путь документа, без query и обходов
+	if !strings.HasPrefix(req.Path, downloadPathPrefix) || len(req.Path) == len(downloadPathPrefix) ||
+		path.Clean(req.Path) != req.Path || strings.ContainsAny(req.Path, "?#") {
+		logx.Error(h.Log, reqID, op, "bad path", domain.ErrBadParams, "path", req.Path)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	raw, exp, err := h.Tickets.Create(r.Context(), domain.DownloadTicket{
+		UserID: me.ID, Login: me.Login, Role: me.Role, Path: req.Path,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "create ticket failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "path", req.Path)
+	v1.WriteOKResponse(w, r, downloadLinkResponse{
+		URL:       req.Path + "?token=" + url.QueryEscape(raw),
+		ExpiresAt: exp,
+	})
+}
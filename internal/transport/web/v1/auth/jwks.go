@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+)
+
+// HandlerJWKS публикует публичные ключи подписи JWT, чтобы другие сервисы
+// могли проверять токены my-docs без общего секрета.
+type HandlerJWKS struct {
+	Log  *log.Logger
+	Keys domain.KeySetProvider
+}
+
+// JWKS godoc
+// @Summary     JSON Web Key Set
+// @Description Публичные ключи (RS256/EdDSA) для проверки JWT. Формат RFC 7517, без конверта.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} domain.JWKSet
+// @Router      /.well-known/jwks.json [get]
+func (h *HandlerJWKS) JWKS(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.jwks"
+	reqID := mw.RequestIDFromCtx(r.Context())
+
+	set := h.Keys.JWKS()
+	w.Header().Set("Content-Type", "application/json")
+	// ключи меняются только при ротации — даём проверяющим сервисам кэшировать
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_ = json.NewEncoder(w).Encode(set)
+	}
+	logx.Info(h.Log, reqID, op, "ok", "keys", len(set.Keys))
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/EgorLis/my-docs/internal/transport/web/logx"
@@ -13,10 +14,13 @@ import (
 )
 
 type HandlerLogin struct {
-	Log    *log.Logger
-	Users  domain.UsersRepo
-	Hasher domain.PasswordHasher
-	Tokens domain.TokenManager
+	Log      *log.Logger
+	Users    domain.UsersRepo
+	Hasher   domain.PasswordHasher
+	Sessions *SessionIssuer
+	Throttle domain.LoginThrottle
+	Attempts domain.LoginAttemptsRepo
+	Pending  domain.MFAPendingStore
 }
 
 type loginRequest struct {
@@ -24,13 +28,28 @@ type loginRequest struct {
 	Pswd  string `json:"pswd"`
 }
 
+// Ответ первого шага, если у пользователя включена 2FA
+type mfaRequiredResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"` // обменять на токены через POST /api/auth/mfa
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// В cookie-режиме (X-Session-Mode: cookie) токены уходят в HttpOnly cookie,
+// а в теле остаются срок и CSRF-токен.
 type loginResponse struct {
-	Token string `json:"token"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"` // срок жизни access-токена
+	CSRFToken    string    `json:"csrf_token,omitempty"`
+	jti          string
 }
 
 // Login godoc
 // @Summary     Authenticate user
-// @Description Возвращает JWT при валидных логине и пароле.
+// @Description Возвращает JWT (access) и refresh-токен при валидных логине и пароле.
+// @Description Если включена 2FA — вместо них mfa_token для POST /api/auth/mfa.
+// @Description С заголовком X-Session-Mode: cookie токены ставятся в HttpOnly cookie, в теле — csrf_token.
 // @Tags        auth
 // @Accept      json
 // @Produce     json
@@ -38,6 +57,8 @@ type loginResponse struct {
 // @Success     200 {object} domain.APIEnvelope{response=loginResponse}
 // @Failure     400 {object} domain.APIEnvelope
 // @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope "аккаунт отключён (code 1010)"
+// @Failure     429 {object} domain.APIEnvelope "backoff (code 1429) или блокировка аккаунта (code 1423); см. Retry-After"
 // @Failure     500 {object} domain.APIEnvelope
 // @Router      /api/auth [post]
 func (h *HandlerLogin) Login(w http.ResponseWriter, r *http.Request) {
@@ -72,10 +93,30 @@ func (h *HandlerLogin) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := mw.ClientIP(r)
+
+	// троттлинг: попытка учитывается до проверки пароля, при недоступном Redis
+	// не блокируем вход, только логируем
+	st, err := h.Throttle.Attempt(r.Context(), req.Login, ip)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "throttle attempt failed", err, "login", req.Login, "ip", ip)
+	}
+	if st.Blocked() {
+		reason := domain.AttemptThrottled
+		if st.Locked {
+			reason = domain.AttemptLocked
+		}
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, req.Login, nil, reason)
+		logx.Error(h.Log, reqID, op, "throttled", st.Err(), "login", req.Login, "ip", ip, "retry_after", st.RetryAfter)
+		v1.WriteThrottled(w, r, st.Err(), st.RetryAfter)
+		return
+	}
+
 	// достаём пользователя
 	u, err := h.Users.UserByLogin(r.Context(), req.Login)
 	if err != nil {
 		logx.Error(h.Log, reqID, op, "user not found", err, "login", req.Login)
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, req.Login, nil, domain.AttemptUnknownLogin)
 		v1.WriteDomainError(w, r, domain.ErrUnauth)
 		return
 	}
@@ -84,18 +125,82 @@ func (h *HandlerLogin) Login(w http.ResponseWriter, r *http.Request) {
 	ok, err := h.Hasher.Verify(req.Pswd, string(u.PassHash))
 	if err != nil || !ok {
 		logx.Error(h.Log, reqID, op, "password verify failed", err, "login", req.Login)
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, req.Login, &u.ID, domain.AttemptBadPassword)
 		v1.WriteDomainError(w, r, domain.ErrUnauth)
 		return
 	}
 
-	// выдаём токен
-	token, _, err := h.Tokens.Issue(r.Context(), u.ID, u.Login)
+	// пароль верен: IP попытку возвращаем, логин — только после второго фактора,
+	// чтобы подбор кода TOTP копился в том же счётчике
+	succeed(r, h.Log, reqID, op, h.Throttle, "", ip)
+
+	// отключённому аккаунту сообщаем об этом только после верного пароля
+	if u.Disabled() {
+		logx.Error(h.Log, reqID, op, "account disabled", domain.ErrAccountDisabled, "user_id", u.ID)
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptDisabled)
+		v1.WriteDomainError(w, r, domain.ErrAccountDisabled)
+		return
+	}
+
+	// пароль верен — заодно апгрейдим хэш, если параметры argon2 с тех пор усилили
+	if h.Hasher.NeedsRehash(string(u.PassHash)) {
+		if hashStr, err := h.Hasher.Hash(req.Pswd); err != nil {
+			logx.Error(h.Log, reqID, op, "rehash failed", err, "user_id", u.ID)
+		} else if err := h.Users.SetPasswordHash(r.Context(), u.ID, []byte(hashStr)); err != nil {
+			logx.Error(h.Log, reqID, op, "store rehash failed", err, "user_id", u.ID)
+		} else {
+			logx.Info(h.Log, reqID, op, "password rehashed", "user_id", u.ID)
+		}
+	}
+
+	// второй фактор: токены выдаст /api/auth/mfa
+	if u.MFAEnabled {
+		mt, exp, err := h.Pending.Create(r.Context(), u.ID)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "create mfa token failed", err, "user_id", u.ID)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptMFAPending)
+		logx.Info(h.Log, reqID, op, "mfa required", "user_id", u.ID, "login", u.Login)
+		v1.WriteOKResponse(w, r, mfaRequiredResponse{MFARequired: true, MFAToken: mt, ExpiresAt: exp})
+		return
+	}
+
+	// выдаём токены и заводим сессию
+	resp, err := h.Sessions.Start(r, u)
 	if err != nil {
-		logx.Error(h.Log, reqID, op, "issue token failed", err, "user_id", u.ID, "login", u.Login)
+		logx.Error(h.Log, reqID, op, "start session failed", err, "user_id", u.ID, "login", u.Login)
 		v1.WriteDomainError(w, r, domain.ErrUnexpected)
 		return
 	}
 
+	succeed(r, h.Log, reqID, op, h.Throttle, u.Login, "")
+	recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptOK)
+
 	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login)
-	v1.WriteOKResponse(w, r, loginResponse{Token: token})
+	h.Sessions.Write(w, r, resp, h.Sessions.Cookies.Wanted(r))
+}
+
+// succeed снимает с троттлинга попытку с верными учётными данными.
+func succeed(r *http.Request, lg *log.Logger, reqID, op string, th domain.LoginThrottle, login, ip string) {
+	if err := th.Succeed(r.Context(), login, ip); err != nil {
+		logx.Error(lg, reqID, op, "throttle succeed failed", err, "login", login, "ip", ip)
+	}
+}
+
+// recordAttempt пишет попытку входа; ошибка записи не ломает ответ.
+func recordAttempt(r *http.Request, lg *log.Logger, reqID, op string, repo domain.LoginAttemptsRepo,
+	login string, userID *domain.UserID, reason string) {
+	err := repo.RecordLoginAttempt(r.Context(), domain.LoginAttempt{
+		Login:     login,
+		UserID:    userID,
+		IP:        mw.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Success:   reason == domain.AttemptOK || reason == domain.AttemptOIDC,
+		Reason:    reason,
+	})
+	if err != nil {
+		logx.Error(lg, reqID, op, "audit write failed", err, "login", login)
+	}
 }
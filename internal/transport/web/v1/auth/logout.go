@@ -12,9 +12,13 @@ import (
 )
 
 type HandlerLogout struct {
-	Log       *log.Logger
-	Tokens    domain.TokenManager
-	Blacklist domain.TokenBlacklist
+	Log           *log.Logger
+	Tokens        domain.TokenManager
+	Blacklist     domain.TokenBlacklist
+	RefreshTokens domain.RefreshTokensRepo
+	Sessions      domain.SessionsRepo
+	Cookies       *mw.Cookies
+	QueryTokens   mw.QueryTokenMode
 }
 
 type logoutResponse struct {
@@ -23,7 +27,9 @@ type logoutResponse struct {
 
 // Logout godoc
 // @Summary     Logout (revoke token)
-// @Description Завершает сессию: помечает токен как отозванный до истечения exp.
+// @Description Завершает сессию: помечает токен как отозванный до истечения exp
+// @Description и отзывает связанное с ним семейство refresh-токенов.
+// @Description DELETE /api/auth/ без токена завершает cookie-сессию (нужен X-CSRF-Token) и стирает cookie.
 // @Tags        auth
 // @Produce     json
 // @Param       token path string true "JWT token (raw)"
@@ -44,7 +50,11 @@ func (h *HandlerLogout) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// извлекаем токен: при роутинге "DELETE /api/auth/" — хвост после префикса
-	raw := getTokenFromPathOrHeader(r)
+	raw := getTokenFromPathOrHeader(r, h.QueryTokens)
+	viaCookie := false
+	if raw == "" {
+		raw, viaCookie = h.Cookies.Access(r), true
+	}
 	if raw == "" {
 		logx.Error(h.Log, reqID, op, "missing token", domain.ErrBadParams)
 		v1.WriteDomainError(w, r, domain.ErrBadParams)
@@ -57,6 +67,11 @@ func (h *HandlerLogout) Logout(w http.ResponseWriter, r *http.Request) {
 		v1.WriteDomainError(w, r, domain.ErrUnauth)
 		return
 	}
+	if viaCookie && !h.Cookies.ValidCSRF(r, claims.JTI) {
+		logx.Error(h.Log, reqID, op, "csrf check failed", domain.ErrForbidden, "jti", claims.JTI)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
 
 	// ревокация до exp
 	if err := h.Blacklist.Revoke(r.Context(), claims.JTI, claims.ExpiresAt); err != nil {
@@ -65,20 +80,40 @@ func (h *HandlerLogout) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// отзываем refresh-семейство, выданное вместе с этим access-токеном (если есть)
+	if rt, err := h.RefreshTokens.RefreshTokenByAccessJTI(r.Context(), claims.JTI); err == nil {
+		if err := revokeRefreshFamily(r.Context(), h.RefreshTokens, h.Blacklist, rt.FamilyID); err != nil {
+			logx.Error(h.Log, reqID, op, "revoke refresh family failed", err, "family", rt.FamilyID)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		if err := h.Sessions.RevokeSession(r.Context(), rt.FamilyID); err != nil {
+			logx.Error(h.Log, reqID, op, "revoke session failed", err, "session", rt.FamilyID)
+		}
+	}
+
+	if viaCookie {
+		h.Cookies.Clear(w)
+	}
+
 	logx.Info(h.Log, reqID, op, "ok", "jti", claims.JTI)
 	v1.WriteOKResponse(w, r, logoutResponse{Revoked: claims.JTI})
 }
 
-func getTokenFromPathOrHeader(r *http.Request) string {
+func getTokenFromPathOrHeader(r *http.Request, mode mw.QueryTokenMode) string {
 	// 1) DELETE /api/auth/{token}
 	const pfx = "/api/auth/"
 	if strings.HasPrefix(r.URL.Path, pfx) && len(r.URL.Path) > len(pfx) {
 		return r.URL.Path[len(pfx):]
 	}
-	// 2) query ?token=...
-	if t := r.URL.Query().Get("token"); t != "" {
-		return t
+	// 2) Authorization: Bearer ...
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	// 3) query ?token=... (если деплой не запретил токены в URL)
+	if mode.AllowsBearer() {
+		return r.URL.Query().Get("token")
 	}
-	// 3) Authorization: Bearer ...
-	return v1.TokenFromRequest(r)
+	return ""
 }
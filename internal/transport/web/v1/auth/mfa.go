@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/mfa"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// HandlerMFA — второй шаг входа: mfa_token + код → access/refresh.
+type HandlerMFA struct {
+	Log      *log.Logger
+	Users    domain.UsersRepo
+	MFA      domain.MFARepo
+	Box      domain.SecretBox
+	Pending  domain.MFAPendingStore
+	Sessions *SessionIssuer
+	Throttle domain.LoginThrottle
+	Attempts domain.LoginAttemptsRepo
+}
+
+type mfaRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // TOTP (6 цифр) или код восстановления
+}
+
+// Verify godoc
+// @Summary     Complete login with second factor
+// @Description Обменивает mfa_token из POST /api/auth и TOTP-код (или код восстановления) на JWT и refresh-токен.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body mfaRequest true "mfa_token, code"
+// @Success     200 {object} domain.APIEnvelope{response=loginResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     429 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/mfa [post]
+func (h *HandlerMFA) Verify(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.mfa"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	var req mfaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.MFAToken == "" || req.Code == "" {
+		logx.Error(h.Log, reqID, op, "empty mfa_token or code", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	userID, err := h.Pending.Lookup(r.Context(), req.MFAToken)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "mfa token not found", err)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	u, err := h.Users.UserByID(r.Context(), userID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "user not found", err, "user_id", userID)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	// подбор кода ограничивается тем же троттлингом, что и пароль
+	ip := mw.ClientIP(r)
+	st, err := h.Throttle.Attempt(r.Context(), u.Login, ip)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "throttle attempt failed", err, "login", u.Login)
+	}
+	if st.Blocked() {
+		logx.Error(h.Log, reqID, op, "throttled", st.Err(), "login", u.Login, "retry_after", st.RetryAfter)
+		v1.WriteThrottled(w, r, st.Err(), st.RetryAfter)
+		return
+	}
+
+	ok, err := checkSecondFactor(r.Context(), h.MFA, h.Box, u.ID, req.Code)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "second factor check failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if !ok {
+		logx.Error(h.Log, reqID, op, "bad mfa code", domain.ErrUnauth, "user_id", u.ID)
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptBadMFACode)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	succeed(r, h.Log, reqID, op, h.Throttle, u.Login, ip)
+
+	// mfa_token одноразовый
+	if err := h.Pending.Delete(r.Context(), req.MFAToken); err != nil {
+		logx.Error(h.Log, reqID, op, "delete mfa token failed", err, "user_id", u.ID)
+	}
+
+	resp, err := h.Sessions.Start(r, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "start session failed", err, "user_id", u.ID)
+		if errors.Is(err, domain.ErrAccountDisabled) {
+			v1.WriteDomainError(w, r, domain.ErrAccountDisabled)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptOK)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login)
+	h.Sessions.Write(w, r, resp, h.Sessions.Cookies.Wanted(r))
+}
+
+// checkSecondFactor принимает TOTP-код (не старше последнего принятого шага)
+// или неиспользованный код восстановления.
+func checkSecondFactor(ctx context.Context, repo domain.MFARepo, box domain.SecretBox, userID domain.UserID, code string) (bool, error) {
+	if !mfa.LooksLikeTOTP(code) {
+		return repo.UseRecoveryCode(ctx, userID, mfa.HashRecoveryCode(code))
+	}
+
+	st, err := repo.TOTPState(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !st.Enabled || st.Secret == nil || box == nil {
+		return false, nil
+	}
+	secret, err := box.Open(st.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := mfa.Verify(secret, code, time.Now())
+	if !ok || step <= st.LastStep {
+		return false, nil
+	}
+	return repo.UseTOTPStep(ctx, userID, step)
+}
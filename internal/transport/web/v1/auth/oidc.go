@@ -0,0 +1,367 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/oidc"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// Сколько раз пробуем подобрать свободный логин при авто-создании
+const provisionAttempts = 5
+
+// Cookie с хешем state: привязывает callback к браузеру, который начал вход.
+// Путь — только ручки OIDC; SameSite=Lax — cookie придёт с редиректом от провайдера.
+const (
+	oidcStateCookie = "mydocs_oidc_state"
+	oidcCookiePath  = "/api/auth/oidc"
+)
+
+// HandlerOIDC — вход через OpenID Connect (authorization code + PKCE).
+type HandlerOIDC struct {
+	Log        *log.Logger
+	Provider   domain.OIDCProvider // nil — OIDC не настроен
+	States     domain.OIDCStateStore
+	Identities domain.ExternalIdentityRepo
+	Sessions   *SessionIssuer
+	Attempts   domain.LoginAttemptsRepo
+	// Создавать пользователя при первом входе неизвестной внешней учётки
+	AutoProvision bool
+	// Срок cookie с хешем state (как у state в Redis)
+	StateTTL time.Duration
+}
+
+type oidcStartResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+type oidcLinkedResponse struct {
+	Linked bool   `json:"linked"`
+	Login  string `json:"login"`
+}
+
+// Login godoc
+// @Summary     Start OIDC login
+// @Description Редирект на страницу входа провайдера (authorization code + PKCE).
+// @Description ?mode=cookie — по завершении входа сессия будет выдана в HttpOnly cookie.
+// @Tags        auth
+// @Param       mode query string false "cookie"
+// @Success     302
+// @Failure     501 {object} domain.APIEnvelope "OIDC не настроен"
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/oidc/login [get]
+func (h *HandlerOIDC) Login(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.oidc.login"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	// ?mode=cookie — после callback сессия уйдёт в cookie (браузер не может прислать заголовок)
+	authURL, err := h.begin(w, r, nil, r.URL.Query().Get("mode") == "cookie" && h.Sessions.Cookies.On())
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "begin failed", err)
+		v1.WriteDomainError(w, r, oidcError(err))
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "redirect")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Link godoc
+// @Summary     Link OIDC identity
+// @Description Возвращает auth_url для привязки внешней учётки к текущему пользователю.
+// @Description После входа у провайдера callback привяжет учётку вместо выдачи токенов. Только из JWT-сессии.
+// @Description Ответ ставит cookie с хешем state: auth_url нужно открыть в том же браузере.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{response=oidcStartResponse}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     501 {object} domain.APIEnvelope "OIDC не настроен"
+// @Router      /api/auth/oidc/link [post]
+func (h *HandlerOIDC) Link(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.oidc.link"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	authURL, err := h.begin(w, r, &me.ID, false)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "begin failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, oidcError(err))
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID)
+	v1.WriteOKResponse(w, r, oidcStartResponse{AuthURL: authURL})
+}
+
+// Callback godoc
+// @Summary     OIDC callback
+// @Description Обменивает code на ID-токен, проверяет его по JWKS провайдера и выдаёт обычные JWT + refresh.
+// @Description Неизвестная учётка создаётся (OIDC_AUTO_PROVISION) или отклоняется; при привязке — linked=true.
+// @Description state принимается только вместе с cookie, выставленной при старте входа в этом же браузере.
+// @Tags        auth
+// @Produce     json
+// @Param       code  query string true "authorization code"
+// @Param       state query string true "state"
+// @Success     200 {object} domain.APIEnvelope{response=loginResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope "аккаунт отключён (1010) или учётка не привязана"
+// @Failure     501 {object} domain.APIEnvelope "OIDC не настроен"
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/oidc/callback [get]
+func (h *HandlerOIDC) Callback(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.oidc.callback"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	if h.Provider == nil {
+		logx.Error(h.Log, reqID, op, "oidc not configured", domain.ErrNotImplemented)
+		v1.WriteDomainError(w, r, domain.ErrNotImplemented)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		logx.Error(h.Log, reqID, op, "provider returned error", domain.ErrUnauth, "error", e, "description", q.Get("error_description"))
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	code, state := q.Get("code"), q.Get("state")
+	if code == "" || state == "" {
+		logx.Error(h.Log, reqID, op, "empty code or state", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	// state должен прийти в тот браузер, который начал вход: иначе чужой
+	// callback выдал бы сессию атакующего или привязал учётку жертвы к нему
+	bound := h.stateBound(r, state)
+	h.clearStateCookie(w, r)
+	if !bound {
+		logx.Error(h.Log, reqID, op, "state not bound to this browser", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	flow, err := h.States.Take(r.Context(), state)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "unknown state", err)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	cl, err := h.Provider.Exchange(r.Context(), code, flow.CodeVerifier)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "exchange failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(cl.Nonce), []byte(flow.Nonce)) != 1 {
+		logx.Error(h.Log, reqID, op, "nonce mismatch", domain.ErrUnauth, "sub", cl.Subject)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	ident := domain.ExternalIdentity{Issuer: h.Provider.Issuer(), Subject: cl.Subject}
+	if cl.EmailVerified {
+		ident.Email = cl.Email
+	}
+
+	// привязка к уже вошедшему пользователю
+	if flow.LinkUserID != nil {
+		if err := h.Identities.LinkExternalIdentity(r.Context(), *flow.LinkUserID, ident); err != nil {
+			logx.Error(h.Log, reqID, op, "link failed", err, "user_id", *flow.LinkUserID, "sub", cl.Subject)
+			v1.WriteDomainError(w, r, mapRepoError(err))
+			return
+		}
+		logx.Info(h.Log, reqID, op, "linked", "user_id", *flow.LinkUserID, "sub", cl.Subject)
+		v1.WriteOKResponse(w, r, oidcLinkedResponse{Linked: true})
+		return
+	}
+
+	u, err := h.Identities.UserByExternalIdentity(r.Context(), ident.Issuer, ident.Subject)
+	switch {
+	case errors.Is(err, domain.ErrNotFound) && h.AutoProvision:
+		u, err = h.provision(r, cl, ident)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "provision failed", err, "sub", cl.Subject)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		logx.Info(h.Log, reqID, op, "user provisioned", "user_id", u.ID, "login", u.Login)
+	case errors.Is(err, domain.ErrNotFound):
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, cl.PreferredUsername, nil, domain.AttemptOIDCUnknown)
+		logx.Error(h.Log, reqID, op, "identity not linked", domain.ErrForbidden, "sub", cl.Subject)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	case err != nil:
+		logx.Error(h.Log, reqID, op, "lookup failed", err, "sub", cl.Subject)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	if u.Disabled() {
+		logx.Error(h.Log, reqID, op, "account disabled", domain.ErrAccountDisabled, "user_id", u.ID)
+		recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptDisabled)
+		v1.WriteDomainError(w, r, domain.ErrAccountDisabled)
+		return
+	}
+
+	// второй фактор проверил провайдер — локальная TOTP здесь не спрашивается
+	resp, err := h.Sessions.Start(r, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "start session failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	recordAttempt(r, h.Log, reqID, op, h.Attempts, u.Login, &u.ID, domain.AttemptOIDC)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login)
+	h.Sessions.Write(w, r, resp, flow.Cookie)
+}
+
+// begin создаёт state/nonce/PKCE, привязывает state к браузеру cookie и
+// возвращает URL авторизации у провайдера.
+func (h *HandlerOIDC) begin(w http.ResponseWriter, r *http.Request, linkUser *domain.UserID, cookie bool) (string, error) {
+	if h.Provider == nil {
+		return "", domain.ErrNotImplemented
+	}
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+	flow := domain.OIDCFlow{Nonce: nonce, CodeVerifier: verifier, LinkUserID: linkUser, Cookie: cookie}
+	if err := h.States.Save(r.Context(), state, flow); err != nil {
+		return "", err
+	}
+	authURL, err := h.Provider.AuthCodeURL(r.Context(), state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return "", err
+	}
+	ttl := h.StateTTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	http.SetCookie(w, h.stateCookie(r, oidc.StateBinding(state), int(ttl.Seconds())))
+	return authURL, nil
+}
+
+// stateBound: cookie браузера совпадает с хешем state (сравнение за постоянное время).
+func (h *HandlerOIDC) stateBound(r *http.Request, state string) bool {
+	ck, err := r.Cookie(oidcStateCookie)
+	if err != nil || ck.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(ck.Value), []byte(oidc.StateBinding(state))) == 1
+}
+
+func (h *HandlerOIDC) clearStateCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, h.stateCookie(r, "", -1))
+}
+
+func (h *HandlerOIDC) stateCookie(r *http.Request, val string, maxAge int) *http.Cookie {
+	secure := r.TLS != nil
+	if c := h.Sessions.Cookies; c != nil && c.Secure {
+		secure = true
+	}
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    val,
+		Path:     oidcCookiePath,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	}
+}
+
+// provision создаёт пользователя для новой внешней учётки. Логин выводится из
+// preferred_username / email; при конфликте добавляется случайный суффикс.
+func (h *HandlerOIDC) provision(r *http.Request, cl domain.OIDCClaims, ident domain.ExternalIdentity) (domain.User, error) {
+	base := loginBase(cl)
+	login := base
+	var lastErr error
+	for range provisionAttempts {
+		u, err := h.Identities.CreateExternalUser(r.Context(), login, domain.RoleUser, ident)
+		if err == nil {
+			return u, nil
+		}
+		if !errors.Is(err, domain.ErrBadParams) {
+			return domain.User{}, err
+		}
+		// занят логин — или эту же учётку параллельно уже создали
+		if u, err := h.Identities.UserByExternalIdentity(r.Context(), ident.Issuer, ident.Subject); err == nil {
+			return u, nil
+		}
+		lastErr = err
+		login = fmt.Sprintf("%s%04d", base, rand.IntN(10000))
+	}
+	return domain.User{}, fmt.Errorf("no free login for %q: %w", base, lastErr)
+}
+
+// loginBase приводит имя из ID-токена к формату логина (латиница и цифры, от 8 символов).
+func loginBase(cl domain.OIDCClaims) string {
+	src := cl.PreferredUsername
+	if src == "" {
+		src, _, _ = strings.Cut(cl.Email, "@")
+	}
+	var b strings.Builder
+	for _, c := range src {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		}
+	}
+	login := b.String()
+	if login == "" {
+		login = "user"
+	}
+	for len(login) < 8 {
+		login += fmt.Sprint(rand.IntN(10))
+	}
+	return login
+}
+
+// oidcError: ошибки IdP/Redis наружу отдаём как 500, "не настроено" — как 501.
+func oidcError(err error) error {
+	if errors.Is(err, domain.ErrNotImplemented) {
+		return domain.ErrNotImplemented
+	}
+	return domain.ErrUnexpected
+}
+
+// mapRepoError: доменные ошибки репозитория пропускаем, прочие — 500.
+func mapRepoError(err error) error {
+	for _, e := range []error{domain.ErrBadParams, domain.ErrNotFound} {
+		if errors.Is(err, e) {
+			return e
+		}
+	}
+	return domain.ErrUnexpected
+}
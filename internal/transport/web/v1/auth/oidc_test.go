@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorLis/my-docs/internal/auth/oidc"
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func TestLoginBase(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims domain.OIDCClaims
+		prefix string
+	}{
+		{"preferred_username", domain.OIDCClaims{PreferredUsername: "alexander", Email: "other@example.com"}, "alexander"},
+		{"email без домена", domain.OIDCClaims{Email: "john.smith@example.com"}, "johnsmith"},
+		{"лишние символы выбрасываются", domain.OIDCClaims{PreferredUsername: "Иван_Petrov-2000!"}, "Petrov2000"},
+		{"короткое дополняется цифрами", domain.OIDCClaims{PreferredUsername: "bob"}, "bob"},
+		{"ничего подходящего", domain.OIDCClaims{PreferredUsername: "Иван"}, "user"},
+		{"пусто", domain.OIDCClaims{}, "user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loginBase(tt.claims)
+			if !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("loginBase = %q, want prefix %q", got, tt.prefix)
+			}
+			if len(got) < 8 {
+				t.Errorf("loginBase = %q, shorter than 8", got)
+			}
+			for _, c := range got[len(tt.prefix):] {
+				if c < '0' || c > '9' {
+					t.Errorf("loginBase = %q, suffix must be digits", got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestStateBound(t *testing.T) {
+	h := &HandlerOIDC{}
+	state := "abc123"
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		want   bool
+	}{
+		{"тот же браузер", &http.Cookie{Name: oidcStateCookie, Value: oidc.StateBinding(state)}, true},
+		{"нет cookie", nil, false},
+		{"пустая cookie", &http.Cookie{Name: oidcStateCookie, Value: ""}, false},
+		{"cookie другого входа", &http.Cookie{Name: oidcStateCookie, Value: oidc.StateBinding("other")}, false},
+		{"сам state вместо хеша", &http.Cookie{Name: oidcStateCookie, Value: state}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state="+state, nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			if got := h.stateBound(r, state); got != tt.want {
+				t.Errorf("stateBound = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
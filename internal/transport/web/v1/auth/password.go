@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// HandlerPassword — смена пароля самим пользователем (только из JWT-сессии).
+type HandlerPassword struct {
+	Log           *log.Logger
+	Users         domain.UsersRepo
+	Hasher        domain.PasswordHasher
+	Epochs        domain.TokenEpochs
+	RefreshTokens domain.RefreshTokensRepo
+	SessionsRepo  domain.SessionsRepo
+	Sessions      *SessionIssuer
+}
+
+type changePasswordRequest struct {
+	Pswd    string `json:"pswd"`     // текущий пароль
+	NewPswd string `json:"new_pswd"` // новый пароль
+}
+
+// Change godoc
+// @Summary     Change password
+// @Description Меняет пароль, завершает все сессии пользователя и выдаёт новую пару токенов для текущего клиента.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body changePasswordRequest true "pswd, new_pswd"
+// @Success     200 {object} domain.APIEnvelope{response=loginResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/password [post]
+func (h *HandlerPassword) Change(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.password.change"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, info, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if !domain.ValidPassword(req.NewPswd) || req.NewPswd == req.Pswd {
+		logx.Error(h.Log, reqID, op, "new password validation failed", domain.ErrBadParams, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	u, err := h.Users.UserByID(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "user not found", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	// украденного access-токена недостаточно: нужен текущий пароль
+	ok, err := h.Hasher.Verify(req.Pswd, string(u.PassHash))
+	if err != nil || !ok {
+		logx.Error(h.Log, reqID, op, "current password mismatch", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	hashStr, err := h.Hasher.Hash(req.NewPswd)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "hash failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.Users.SetPasswordHash(r.Context(), u.ID, []byte(hashStr)); err != nil {
+		logx.Error(h.Log, reqID, op, "store password failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	n, err := RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.SessionsRepo, u.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "revoke sessions failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// текущий клиент продолжает работу в новой сессии
+	resp, err := h.Sessions.Start(r, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "start session failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "revoked_sessions", n)
+	h.Sessions.Write(w, r, resp, info.ViaCookie || h.Sessions.Cookies.Wanted(r))
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/EgorLis/my-docs/internal/auth/epoch"
+	"github.com/EgorLis/my-docs/internal/auth/password"
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+)
+
+// memKV — кеш в памяти для эпох токенов
+type memKV struct {
+	mu sync.Mutex
+	m  map[string][]byte
+}
+
+func (k *memKV) Get(_ context.Context, key string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.m[key], nil
+}
+
+func (k *memKV) Set(_ context.Context, key string, val []byte, _ int) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.m[key] = val
+	return nil
+}
+
+type fakeUsers struct {
+	domain.UsersRepo
+	u domain.User
+}
+
+func (f *fakeUsers) UserByID(context.Context, domain.UserID) (domain.User, error) { return f.u, nil }
+
+func (f *fakeUsers) SetPasswordHash(_ context.Context, _ domain.UserID, h []byte) error {
+	f.u.PassHash = h
+	return nil
+}
+
+type fakeRefresh struct{ domain.RefreshTokensRepo }
+
+func (fakeRefresh) CreateRefreshToken(_ context.Context, rt domain.RefreshToken) (domain.RefreshToken, error) {
+	return rt, nil
+}
+func (fakeRefresh) RevokeUserRefreshTokens(context.Context, domain.UserID) error { return nil }
+
+type fakeSessions struct{ domain.SessionsRepo }
+
+func (fakeSessions) CreateSession(context.Context, domain.Session) error { return nil }
+func (fakeSessions) RevokeAllSessions(context.Context, domain.UserID) (int64, error) {
+	return 1, nil
+}
+
+type noBlacklist struct{}
+
+func (noBlacklist) Revoke(context.Context, string, time.Time) error { return nil }
+func (noBlacklist) IsRevoked(context.Context, string) (bool, error) { return false, nil }
+
+// Смена пароля отзывает прежние токены, а выданный в ответе access-токен
+// сразу проходит аутентификацию — в ту же секунду, что и сдвиг эпохи.
+func TestChangePasswordKeepsNewSession(t *testing.T) {
+	hasher := password.NewDefault()
+	hash, err := hasher.Hash("Passw0rd!0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUsers{u: domain.User{ID: uuid.New(), Login: "alice", Role: domain.RoleUser, PassHash: []byte(hash)}}
+	tokens := token.New("test-secret", "test", time.Minute)
+	epochs := epoch.NewStore(&memKV{m: map[string][]byte{}}, time.Minute)
+	deps := mw.AuthDeps{Tokens: tokens, Blacklist: noBlacklist{}, Epochs: epochs}
+
+	h := &HandlerPassword{
+		Log:           log.New(io.Discard, "", 0),
+		Users:         users,
+		Hasher:        hasher,
+		Epochs:        epochs,
+		RefreshTokens: fakeRefresh{},
+		SessionsRepo:  fakeSessions{},
+		Sessions: &SessionIssuer{
+			Tokens:        tokens,
+			RefreshTokens: fakeRefresh{},
+			Sessions:      fakeSessions{},
+			RefreshTTL:    time.Hour,
+		},
+	}
+	change := mw.RequireAuth(deps, http.HandlerFunc(h.Change))
+	whoami := mw.RequireAuth(deps, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(hd http.Handler, tok, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/password", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+tok)
+		w := httptest.NewRecorder()
+		hd.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := range 5 {
+		oldTok, _, err := tokens.Issue(context.Background(), users.u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pswd, next := fmt.Sprintf("Passw0rd!%d", i), fmt.Sprintf("Passw0rd!%d", i+1)
+
+		w := call(change, oldTok, `{"pswd":"`+pswd+`","new_pswd":"`+next+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("change #%d: status %d, body %s", i, w.Code, w.Body)
+		}
+		var env struct {
+			Response loginResponse `json:"response"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil || env.Response.Token == "" {
+			t.Fatalf("change #%d: no token in %s (%v)", i, w.Body, err)
+		}
+
+		if w := call(whoami, env.Response.Token, ""); w.Code != http.StatusNoContent {
+			t.Errorf("change #%d: new token rejected with %d", i, w.Code)
+		}
+		if w := call(whoami, oldTok, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("change #%d: old token accepted with %d", i, w.Code)
+		}
+	}
+}
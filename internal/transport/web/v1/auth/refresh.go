@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// HandlerRefresh обменивает refresh-токен на новую пару access+refresh (ротация).
+type HandlerRefresh struct {
+	Log           *log.Logger
+	Users         domain.UsersRepo
+	Tokens        domain.TokenManager
+	RefreshTokens domain.RefreshTokensRepo
+	Sessions      domain.SessionsRepo
+	Blacklist     domain.TokenBlacklist
+	RefreshTTL    time.Duration
+	Cookies       *mw.Cookies
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh godoc
+// @Summary     Refresh access token
+// @Description Обменивает refresh-токен на новую пару токенов. Refresh-токен одноразовый:
+// @Description повторное использование уже обменянного токена отзывает всё семейство.
+// @Description Без refresh_token в теле берётся из cookie (cookie-режим).
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body refreshRequest true "refresh_token"
+// @Success     200 {object} domain.APIEnvelope{response=loginResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/auth/refresh [post]
+func (h *HandlerRefresh) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.refresh"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		logx.Error(h.Log, reqID, op, "method not allowed", domain.ErrMethodNotAllowed, "method", r.Method)
+		v1.WriteDomainError(w, r, domain.ErrMethodNotAllowed)
+		return
+	}
+
+	var req refreshRequest
+	ct := r.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logx.Error(h.Log, reqID, op, "bad json", err)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+	} else {
+		_ = r.ParseForm()
+		req.RefreshToken = r.FormValue("refresh_token")
+	}
+	// браузер в cookie-режиме: refresh-токен в HttpOnly cookie, ответ — тоже в cookie
+	viaCookie := false
+	if req.RefreshToken == "" {
+		if raw := h.Cookies.Refresh(r); raw != "" {
+			req.RefreshToken, viaCookie = raw, true
+		}
+	}
+	if req.RefreshToken == "" {
+		logx.Error(h.Log, reqID, op, "empty refresh_token", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	old, err := h.RefreshTokens.RefreshTokenByHash(r.Context(), token.HashOpaque(req.RefreshToken))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "refresh token not found", err)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	// CSRF-токен cookie-сессии привязан к access-токену, выданному вместе с этим refresh
+	if viaCookie && !h.Cookies.ValidCSRF(r, old.AccessJTI) {
+		logx.Error(h.Log, reqID, op, "csrf check failed", domain.ErrForbidden, "family", old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	if old.RevokedAt != nil {
+		logx.Error(h.Log, reqID, op, "refresh token revoked", domain.ErrUnauth, "family", old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if old.RotatedAt != nil {
+		// reuse: токен уже обменян — вероятно, утёк. Гасим всё семейство.
+		h.revokeOnReuse(r.Context(), reqID, op, old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrTokenReused)
+		return
+	}
+	if time.Now().After(old.ExpiresAt) {
+		logx.Error(h.Log, reqID, op, "refresh token expired", domain.ErrUnauth, "family", old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	u, err := h.Users.UserByID(r.Context(), old.UserID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "user not found", err, "user_id", old.UserID)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+
+	if u.Disabled() {
+		logx.Error(h.Log, reqID, op, "account disabled", domain.ErrAccountDisabled, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrAccountDisabled)
+		return
+	}
+
+	access, claims, err := h.Tokens.Issue(r.Context(), u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "issue token failed", err, "user_id", u.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	raw, hash, err := token.NewOpaque()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate refresh failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	_, err = h.RefreshTokens.RotateRefreshToken(r.Context(), old.ID, domain.RefreshToken{
+		UserID:          u.ID,
+		FamilyID:        old.FamilyID,
+		TokenHash:       hash,
+		AccessJTI:       claims.JTI,
+		AccessExpiresAt: claims.ExpiresAt,
+		ExpiresAt:       time.Now().Add(h.RefreshTTL),
+	})
+	if errors.Is(err, domain.ErrTokenReused) {
+		// гонка: параллельный запрос успел обменять тот же токен
+		_ = h.Blacklist.Revoke(r.Context(), claims.JTI, claims.ExpiresAt)
+		h.revokeOnReuse(r.Context(), reqID, op, old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrTokenReused)
+		return
+	}
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "rotate refresh failed", err, "family", old.FamilyID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// сессия та же (семейство), меняется текущий access-токен
+	if err := h.Sessions.UpdateSessionToken(r.Context(), old.FamilyID, claims.JTI,
+		time.Now().Add(h.RefreshTTL), mw.ClientIP(r), r.UserAgent()); err != nil {
+		logx.Error(h.Log, reqID, op, "update session failed", err, "session", old.FamilyID)
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "family", old.FamilyID)
+	resp := loginResponse{Token: access, RefreshToken: raw, ExpiresAt: claims.ExpiresAt, jti: claims.JTI}
+	writeSession(w, r, h.Cookies, resp, viaCookie || h.Cookies.Wanted(r))
+}
+
+func (h *HandlerRefresh) revokeOnReuse(ctx context.Context, reqID, op string, family uuid.UUID) {
+	logx.Error(h.Log, reqID, op, "refresh token reuse detected, revoking family", domain.ErrTokenReused, "family", family)
+	if err := revokeRefreshFamily(ctx, h.RefreshTokens, h.Blacklist, family); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke family failed", err, "family", family)
+	}
+	if err := h.Sessions.RevokeSession(ctx, family); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke session failed", err, "session", family)
+	}
+}
+
+// issueRefresh создаёт refresh-токен семейства familyID в паре с только что выданным access.
+func issueRefresh(
+	ctx context.Context,
+	repo domain.RefreshTokensRepo,
+	userID domain.UserID,
+	familyID uuid.UUID,
+	access domain.TokenClaims,
+	ttl time.Duration,
+) (string, error) {
+	raw, hash, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+	_, err = repo.CreateRefreshToken(ctx, domain.RefreshToken{
+		UserID:          userID,
+		FamilyID:        familyID,
+		TokenHash:       hash,
+		AccessJTI:       access.JTI,
+		AccessExpiresAt: access.ExpiresAt,
+		ExpiresAt:       time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// revokeRefreshFamily отзывает все refresh-токены семейства и заносит в блэклист
+// ещё живые access-токены, выданные вместе с ними.
+func revokeRefreshFamily(ctx context.Context, repo domain.RefreshTokensRepo, bl domain.TokenBlacklist, family uuid.UUID) error {
+	revoked, err := repo.RevokeRefreshFamily(ctx, family)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, rt := range revoked {
+		if rt.AccessExpiresAt.After(now) {
+			if err := bl.Revoke(ctx, rt.AccessJTI, rt.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/EgorLis/my-docs/internal/auth/token"
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/EgorLis/my-docs/internal/transport/web/logx"
 	"github.com/EgorLis/my-docs/internal/transport/web/mw"
@@ -18,29 +20,36 @@ type HandlerRegister struct {
 	Users      domain.UsersRepo
 	Hasher     domain.PasswordHasher
 	AdminToken string
+	Throttle   domain.LoginThrottle
+	Invites    domain.InvitesRepo
 }
 
 type registerRequest struct {
-	Token string `json:"token"` // админ-токен (из конфига)
-	Login string `json:"login"`
-	Pswd  string `json:"pswd"`
+	Token  string `json:"token,omitempty"`  // админ-токен (из конфига)
+	Invite string `json:"invite,omitempty"` // код приглашения (вместо админ-токена)
+	Login  string `json:"login"`
+	Pswd   string `json:"pswd"`
+	Role   string `json:"role,omitempty"` // user (по умолчанию) | admin; при инвайте — роль из приглашения
 }
 
 type registerResponse struct {
 	Login string `json:"login"`
+	Role  string `json:"role"`
 }
 
 // Register godoc
 // @Summary     Register new user
-// @Description Регистрация нового пользователя (доступно только по admin-token из конфига).
+// @Description Регистрация нового пользователя по admin-token из конфига или по коду приглашения (invite).
+// @Description Приглашение гасится атомарно; роль берётся из приглашения.
 // @Tags        auth
 // @Accept      json
 // @Produce     json
-// @Param       request body registerRequest true "token, login, pswd"
+// @Param       request body registerRequest true "token | invite, login, pswd, role"
 // @Success     200 {object} domain.APIEnvelope{response=registerResponse}
 // @Failure     400 {object} domain.APIEnvelope
 // @Failure     401 {object} domain.APIEnvelope
 // @Failure     405 {object} domain.APIEnvelope
+// @Failure     429 {object} domain.APIEnvelope
 // @Failure     500 {object} domain.APIEnvelope
 // @Router      /api/register [post]
 func (h *HandlerRegister) Register(w http.ResponseWriter, r *http.Request) {
@@ -67,19 +76,27 @@ func (h *HandlerRegister) Register(w http.ResponseWriter, r *http.Request) {
 		// form / query
 		_ = r.ParseForm()
 		req.Token = r.FormValue("token")
+		req.Invite = r.FormValue("invite")
 		req.Login = r.FormValue("login")
 		req.Pswd = r.FormValue("pswd")
+		req.Role = r.FormValue("role")
 	}
 
-	// 1) Проверка admin token
-	if req.Token == "" || req.Token != h.AdminToken {
-		logx.Error(h.Log, reqID, op, "bad admin token", domain.ErrUnauth)
-		v1.WriteDomainError(w, r, domain.ErrUnauth)
+	if req.Token == "" && req.Invite != "" {
+		h.registerWithInvite(w, r, reqID, op, req)
+		return
+	}
+
+	// 1) Проверка admin token (с троттлингом подбора по IP)
+	if !checkAdminToken(w, r, h.Log, reqID, op, h.Throttle, h.AdminToken, req.Token) {
 		return
 	}
 
 	// 2) Валидация логина/пароля (домен)
-	if !domain.ValidLogin(req.Login) || !domain.ValidPassword(req.Pswd) {
+	if req.Role == "" {
+		req.Role = domain.RoleUser
+	}
+	if !domain.ValidLogin(req.Login) || !domain.ValidPassword(req.Pswd) || !domain.ValidRole(req.Role) {
 		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "login", req.Login)
 		v1.WriteDomainError(w, r, domain.ErrBadParams)
 		return
@@ -94,7 +111,7 @@ func (h *HandlerRegister) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 4) Создаём пользователя
-	u, err := h.Users.CreateUser(r.Context(), req.Login, []byte(hashStr))
+	u, err := h.Users.CreateUser(r.Context(), req.Login, []byte(hashStr), req.Role)
 	if err != nil {
 		// возможен уникальный конфликт по login — маппим как bad params
 		logx.Error(h.Log, reqID, op, "create user failed", err, "login", req.Login)
@@ -104,5 +121,51 @@ func (h *HandlerRegister) Register(w http.ResponseWriter, r *http.Request) {
 
 	// 5) Ответ по конверту
 	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login)
-	v1.WriteOKResponse(w, r, registerResponse{Login: u.Login})
+	v1.WriteOKResponse(w, r, registerResponse{Login: u.Login, Role: u.Role})
+}
+
+// registerWithInvite: регистрация по коду приглашения. Каждая попытка с кодом
+// считается по IP — так же, как admin-token; верный код попытку возвращает.
+func (h *HandlerRegister) registerWithInvite(w http.ResponseWriter, r *http.Request, reqID, op string, req registerRequest) {
+	ip := mw.ClientIP(r)
+
+	if !domain.ValidLogin(req.Login) || !domain.ValidPassword(req.Pswd) {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "login", req.Login)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	hashStr, err := h.Hasher.Hash(req.Pswd)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "hash failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	st, err := h.Throttle.Attempt(r.Context(), "", ip)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "throttle attempt failed", err, "ip", ip)
+	}
+	if st.Blocked() {
+		logx.Error(h.Log, reqID, op, "throttled", st.Err(), "ip", ip, "retry_after", st.RetryAfter)
+		v1.WriteThrottled(w, r, st.Err(), st.RetryAfter)
+		return
+	}
+
+	u, err := h.Invites.RegisterWithInvite(r.Context(), token.HashOpaque(req.Invite), req.Login, []byte(hashStr))
+	if errors.Is(err, domain.ErrUnauth) {
+		logx.Error(h.Log, reqID, op, "bad invite", domain.ErrUnauth, "ip", ip)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	succeed(r, h.Log, reqID, op, h.Throttle, "", ip)
+	if err != nil {
+		// возможен уникальный конфликт по login — маппим как bad params (инвайт не тратится)
+		logx.Error(h.Log, reqID, op, "create user failed", err, "login", req.Login)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", u.ID, "login", u.Login, "role", u.Role, "invited_by", u.InvitedBy)
+	v1.WriteOKResponse(w, r, registerResponse{Login: u.Login, Role: u.Role})
 }
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// SessionIssuer — общий финал входа: выдаёт access+refresh и заводит запись
+// о сессии (user agent, IP), чтобы её можно было увидеть и отозвать.
+type SessionIssuer struct {
+	Tokens        domain.TokenManager
+	RefreshTokens domain.RefreshTokensRepo
+	Sessions      domain.SessionsRepo
+	RefreshTTL    time.Duration
+	Cookies       *mw.Cookies
+}
+
+func (s *SessionIssuer) Start(r *http.Request, u domain.User) (loginResponse, error) {
+	if u.Disabled() {
+		return loginResponse{}, domain.ErrAccountDisabled
+	}
+	access, claims, err := s.Tokens.Issue(r.Context(), u)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	// refresh-токен открывает новое семейство = новая сессия
+	family := uuid.New()
+	refresh, err := issueRefresh(r.Context(), s.RefreshTokens, u.ID, family, claims, s.RefreshTTL)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	err = s.Sessions.CreateSession(r.Context(), domain.Session{
+		ID:         family,
+		UserID:     u.ID,
+		CurrentJTI: claims.JTI,
+		UserAgent:  r.UserAgent(),
+		IP:         mw.ClientIP(r),
+		ExpiresAt:  time.Now().Add(s.RefreshTTL),
+	})
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	return loginResponse{Token: access, RefreshToken: refresh, ExpiresAt: claims.ExpiresAt, jti: claims.JTI}, nil
+}
+
+func (s *SessionIssuer) Write(w http.ResponseWriter, r *http.Request, resp loginResponse, cookie bool) {
+	writeSession(w, r, s.Cookies, resp, cookie)
+}
+
+// writeSession отдаёт выданную пару токенов: в теле или, в cookie-режиме,
+// в HttpOnly cookie (в теле тогда только срок и CSRF-токен).
+func writeSession(w http.ResponseWriter, r *http.Request, c *mw.Cookies, resp loginResponse, cookie bool) {
+	if cookie && c.On() {
+		resp.CSRFToken = c.SetSession(w, resp.Token, resp.ExpiresAt, resp.RefreshToken, resp.jti)
+		resp.Token, resp.RefreshToken = "", ""
+	}
+	v1.WriteOKResponse(w, r, resp)
+}
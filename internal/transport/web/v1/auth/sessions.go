@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+type HandlerSessions struct {
+	Log           *log.Logger
+	Sessions      domain.SessionsRepo
+	RefreshTokens domain.RefreshTokensRepo
+	Blacklist     domain.TokenBlacklist
+	Epochs        domain.TokenEpochs
+}
+
+type sessionView struct {
+	domain.Session
+	Current bool `json:"current"`
+}
+
+// sessionCaller: сессиями управляет только сам пользователь из JWT-сессии,
+// PAT к ним доступа не имеет.
+func sessionCaller(r *http.Request) (domain.User, mw.AuthInfo, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, mw.AuthInfo{}, domain.ErrUnauth
+	}
+	info, _ := mw.AuthInfoFromCtx(r.Context())
+	if info.Kind != mw.AuthJWT {
+		return domain.User{}, mw.AuthInfo{}, domain.ErrForbidden
+	}
+	return me, info, nil
+}
+
+// List godoc
+// @Summary     List active sessions
+// @Description Активные сессии текущего пользователя; текущая помечена current=true.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.Session}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/auth/sessions [get]
+func (h *HandlerSessions) List(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.sessions.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, info, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	list, err := h.Sessions.ListActiveSessions(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	out := make([]sessionView, 0, len(list))
+	for _, s := range list {
+		out = append(out, sessionView{Session: s, Current: s.CurrentJTI == info.JTI})
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(out))
+	v1.WriteOKData(w, r, out)
+}
+
+// Revoke godoc
+// @Summary     Revoke session
+// @Description Отзывает refresh-токены сессии и её текущий access-токен.
+// @Tags        auth
+// @Produce     json
+// @Param       id path string true "session id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/auth/sessions/{id} [delete]
+func (h *HandlerSessions) Revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.sessions.revoke"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad session id", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	s, err := h.Sessions.SessionByID(r.Context(), id, me.ID)
+	if err != nil || s.RevokedAt != nil {
+		logx.Error(h.Log, reqID, op, "session not found", err, "session", id)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+
+	if err := revokeRefreshFamily(r.Context(), h.RefreshTokens, h.Blacklist, s.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke family failed", err, "session", s.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.Sessions.RevokeSession(r.Context(), s.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke session failed", err, "session", s.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "session", s.ID)
+	v1.WriteOKResponse(w, r, map[string]bool{s.ID.String(): true})
+}
+
+// RevokeAll godoc
+// @Summary     Sign out everywhere
+// @Description Сдвигает эпоху токенов пользователя: все ранее выданные JWT (включая текущий)
+// @Description перестают приниматься, refresh-токены отзываются.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/auth/sessions [delete]
+func (h *HandlerSessions) RevokeAll(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.sessions.revoke_all"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	n, err := RevokeAllSessions(r.Context(), h.Epochs, h.RefreshTokens, h.Sessions, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "revoke all sessions failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "revoked", n)
+	v1.WriteOKResponse(w, r, map[string]int64{"revoked": n})
+}
+
+// RevokeAllSessions — "выйти везде": сдвиг эпохи (одна запись вместо блэклиста
+// на каждый токен), отзыв refresh-токенов и записей о сессиях.
+func RevokeAllSessions(ctx context.Context, ep domain.TokenEpochs, rt domain.RefreshTokensRepo,
+	ss domain.SessionsRepo, userID domain.UserID) (int64, error) {
+	if _, err := ep.Bump(ctx, userID); err != nil {
+		return 0, err
+	}
+	if err := rt.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return 0, err
+	}
+	return ss.RevokeAllSessions(ctx, userID)
+}
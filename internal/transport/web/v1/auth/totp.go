@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/mfa"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// HandlerTOTP — подключение/отключение TOTP 2FA (только из JWT-сессии).
+// Box == nil — ключ шифрования не настроен, 2FA недоступна.
+type HandlerTOTP struct {
+	Log    *log.Logger
+	MFA    domain.MFARepo
+	Box    domain.SecretBox
+	Issuer string // имя сервиса в приложении-аутентификаторе
+}
+
+type totpEnrollResponse struct {
+	Secret string `json:"secret"` // base32, для ручного ввода
+	URI    string `json:"uri"`    // otpauth://, для QR-кода
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type totpConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // показываются один раз
+}
+
+// Enroll godoc
+// @Summary     Start TOTP enrollment
+// @Description Генерирует новый секрет; 2FA включится после подтверждения кодом.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{response=totpEnrollResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     501 {object} domain.APIEnvelope
+// @Router      /api/auth/mfa/totp [post]
+func (h *HandlerTOTP) Enroll(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.totp.enroll"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if h.Box == nil {
+		logx.Error(h.Log, reqID, op, "mfa key not configured", domain.ErrNotImplemented)
+		v1.WriteDomainError(w, r, domain.ErrNotImplemented)
+		return
+	}
+
+	secret, err := mfa.NewSecret()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate secret failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	sealed, err := h.Box.Seal(secret)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "seal secret failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.MFA.SetTOTPSecret(r.Context(), me.ID, sealed); err != nil {
+		logx.Error(h.Log, reqID, op, "store secret failed", err, "user_id", me.ID)
+		if errors.Is(err, domain.ErrBadParams) {
+			// уже включена: сначала отключить
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID)
+	v1.WriteOKResponse(w, r, totpEnrollResponse{
+		Secret: mfa.EncodeSecret(secret),
+		URI:    mfa.ProvisioningURI(h.Issuer, me.Login, secret),
+	})
+}
+
+// Confirm godoc
+// @Summary     Confirm TOTP enrollment
+// @Description Проверяет код из приложения, включает 2FA и возвращает коды восстановления.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body totpCodeRequest true "code"
+// @Success     200 {object} domain.APIEnvelope{response=totpConfirmResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     501 {object} domain.APIEnvelope
+// @Router      /api/auth/mfa/totp/confirm [post]
+func (h *HandlerTOTP) Confirm(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.totp.confirm"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if h.Box == nil {
+		logx.Error(h.Log, reqID, op, "mfa key not configured", domain.ErrNotImplemented)
+		v1.WriteDomainError(w, r, domain.ErrNotImplemented)
+		return
+	}
+
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	st, err := h.MFA.TOTPState(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "load totp state failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if st.Enabled || st.Secret == nil {
+		logx.Error(h.Log, reqID, op, "nothing to confirm", domain.ErrBadParams, "user_id", me.ID, "enabled", st.Enabled)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	secret, err := h.Box.Open(st.Secret)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "open secret failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	step, ok := mfa.Verify(secret, strings.TrimSpace(req.Code), time.Now())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "bad code", domain.ErrBadParams, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	codes, hashes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate recovery codes failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if err := h.MFA.EnableTOTP(r.Context(), me.ID, step, hashes); err != nil {
+		logx.Error(h.Log, reqID, op, "enable totp failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID)
+	v1.WriteOKResponse(w, r, totpConfirmResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary     Disable TOTP
+// @Description Отключает 2FA; нужен действующий TOTP-код или код восстановления.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       request body totpCodeRequest true "code"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/auth/mfa/totp [delete]
+func (h *HandlerTOTP) Disable(w http.ResponseWriter, r *http.Request) {
+	const op = "auth.totp.disable"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, _, err := sessionCaller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req totpCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		logx.Error(h.Log, reqID, op, "bad json or empty code", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	ok, err := checkSecondFactor(r.Context(), h.MFA, h.Box, me.ID, strings.TrimSpace(req.Code))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "second factor check failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	if !ok {
+		logx.Error(h.Log, reqID, op, "bad code", domain.ErrForbidden, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	if err := h.MFA.DisableTOTP(r.Context(), me.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "disable totp failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID)
+	v1.WriteOKResponse(w, r, map[string]bool{"mfa_enabled": false})
+}
@@ -12,7 +12,9 @@ import (
 )
 
 // Delete godoc
-// @Summary     Delete document (owner only)
+// @Summary     Delete document (owner or co_owner)
+// @Description Документ переносится в корзину (GET /api/trash) и скрывается из выдачи;
+// @Description контент удаляется только при окончательном удалении.
 // @Tags        docs
 // @Param token query string false "Auth token (alternative to Authorization: Bearer)"
 // @Param       id path string true "document id"
@@ -37,6 +39,11 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		v1.WriteDomainError(w, r, domain.ErrUnauth)
 		return
 	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsWrite) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsWrite)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
 
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/docs/")
 	idStr = unescape(idStr)
@@ -47,23 +54,28 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// для удаления нам нужен storageKey → подтянем метаданные без ACL (или с ACL как владелец)
-	d, _, err := h.Docs.DocByID(r.Context(), docID, &me)
+	// метаданные с ACL: права, владелец и public — для инвалидации кеша
+	d, _, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
 	if err != nil {
 		logx.Error(h.Log, reqID, op, "doc not found", err, "doc_id", docID)
 		v1.WriteDomainError(w, r, domain.ErrNotFound)
 		return
 	}
-	if d.OwnerID != me.ID {
-		logx.Error(h.Log, reqID, op, "forbidden (not owner)", domain.ErrForbidden, "doc_id", d.ID, "owner_id", d.OwnerID, "me", me.ID)
+	if !domain.PermAllows(d.Permission, domain.PermCoOwner) {
+		logx.Error(h.Log, reqID, op, "forbidden (not co_owner)", domain.ErrForbidden, "doc_id", d.ID, "permission", d.Permission, "me", me.ID)
 		v1.WriteDomainError(w, r, domain.ErrForbidden)
 		return
 	}
 
-	// сначала удаляем из storage (не критично, если объекта нет)
-	_ = h.Storage.Delete(r.Context(), d.StorageKey)
+	// кому документ был виден в списках (гранты остаются до окончательного удаления)
+	affected := []domain.UserID{me.ID, d.OwnerID}
+	if audience, err := h.Shares.DocAudience(r.Context(), d.ID); err == nil {
+		affected = append(affected, audience...)
+	} else {
+		logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", d.ID)
+	}
 
-	// затем из БД
+	// в корзину; storage не трогаем — объекты удаляются при окончательном удалении
 	if err := h.Docs.DocDelete(r.Context(), d.ID, me.ID); err != nil {
 		logx.Error(h.Log, reqID, op, "db delete failed", err, "doc_id", d.ID)
 		v1.WriteDomainError(w, r, domain.ErrUnexpected)
@@ -75,7 +87,10 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		domain.CacheKeyDocMeta(d.ID),
 		domain.CacheKeyDocJSON(d.ID),
 	)
-	_ = h.Cache.Del(r.Context(), domain.CacheKeyDocList(me.ID.String(), "*"))
+	h.bumpLists(r.Context(), affected...)
+	if d.Public {
+		h.bumpAnonLists(r.Context())
+	}
 
 	logx.Info(h.Log, reqID, op, "ok", "doc_id", d.ID)
 	v1.WriteOKResponse(w, r, map[string]bool{d.ID.String(): true})
@@ -18,10 +18,15 @@ import (
 // @Summary     Get single document or file
 // @Tags        docs
 // @Produce     json
+// @Description Без аутентификации доступны только публичные документы (Cache-Control: public).
+// @Description fields — только выбранные пути JSON-тела: {"$.customer.name": "ACME"} (для файлов игнорируется).
 // @Param token query string false "Auth token (alternative to Authorization: Bearer)"
 // @Param       id path string true "document id"
+// @Param       fields query string false "пути JSON через запятую: customer.name,$.items[0].sku"
 // @Success     200 {object} domain.APIEnvelope
 // @Success     200 {file}  []byte "when file"
+// @Header      200 {string} X-Doc-Permission "права текущего пользователя (viewer|commenter|editor|co_owner|owner)"
+// @Failure     400 {object} domain.APIEnvelope
 // @Failure     401 {object} domain.APIEnvelope
 // @Failure     404 {object} domain.APIEnvelope
 // @Router      /api/docs/{id} [get]
@@ -35,10 +40,11 @@ func (h *Handler) GetOne(w http.ResponseWriter, r *http.Request) {
 		v1.WriteDomainError(w, r, domain.ErrMethodNotAllowed)
 		return
 	}
-	me, ok := mw.UserFromCtx(r.Context())
-	if !ok {
-		logx.Error(h.Log, reqID, op, "unauthorized", domain.ErrUnauth)
-		v1.WriteDomainError(w, r, domain.ErrUnauth)
+	// аноним (OptionalAuth) видит только публичные документы
+	p, err := principal(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "missing scope", err, "scope", domain.ScopeDocsRead)
+		v1.WriteDomainError(w, r, err)
 		return
 	}
 
@@ -52,14 +58,25 @@ func (h *Handler) GetOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad fields", err, "fields_raw", r.URL.Query()["fields"])
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
 	// кэш метаданных → ETag short-circuit
 	if b, err := h.Cache.Get(r.Context(), domain.CacheKeyDocMeta(docID)); err == nil && len(b) > 0 {
 		var cached domain.Document
-		if err := json.Unmarshal(b, &cached); err == nil {
+		// Мета в кеше общая и без ACL: без запроса к БД 304 только для публичного
+		// документа или владельцу, иначе по ETag можно узнать о чужом документе
+		// (гранты проверяет DocByID ниже)
+		if err := json.Unmarshal(b, &cached); err == nil && (cached.Public || (p.Kind == domain.PrincipalUser && cached.OwnerID == p.UserID)) {
 			etag := weakETag(cached.Version, cached.SHA256)
 			if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
 				w.Header().Set("ETag", etag)
 				w.Header().Set("Last-Modified", httpTime(cached.UpdatedAt))
+				h.setCacheControl(w, p)
 				w.WriteHeader(http.StatusNotModified)
 				logx.Info(h.Log, reqID, op, "not modified by etag", "doc_id", cached.ID)
 				return
@@ -70,24 +87,26 @@ func (h *Handler) GetOne(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Достаём актуальные метаданные (с ACL) и JSON
-	meUser := me
-	d, dj, err := h.Docs.DocByID(r.Context(), docID, &meUser)
+	d, dj, err := h.Docs.DocByID(r.Context(), docID, p)
 	if err != nil {
-		logx.Error(h.Log, reqID, op, "db doc not found/acl", err, "doc_id", docID)
+		logx.Error(h.Log, reqID, op, "db doc not found/acl", err, "doc_id", docID, "anonymous", p.IsAnonymous())
 		v1.WriteDomainError(w, r, domain.ErrNotFound)
 		return
 	}
 
-	// Кэшируем мету
-	if buf, err := json.Marshal(d); err == nil {
+	// Кэшируем мету (без прав — они у каждого пользователя свои)
+	shared := d
+	shared.Permission = domain.PermNone
+	if buf, err := json.Marshal(shared); err == nil {
 		_ = h.Cache.Set(r.Context(), domain.CacheKeyDocMeta(d.ID), buf, h.DocTTL)
 	}
 
 	// Готовим общие заголовки
+	w.Header().Set("X-Doc-Permission", d.Permission)
 	etag := weakETag(d.Version, d.SHA256)
 	w.Header().Set("ETag", etag)
 	w.Header().Set("Last-Modified", httpTime(d.UpdatedAt))
-	w.Header().Set("Cache-Control", "private, max-age=60")
+	h.setCacheControl(w, p)
 
 	// Conditional по ETag (If-None-Match)
 	if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
@@ -138,7 +157,18 @@ func (h *Handler) GetOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Иначе — JSON-контент
+	// Иначе — JSON-контент; проекция не кешируется (кеш — только полное тело)
+	if len(fields) > 0 {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			logx.Info(h.Log, reqID, op, "head fields ok", "doc_id", d.ID)
+			return
+		}
+		out := project(dj, fields)
+		logx.Info(h.Log, reqID, op, "json fields ok", "doc_id", d.ID, "fields", len(fields), "found", len(out))
+		v1.WriteOKData(w, r, out)
+		return
+	}
 	if dj != nil {
 		// Кэшируем готовый конверт data
 		env := domain.OkData(dj)
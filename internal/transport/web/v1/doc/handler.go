@@ -7,13 +7,28 @@ import (
 )
 
 type Handler struct {
-	Log     *log.Logger
-	Users   domain.UsersRepo
-	Docs    domain.DocsRepo
-	Shares  domain.SharesRepo
-	Storage domain.BlobStorage
-	Cache   domain.Cache
+	Log    *log.Logger
+	Users  domain.UsersRepo
+	Docs   domain.DocsRepo
+	Shares domain.SharesRepo
+	// Передача владения (запрос → согласие получателя)
+	Transfers domain.TransfersRepo
+	// Ревизии контента (PUT /content, история, откат)
+	Revisions domain.RevisionsRepo
+	// Корзина (мягкое удаление, восстановление, окончательное удаление)
+	Trash domain.TrashRepo
+	// Папки (загрузка в папку, перенос документа)
+	Folders domain.FoldersRepo
+	// JSON-схемы: привязка к документу и проверка JSON-тела
+	Schemas   domain.SchemasRepo
+	Validator domain.SchemaValidator
+	Storage   domain.BlobStorage
+	Cache     domain.Cache
 
 	ListTTL int // секунд
 	DocTTL  int // секунд
+	// max-age для ответов анонимам (только публичные документы)
+	PublicMaxAge int // секунд
+	// Сколько последних ревизий контента хранить (0 — без ограничения)
+	KeepRevisions int
 }
@@ -1,15 +1,20 @@
 package doc
 
 import (
+	"context"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	"github.com/google/uuid"
 )
 
 func weakETag(version int64, sha []byte) string {
@@ -22,15 +27,157 @@ func weakETag(version int64, sha []byte) string {
 
 func httpTime(t time.Time) string { return t.UTC().Format(time.RFC1123) }
 
+// Владелец кеша списков для анонимов (вместо id пользователя)
+const anonListOwner = "anon"
+
+// principal: аутентифицированный пользователь (со скоупом need) или аноним.
+func principal(r *http.Request, need string) (domain.Principal, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.Anonymous, nil
+	}
+	if !mw.HasScope(r.Context(), need) {
+		return domain.Principal{}, domain.ErrForbidden
+	}
+	return domain.AsUser(me), nil
+}
+
+// listOwner — чьи это страницы списка в кеше.
+func listOwner(p domain.Principal) string {
+	if p.IsAnonymous() {
+		return anonListOwner
+	}
+	return p.UserID.String()
+}
+
+// setCacheControl: анонимам — public (только публичные документы), остальным — private.
+// Vary — чтобы общий кеш не отдал анонимный ответ пользователю и наоборот.
+func (h *Handler) setCacheControl(w http.ResponseWriter, p domain.Principal) {
+	w.Header().Set("Vary", "Authorization, Cookie")
+	if p.IsAnonymous() {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", h.PublicMaxAge))
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=60")
+}
+
+// listVersion — текущая версия списков владельца (входит в pageKey).
+// Нет ключа / ошибка кеша — "0".
+func (h *Handler) listVersion(ctx context.Context, owner string) string {
+	b, err := h.Cache.Get(ctx, domain.CacheKeyDocListVer(owner))
+	if err != nil || len(b) == 0 {
+		return "0"
+	}
+	return string(b)
+}
+
+// bumpLists инвалидирует все закешированные страницы списков пользователей.
+func (h *Handler) bumpLists(ctx context.Context, users ...domain.UserID) {
+	if err := InvalidateLists(ctx, h.Cache, users...); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "docs.lists", "bump list version failed", err, "users", len(users))
+	}
+}
+
+// InvalidateLists поднимает версию списков пользователей: новая версия → новые
+// ключи страниц, старые доживают свой TTL. Нужна и вне пакета (состав групп).
+func InvalidateLists(ctx context.Context, cache domain.Cache, users ...domain.UserID) error {
+	var firstErr error
+	seen := make(map[domain.UserID]bool, len(users))
+	for _, u := range users {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		if _, err := cache.Incr(ctx, domain.CacheKeyDocListVer(u.String())); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// InvalidateOwnership сбрасывает кеш после смены владельца: метаданные
+// документов и списки обоих владельцев и всех, у кого есть гранты.
+func InvalidateOwnership(ctx context.Context, cache domain.Cache, mv domain.OwnershipMove) error {
+	firstErr := InvalidateDocs(ctx, cache, mv.Docs...)
+	users := append([]domain.UserID{mv.From, mv.To}, mv.Audience...)
+	if err := InvalidateLists(ctx, cache, users...); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// DropBlobs удаляет из storage объекты, на которые больше не ссылается ни документ,
+// ни ревизия: ключи контентные и бывают общими. Ошибки storage не критичны
+// (как в docs.delete). Нужна и вне пакета (удаление пользователя).
+func DropBlobs(ctx context.Context, revs domain.RevisionsRepo, store domain.BlobStorage, keys []string) (int, error) {
+	orphans, err := revs.UnreferencedBlobs(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range orphans {
+		_ = store.Delete(ctx, k)
+	}
+	return len(orphans), nil
+}
+
+func (h *Handler) dropBlobs(ctx context.Context, keys ...string) {
+	if _, err := DropBlobs(ctx, h.Revisions, h.Storage, keys); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "docs.blobs", "drop blobs failed", err, "keys", len(keys))
+	}
+}
+
+// folderTarget: класть документы в папку можно с правом editor и выше.
+// Нет папки или доступа — ErrNotFound.
+func (h *Handler) folderTarget(ctx context.Context, id uuid.UUID, me domain.UserID) error {
+	f, err := h.Folders.FolderByID(ctx, id, me)
+	if err != nil {
+		return err
+	}
+	if !domain.PermAllows(f.Permission, domain.PermEditor) {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// bumpAnonLists — после изменения набора публичных документов.
+func (h *Handler) bumpAnonLists(ctx context.Context) {
+	if err := InvalidateAnonLists(ctx, h.Cache); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "docs.lists", "bump anon list version failed", err)
+	}
+}
+
+// InvalidateAnonLists поднимает версию анонимных списков (публичные документы).
+func InvalidateAnonLists(ctx context.Context, cache domain.Cache) error {
+	_, err := cache.Incr(ctx, domain.CacheKeyDocListVer(anonListOwner))
+	return err
+}
+
+// InvalidateDocs сбрасывает закешированные метаданные и JSON документов
+// (нужна и вне пакета — удаление папки с содержимым).
+func InvalidateDocs(ctx context.Context, cache domain.Cache, docs ...domain.DocID) error {
+	var firstErr error
+	for _, id := range docs {
+		if err := cache.Del(ctx, domain.CacheKeyDocMeta(id), domain.CacheKeyDocJSON(id)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 // pageKey = хэш фильтров/сортировки/лимита, чтобы был компактный и стабильный
-func makeListPageKey(login, key, val, sort string, limit int) string {
+func makeListPageKey(ver, login, key, val, sort string, limit int, folder string, recursive bool, labels, schema, jsonQ string) string {
 	h := sha1.New()
 	// важно: явно разделять поля
+	io.WriteString(h, "ver="+ver+";")
 	io.WriteString(h, "login="+login+";")
 	io.WriteString(h, "key="+key+";")
 	io.WriteString(h, "val="+val+";")
 	io.WriteString(h, "sort="+sort+";")
 	io.WriteString(h, fmt.Sprintf("limit=%d;", limit))
+	io.WriteString(h, fmt.Sprintf("folder=%s;recursive=%t;", folder, recursive))
+	io.WriteString(h, "labels="+labels+";")
+	io.WriteString(h, "schema="+schema+";")
+	io.WriteString(h, "json="+jsonQ+";")
 	return hex.EncodeToString(h.Sum(nil))
 }
 
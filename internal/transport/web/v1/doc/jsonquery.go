@@ -0,0 +1,87 @@
+package doc
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// jsonFilter: ?json.contains=<объект> (@>), ?json.eq=<путь>=<значение> и
+// ?json.exists=<путь>; каждый можно повторять, нужны все условия.
+func jsonFilter(q url.Values) ([]domain.JSONFilter, error) {
+	var out []domain.JSONFilter
+	for _, s := range q["json.contains"] {
+		v, ok := domain.JSONObject(s)
+		if !ok {
+			return nil, domain.ErrBadParams
+		}
+		out = append(out, domain.JSONFilter{Op: domain.JSONContains, Value: v})
+	}
+	for _, s := range q["json.eq"] {
+		p, rest, ok := domain.CutJSONPath(s)
+		if !ok || !strings.HasPrefix(rest, "=") {
+			return nil, domain.ErrBadParams
+		}
+		v, ok := domain.JSONScalar(rest[1:])
+		if !ok {
+			return nil, domain.ErrBadParams
+		}
+		out = append(out, domain.JSONFilter{Op: domain.JSONEq, Path: p, Value: v})
+	}
+	for _, s := range q["json.exists"] {
+		p, ok := domain.ParseJSONPath(s)
+		if !ok {
+			return nil, domain.ErrBadParams
+		}
+		out = append(out, domain.JSONFilter{Op: domain.JSONExists, Path: p})
+	}
+	if len(out) > domain.MaxJSONFilters {
+		return nil, domain.ErrBadParams
+	}
+	return out, nil
+}
+
+// jsonKey — каноническая запись фильтров для ключа кеша списков.
+func jsonKey(fs []domain.JSONFilter) string {
+	var b strings.Builder
+	for _, f := range fs {
+		b.WriteString(string(f.Op) + ":" + f.Path.String() + "=" + string(f.Value) + "\n")
+	}
+	return b.String()
+}
+
+// parseFields: ?fields=customer.name,$.items[0] (можно повторять).
+func parseFields(q url.Values) ([]domain.JSONPath, error) {
+	var out []domain.JSONPath
+	for _, s := range q["fields"] {
+		for s != "" {
+			p, rest, ok := domain.CutJSONPath(strings.TrimLeft(s, " "))
+			if !ok {
+				return nil, domain.ErrBadParams
+			}
+			rest = strings.TrimLeft(rest, " ")
+			if rest != "" && rest[0] != ',' {
+				return nil, domain.ErrBadParams
+			}
+			out = append(out, p)
+			s = strings.TrimPrefix(rest, ",")
+		}
+	}
+	if len(out) > domain.MaxJSONFields {
+		return nil, domain.ErrBadParams
+	}
+	return out, nil
+}
+
+// project — только выбранные пути: {"$.customer.name": "ACME"}; ключ — каноническая
+// запись пути, отсутствующие в теле пути не попадают в ответ.
+func project(body domain.DocJSON, fields []domain.JSONPath) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, p := range fields {
+		if v, ok := p.Lookup(map[string]any(body)); ok {
+			out[p.String()] = v
+		}
+	}
+	return out
+}
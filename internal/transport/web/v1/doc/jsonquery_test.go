@@ -0,0 +1,148 @@
+package doc
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+func TestJSONFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // op:path=value
+		ok    bool
+	}{
+		{"нет фильтров", "", nil, true},
+		{"contains", `json.contains={"a": 1}`, []string{`contains:$={"a":1}`}, true},
+		{"eq со строкой без кавычек", "json.eq=status=paid", []string{`eq:$.status="paid"`}, true},
+		{"eq с числом", "json.eq=$.total=10", []string{`eq:$.total=10`}, true},
+		{"eq со знаком = в значении", "json.eq=note=a=b", []string{`eq:$.note="a=b"`}, true},
+		{"exists", "json.exists=$.items[0]", []string{`exists:$.items[0]=`}, true},
+		{"несколько", "json.exists=a&json.exists=b", []string{`exists:$.a=`, `exists:$.b=`}, true},
+
+		{"contains не объект", "json.contains=[1]", nil, false},
+		{"contains не JSON", "json.contains={", nil, false},
+		{"eq без значения", "json.eq=status", nil, false},
+		{"eq с объектом", `json.eq=a={"b":1}`, nil, false},
+		{"eq с плохим путём", "json.eq=a..b=1", nil, false},
+		{"exists с хвостом", "json.exists=a b", nil, false},
+		{"exists с wildcard", "json.exists=a.*", nil, false},
+		{"NUL в значении", `json.eq=a="\u0000"`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(strings.NewReplacer(`"`, "%22", " ", "%20", "{", "%7B", "}", "%7D").Replace(tt.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs, err := jsonFilter(q)
+			if (err == nil) != tt.ok {
+				t.Fatalf("jsonFilter err = %v, want ok %v", err, tt.ok)
+			}
+			var got []string
+			for _, f := range fs {
+				got = append(got, string(f.Op)+":"+f.Path.String()+"="+string(f.Value))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonFilter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFilterLimit(t *testing.T) {
+	q := url.Values{}
+	for range domain.MaxJSONFilters {
+		q.Add("json.exists", "a")
+	}
+	if _, err := jsonFilter(q); err != nil {
+		t.Errorf("%d filters rejected: %v", domain.MaxJSONFilters, err)
+	}
+	q.Add("json.exists", "a")
+	if _, err := jsonFilter(q); err == nil {
+		t.Errorf("more than %d filters accepted", domain.MaxJSONFilters)
+	}
+}
+
+func TestJSONKey(t *testing.T) {
+	// разные фильтры — разные ключи кеша, одинаковые по смыслу — один ключ
+	parse := func(raw string) string {
+		q, _ := url.ParseQuery(raw)
+		fs, err := jsonFilter(q)
+		if err != nil {
+			t.Fatalf("jsonFilter(%s): %v", raw, err)
+		}
+		return jsonKey(fs)
+	}
+	if parse("json.eq=status=paid") != parse("json.eq=$.status=%22paid%22") {
+		t.Error("equivalent filters produce different keys")
+	}
+	if parse("json.eq=status=paid") == parse("json.eq=status=draft") {
+		t.Error("different values share a key")
+	}
+	if parse("json.exists=a.b") == parse(`json.exists=$[%22a.b%22]`) {
+		t.Error("different paths share a key")
+	}
+	if parse("") != "" {
+		t.Error("no filters must give an empty key")
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+		ok     bool
+	}{
+		{"нет", nil, nil, true},
+		{"один", []string{"customer.name"}, []string{"$.customer.name"}, true},
+		{"через запятую", []string{"a, $.b[0] ,c"}, []string{"$.a", "$.b[0]", "$.c"}, true},
+		{"повтор параметра", []string{"a", "b"}, []string{"$.a", "$.b"}, true},
+		{"запятая в ключе", []string{`$["x,y"],z`}, []string{`$["x,y"]`, "$.z"}, true},
+		{"пустой параметр", []string{""}, nil, true},
+		{"висящая запятая", []string{"a,"}, []string{"$.a"}, true},
+
+		{"пустой элемент", []string{"a,,b"}, nil, false},
+		{"мусор после пути", []string{"a b"}, nil, false},
+		{"wildcard", []string{"a.*"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := parseFields(url.Values{"fields": tt.fields})
+			if (err == nil) != tt.ok {
+				t.Fatalf("parseFields err = %v, want ok %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			var got []string
+			for _, p := range ps {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	body := domain.DocJSON{
+		"customer": map[string]any{"name": "ACME"},
+		"items":    []any{"a", "b"},
+		"n":        nil,
+	}
+	ps, err := parseFields(url.Values{"fields": {"customer.name,items[1],n,missing,items[5]"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"$.customer.name": "ACME", "$.items[1]": "b", "$.n": nil}
+	if got := project(body, ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("project = %v, want %v", got, want)
+	}
+}
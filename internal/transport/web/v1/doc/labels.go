@@ -0,0 +1,94 @@
+package doc
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+)
+
+// normalizeAttrs: ключи и значения по типам, не больше MaxDocAttrs.
+func normalizeAttrs(in map[string]domain.DocAttr) (map[string]domain.DocAttr, error) {
+	if len(in) > domain.MaxDocAttrs {
+		return nil, domain.ErrBadParams
+	}
+	out := make(map[string]domain.DocAttr, len(in))
+	for k, a := range in {
+		if !domain.ValidAttrKey(k) {
+			return nil, domain.ErrBadParams
+		}
+		a, ok := domain.NormalizeAttr(a)
+		if !ok {
+			return nil, domain.ErrBadParams
+		}
+		out[k] = a
+	}
+	return out, nil
+}
+
+// patchAttrs — merge patch поверх текущих атрибутов: null удаляет атрибут,
+// объект {"type","value"} задаёт его целиком.
+func patchAttrs(current map[string]domain.DocAttr, patch map[string]json.RawMessage) (map[string]domain.DocAttr, error) {
+	out := make(map[string]domain.DocAttr, len(current)+len(patch))
+	for k, a := range current {
+		out[k] = a
+	}
+	for k, raw := range patch {
+		if strings.TrimSpace(string(raw)) == "null" {
+			delete(out, k)
+			continue
+		}
+		var a domain.DocAttr
+		if json.Unmarshal(raw, &a) != nil {
+			return nil, domain.ErrBadParams
+		}
+		out[k] = a
+	}
+	return normalizeAttrs(out)
+}
+
+// labelFilter: ?tag=a&tag=b (все теги сразу) и ?attr.<key>=<value>; для
+// совместимости — key=tag или key=attr.<key> вместе с value.
+func labelFilter(q url.Values) ([]string, map[string]string, error) {
+	rawTags := q["tag"]
+	attrs := map[string]string{}
+	for k, vs := range q {
+		if name, ok := strings.CutPrefix(k, "attr."); ok && len(vs) > 0 {
+			attrs[name] = vs[0]
+		}
+	}
+	switch key := q.Get("key"); {
+	case key == "tag":
+		rawTags = append(rawTags, q.Get("value"))
+	case strings.HasPrefix(key, "attr."):
+		attrs[strings.TrimPrefix(key, "attr.")] = q.Get("value")
+	}
+
+	tags, ok := domain.NormalizeTags(rawTags)
+	if !ok {
+		return nil, nil, domain.ErrBadParams
+	}
+	for k := range attrs {
+		if !domain.ValidAttrKey(k) {
+			return nil, nil, domain.ErrBadParams
+		}
+	}
+	return tags, attrs, nil
+}
+
+// labelsKey — стабильная запись фильтров для ключа кеша списков.
+func labelsKey(tags []string, attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("tags=" + strings.Join(tags, ","))
+	for _, k := range keys {
+		b.WriteString("&" + url.QueryEscape(k) + "=" + url.QueryEscape(attrs[k]))
+	}
+	return b.String()
+}
@@ -10,20 +10,32 @@ import (
 	"github.com/EgorLis/my-docs/internal/transport/web/logx"
 	"github.com/EgorLis/my-docs/internal/transport/web/mw"
 	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
 )
 
 // List godoc
 // @Summary     List documents
+// @Description Без аутентификации — только публичные документы (Cache-Control: public).
 // @Tags        docs
 // @Produce     json
 // @Param token query string false "Auth token (alternative to Authorization: Bearer)"
 // @Param       login query string false "owner login (optional)"
-// @Param       key   query string false "filter key (name|mime)"
+// @Param       key   query string false "filter key (name|mime|tag|attr.<key>)"
 // @Param       value query string false "filter value"
 // @Param       limit query int    false "limit"
 // @Param       sort  query string false "Sort order" Enums(name_asc, name_desc, created_asc, created_desc)
+// @Param       folder    query string false "folder id или root (только документы вне папок)"
+// @Param       recursive query bool   false "вместе с подпапками folder"
+// @Param       tag       query []string false "тег (можно несколько — нужны все)" collectionFormat(multi)
+// @Param       attr.key  query string false "атрибут: attr.<key>=<value>, например attr.project=apollo"
+// @Param       schema    query string false "JSON-схема документов (id или имя)"
+// @Param       json.contains query []string false "JSON-тело содержит объект (@>)" collectionFormat(multi)
+// @Param       json.eq       query []string false "значение по пути: $.customer.name=ACME (значение — литерал JSON или строка)" collectionFormat(multi)
+// @Param       json.exists   query []string false "путь есть в JSON-теле: $.items[0].sku" collectionFormat(multi)
 // @Success     200 {object} domain.APIEnvelope{data=object}
+// @Failure     400 {object} domain.APIEnvelope
 // @Failure     401 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
 // @Router      /api/docs [get]
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	const op = "docs.list"
@@ -35,12 +47,14 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		v1.WriteDomainError(w, r, domain.ErrMethodNotAllowed)
 		return
 	}
-	me, ok := mw.UserFromCtx(r.Context())
-	if !ok {
-		logx.Error(h.Log, reqID, op, "unauthorized", domain.ErrUnauth)
-		v1.WriteDomainError(w, r, domain.ErrUnauth)
+	// аноним (OptionalAuth) видит только публичные документы
+	p, err := principal(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "missing scope", err, "scope", domain.ScopeDocsRead)
+		v1.WriteDomainError(w, r, err)
 		return
 	}
+	owner := listOwner(p)
 
 	login := r.URL.Query().Get("login")
 	key := r.URL.Query().Get("key")
@@ -57,32 +71,83 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// кэш-ключ теперь включает новое значение сортировки
-	pageKey := makeListPageKey(login, key, val, string(sortVal), limit)
-	ckey := domain.CacheKeyDocList(me.ID.String(), pageKey)
+	// папка: id или root; видимость документов внутри — по обычным правам
+	folderRaw := r.URL.Query().Get("folder")
+	recursive := r.URL.Query().Get("recursive") == "true"
+	var folder *uuid.UUID
+	if folderRaw != "" && folderRaw != "root" {
+		id, err := uuid.Parse(folderRaw)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "bad folder id", err, "folder_raw", folderRaw)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+		folder = &id
+	}
+
+	// теги и атрибуты: ?tag=invoice&attr.project=apollo
+	tags, attrs, err := labelFilter(r.URL.Query())
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad label filter", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	// схема: id как есть, имя — своя схема или глобальная
+	var schemaID *uuid.UUID
+	if ref := r.URL.Query().Get("schema"); ref != "" {
+		if id, err := uuid.Parse(ref); err == nil {
+			schemaID = &id
+		} else {
+			sch, err := h.resolveSchema(r.Context(), ref, p.UserID) // аноним — только глобальные
+			if err != nil {
+				logx.Error(h.Log, reqID, op, "schema rejected", err, "schema", ref)
+				v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+				return
+			}
+			schemaID = &sch.ID
+		}
+	}
+	// JSON-тело: ?json.contains={"status":"paid"}&json.eq=$.customer.name=ACME&json.exists=$.due
+	jsonFs, err := jsonFilter(r.URL.Query())
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad json filter", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	schemaKey := ""
+	if schemaID != nil {
+		schemaKey = schemaID.String()
+	}
+
+	// кэш-ключ включает версию списков пользователя и значение сортировки
+	pageKey := makeListPageKey(h.listVersion(r.Context(), owner), login, key, val, string(sortVal), limit, folderRaw, recursive, labelsKey(tags, attrs), schemaKey, jsonKey(jsonFs))
+	ckey := domain.CacheKeyDocList(owner, pageKey)
 	// кеш-хит
 	if b, err := h.Cache.Get(r.Context(), ckey); err == nil && b != nil {
-		w.Header().Set("Cache-Control", "private, max-age=60")
+		h.setCacheControl(w, p)
 		if r.Method == http.MethodHead {
 			w.WriteHeader(http.StatusOK)
-			logx.Info(h.Log, reqID, op, "head from cache ok", "user_id", me.ID, "bytes", len(b))
+			logx.Info(h.Log, reqID, op, "head from cache ok", "list_owner", owner, "bytes", len(b))
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(b)
-		logx.Info(h.Log, reqID, op, "from cache ok", "user_id", me.ID, "bytes", len(b))
+		logx.Info(h.Log, reqID, op, "from cache ok", "list_owner", owner, "bytes", len(b))
 		return
 	}
 
 	// запрос к БД
 	f := domain.ListFilter{
 		Login: login, Key: key, Value: val, Limit: limit, Sort: sortVal,
+		Folder: folder, Root: folderRaw == "root", Recursive: recursive,
+		Tags: tags, Attrs: attrs, Schema: schemaID, JSON: jsonFs,
 	}
 
-	docs, err := h.Docs.DocsList(r.Context(), me, f)
+	docs, err := h.Docs.DocsList(r.Context(), p, f)
 	if err != nil {
-		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		logx.Error(h.Log, reqID, op, "db list failed", err, "list_owner", owner)
 		v1.WriteDomainError(w, r, domain.ErrUnexpected)
 		return
 	}
@@ -95,19 +160,39 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		Public  bool     `json:"public"`
 		Created string   `json:"created"`
 		Grant   []string `json:"grant"`
+		// права текущего пользователя
+		Permission string                    `json:"permission"`
+		Folder     string                    `json:"folder_id,omitempty"`
+		Tags       []string                  `json:"tags,omitempty"`
+		Attrs      map[string]domain.DocAttr `json:"attrs,omitempty"`
+		Schema     string                    `json:"schema_id,omitempty"`
 	}
 	out := struct {
 		Docs []docOut `json:"docs"`
 	}{Docs: make([]docOut, 0, len(docs))}
 
 	for _, d := range docs {
-		gr, _ := h.Shares.ListGrantedLogins(r.Context(), d.ID)
-		out.Docs = append(out.Docs, docOut{
+		// кому выдан доступ — не для анонимов
+		var gr []string
+		if !p.IsAnonymous() {
+			gr, _ = h.Shares.ListGrantedLogins(r.Context(), d.ID)
+		}
+		o := docOut{
 			ID: d.ID.String(), Name: d.Name, Mime: d.MIME,
 			File: d.File, Public: d.Public,
-			Created: d.CreatedAt.Format("2006-01-02 15:04:05"),
-			Grant:   gr,
-		})
+			Created:    d.CreatedAt.Format("2006-01-02 15:04:05"),
+			Grant:      gr,
+			Permission: d.Permission,
+			Tags:       d.Tags,
+			Attrs:      d.Attrs,
+		}
+		if d.FolderID != nil {
+			o.Folder = d.FolderID.String()
+		}
+		if d.SchemaID != nil {
+			o.Schema = d.SchemaID.String()
+		}
+		out.Docs = append(out.Docs, o)
 	}
 
 	env := domain.OkData(out)
@@ -115,11 +200,12 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		_ = h.Cache.Set(r.Context(), ckey, buf, h.ListTTL)
 	}
 
+	h.setCacheControl(w, p)
 	if r.Method == http.MethodHead {
 		w.WriteHeader(http.StatusOK)
-		logx.Info(h.Log, reqID, op, "head ok", "user_id", me.ID, "count", len(out.Docs), "sort", sortVal)
+		logx.Info(h.Log, reqID, op, "head ok", "list_owner", owner, "count", len(out.Docs), "sort", sortVal)
 		return
 	}
-	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(out.Docs), "sort", sortVal)
+	logx.Info(h.Log, reqID, op, "ok", "list_owner", owner, "count", len(out.Docs), "sort", sortVal)
 	v1.WriteEnvelope(w, r, http.StatusOK, env)
 }
@@ -0,0 +1,63 @@
+package doc
+
+import (
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Meta godoc
+// @Summary     Get document metadata
+// @Description Метаданные без контента: имя, mime, папка, теги, атрибуты и права текущего пользователя.
+// @Description Без аутентификации — только публичные документы. ETag тот же, что у GET /api/docs/{id}.
+// @Tags        docs
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Document}
+// @Header      200 {string} ETag "current ETag"
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/meta [get]
+func (h *Handler) Meta(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.meta"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	p, err := principal(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "missing scope", err, "scope", domain.ScopeDocsRead)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad doc id", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	d, _, err := h.Docs.DocByID(r.Context(), docID, p)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db doc not found/acl", err, "doc_id", docID, "anonymous", p.IsAnonymous())
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+
+	etag := weakETag(d.Version, d.SHA256)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", httpTime(d.UpdatedAt))
+	h.setCacheControl(w, p)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
+		w.WriteHeader(http.StatusNotModified)
+		logx.Info(h.Log, reqID, op, "not modified by etag", "doc_id", d.ID)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "doc_id", d.ID, "tags", len(d.Tags), "attrs", len(d.Attrs))
+	v1.WriteOKData(w, r, d)
+}
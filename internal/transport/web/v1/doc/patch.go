@@ -0,0 +1,338 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Patch godoc
+// @Summary     Update document metadata and JSON
+// @Description JSON Merge Patch (RFC 7396) поверх {"name","mime","public","json","folder_id","tags","attrs","schema"}: отсутствующее поле не меняется,
+// @Description null в json удаляет ключ (json: null — всё JSON-тело). Контент файла не меняется.
+// @Description tags заменяются целиком; в attrs null удаляет атрибут, {"type","value"} задаёт его (attrs: null — все).
+// @Description schema — JSON-схема (id или имя), null отвязывает. Итоговый json не соответствует схеме — 422 с error.details.
+// @Description Обязателен If-Match с текущим ETag (412 при несовпадении, 428 без него).
+// @Description name/mime/json/tags/attrs — право editor; public и folder_id (перенос, null — в корень) — co_owner
+// @Description и editor на целевую папку. В папке с unique_names имя занято — 409.
+// @Tags        docs
+// @Accept      json
+// @Produce     json
+// @Param       id       path   string true "document id"
+// @Param       If-Match header string true "current ETag"
+// @Param       request  body   object true "merge patch"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Document}
+// @Header      200 {string} ETag "new ETag"
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Failure     412 {object} domain.APIEnvelope
+// @Failure     422 {object} domain.APIEnvelope
+// @Failure     428 {object} domain.APIEnvelope
+// @Router      /api/docs/{id} [patch]
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.patch"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "unauthorized: no user in ctx", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsWrite) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsWrite)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad doc id", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		logx.Error(h.Log, reqID, op, "if-match required", domain.ErrPreconditionReq, "doc_id", docID)
+		v1.WriteDomainError(w, r, domain.ErrPreconditionReq)
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	d, dj, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc not found or no access", err, "doc_id", docID)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+	etag := weakETag(d.Version, d.SHA256)
+	if !matchETag(ifMatch, etag) {
+		logx.Error(h.Log, reqID, op, "etag mismatch", domain.ErrPrecondition, "doc_id", d.ID, "if_match", ifMatch, "etag", etag)
+		w.Header().Set("ETag", etag)
+		v1.WriteDomainError(w, r, domain.ErrPrecondition)
+		return
+	}
+
+	// пустой патч — ничего не меняем и версию не повышаем
+	if len(patch) == 0 {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", httpTime(d.UpdatedAt))
+		logx.Info(h.Log, reqID, op, "empty patch", "doc_id", d.ID)
+		v1.WriteOKData(w, r, d)
+		return
+	}
+
+	// схема — ссылка по id или имени, её разрешает репозиторий
+	schemaRaw, setSchema := patch["schema"]
+	delete(patch, "schema")
+
+	u, err := parsePatch(patch, d, dj)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "validation failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if setSchema {
+		u.SetSchema = true
+		if !bytes.Equal(bytes.TrimSpace(schemaRaw), []byte("null")) {
+			var ref string
+			if json.Unmarshal(schemaRaw, &ref) != nil || ref == "" {
+				logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "doc_id", d.ID)
+				v1.WriteDomainError(w, r, domain.ErrBadParams)
+				return
+			}
+			sch, err := h.resolveSchema(r.Context(), ref, me.ID)
+			if err != nil {
+				logx.Error(h.Log, reqID, op, "schema rejected", err, "doc_id", d.ID, "schema", ref)
+				v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+				return
+			}
+			u.SchemaID = &sch.ID
+		}
+	}
+	need := domain.PermEditor
+	if u.Public != nil && *u.Public != d.Public {
+		need = domain.PermCoOwner
+	}
+	// перенос меняет унаследованные от папки права
+	moved := u.SetFolder && !sameFolder(u.FolderID, d.FolderID)
+	if moved {
+		need = domain.PermCoOwner
+	}
+	if !domain.PermAllows(d.Permission, need) {
+		logx.Error(h.Log, reqID, op, "permission denied", domain.ErrForbidden, "doc_id", d.ID, "have", d.Permission, "need", need)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	if moved && u.FolderID != nil {
+		if err := h.folderTarget(r.Context(), *u.FolderID, me.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "folder rejected", err, "doc_id", d.ID, "folder_id", *u.FolderID)
+			v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+			return
+		}
+	}
+
+	// новое тело или новая схема: итоговый JSON должен ей соответствовать
+	if u.SetJSON || u.SetSchema {
+		schemaID, body := d.SchemaID, dj
+		if u.SetSchema {
+			schemaID = u.SchemaID
+		}
+		if u.SetJSON {
+			body = u.JSON
+		}
+		if err := h.checkSchema(r.Context(), schemaID, body); err != nil {
+			logx.Error(h.Log, reqID, op, "json does not match schema", err, "doc_id", d.ID)
+			v1.WriteDomainError(w, r, err) // 422 с error.details
+			return
+		}
+	}
+
+	// при переносе списки меняются и у тех, кто видел документ через прежнюю папку
+	affected := []domain.UserID{me.ID, d.OwnerID}
+	if moved {
+		if audience, err := h.Shares.DocAudience(r.Context(), d.ID); err == nil {
+			affected = append(affected, audience...)
+		} else {
+			logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", d.ID)
+		}
+	}
+
+	upd, err := h.Docs.UpdateDoc(r.Context(), d.ID, d.Version, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db update failed", err, "doc_id", d.ID)
+		switch {
+		case errors.Is(err, domain.ErrPrecondition):
+			v1.WriteDomainError(w, r, domain.ErrPrecondition)
+		case errors.Is(err, domain.ErrConflict):
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+	upd.Permission = d.Permission
+
+	// мета и JSON в кеше устарели; в списках видны имя, mime, public, теги и атрибуты
+	if err := h.Cache.Del(r.Context(), domain.CacheKeyDocMeta(d.ID), domain.CacheKeyDocJSON(d.ID)); err != nil {
+		logx.Error(h.Log, reqID, op, "cache del failed", err, "doc_id", d.ID)
+	}
+	if audience, err := h.Shares.DocAudience(r.Context(), d.ID); err == nil {
+		affected = append(affected, audience...)
+	} else {
+		logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", d.ID)
+	}
+	h.bumpLists(r.Context(), affected...)
+	if d.Public || upd.Public {
+		h.bumpAnonLists(r.Context())
+	}
+
+	w.Header().Set("ETag", weakETag(upd.Version, upd.SHA256))
+	w.Header().Set("Last-Modified", httpTime(upd.UpdatedAt))
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "version", upd.Version)
+	v1.WriteOKData(w, r, upd)
+}
+
+// parsePatch: merge patch → DocUpdate. Метаданные удалить нельзя (null → ошибка),
+// json и attrs сливаются с текущими значениями документа d по RFC 7396.
+func parsePatch(patch map[string]json.RawMessage, d domain.Document, current domain.DocJSON) (domain.DocUpdate, error) {
+	var u domain.DocUpdate
+	for k, raw := range patch {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		switch k {
+		case "name":
+			var s string
+			if isNull || json.Unmarshal(raw, &s) != nil || strings.TrimSpace(s) == "" {
+				return u, domain.ErrBadParams
+			}
+			s = strings.TrimSpace(s)
+			u.Name = &s
+		case "mime":
+			var s string
+			if isNull || json.Unmarshal(raw, &s) != nil {
+				return u, domain.ErrBadParams
+			}
+			if _, _, err := mime.ParseMediaType(s); err != nil {
+				return u, domain.ErrBadParams
+			}
+			u.MIME = &s
+		case "public":
+			var b bool
+			if isNull || json.Unmarshal(raw, &b) != nil {
+				return u, domain.ErrBadParams
+			}
+			u.Public = &b
+		case "folder_id":
+			u.SetFolder = true
+			if isNull {
+				continue // в корень
+			}
+			var id uuid.UUID
+			if json.Unmarshal(raw, &id) != nil {
+				return u, domain.ErrBadParams
+			}
+			u.FolderID = &id
+		case "tags":
+			u.SetTags = true
+			if isNull {
+				continue // все теги снимаются
+			}
+			var tags []string
+			if json.Unmarshal(raw, &tags) != nil {
+				return u, domain.ErrBadParams
+			}
+			norm, ok := domain.NormalizeTags(tags)
+			if !ok {
+				return u, domain.ErrBadParams
+			}
+			u.Tags = norm
+		case "attrs":
+			u.SetAttrs = true
+			if isNull {
+				continue // все атрибуты удаляются
+			}
+			var p map[string]json.RawMessage
+			if json.Unmarshal(raw, &p) != nil || p == nil {
+				return u, domain.ErrBadParams
+			}
+			attrs, err := patchAttrs(d.Attrs, p)
+			if err != nil {
+				return u, err
+			}
+			u.Attrs = attrs
+		case "json":
+			u.SetJSON = true
+			if isNull {
+				continue // тело удаляется целиком
+			}
+			var p map[string]any
+			if json.Unmarshal(raw, &p) != nil || p == nil {
+				return u, domain.ErrBadParams // тело документа — только объект
+			}
+			target := map[string]any(current)
+			if target == nil {
+				target = map[string]any{}
+			}
+			u.JSON = domain.DocJSON(mergePatch(target, p).(map[string]any))
+		default:
+			return u, domain.ErrBadParams
+		}
+	}
+	return u, nil
+}
+
+func sameFolder(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// mergePatch — RFC 7396: null удаляет ключ, объекты сливаются рекурсивно,
+// остальное заменяется целиком.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// matchETag: If-Match — список ETag через запятую или "*".
+func matchETag(header, etag string) bool {
+	for _, c := range strings.Split(header, ",") {
+		if c = strings.TrimSpace(c); c == "*" || c == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package doc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWeakETag(t *testing.T) {
+	sha := []byte{0xde, 0xad, 0xbe, 0xef, 0x01, 0x02, 0x03, 0x04, 0x05}
+	tests := []struct {
+		name    string
+		version int64
+		sha     []byte
+		want    string
+	}{
+		{"префикс из 8 hex", 3, sha, `W/"3-deadbeef"`},
+		{"короткий хеш целиком", 1, []byte{0xab}, `W/"1-ab"`},
+		{"без хеша (JSON)", 7, nil, `W/"7-"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weakETag(tt.version, tt.sha); got != tt.want {
+				t.Errorf("weakETag = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if weakETag(1, sha) == weakETag(2, sha) {
+		t.Error("version must change the ETag")
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	const etag = `W/"3-deadbeef"`
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"совпадает", etag, true},
+		{"звёздочка", "*", true},
+		{"в списке", `W/"2-00000000", ` + etag, true},
+		{"пробелы", "  " + etag + "  ", true},
+		{"другая версия", `W/"4-deadbeef"`, false},
+		{"сильный вместо слабого", `"3-deadbeef"`, false},
+		{"подстрока", `W/"3-deadbee"`, false},
+		{"пустой", "", false},
+		{"пустые элементы", ", ,", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchETag(tt.header, etag); got != tt.want {
+				t.Errorf("matchETag(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// Примеры из приложения A RFC 7396
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch, want any
+		mustJSON(t, tt.target, &target)
+		mustJSON(t, tt.patch, &patch)
+		mustJSON(t, tt.want, &want)
+		if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func mustJSON(t *testing.T, s string, v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		t.Fatalf("bad test JSON %s: %v", s, err)
+	}
+}
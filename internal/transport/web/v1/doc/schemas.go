@@ -0,0 +1,42 @@
+package doc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/google/uuid"
+)
+
+// resolveSchema: ссылка на схему — id или имя. По id видны свои и глобальные
+// схемы, по имени своя важнее глобальной; чужая личная схема — 404.
+func (h *Handler) resolveSchema(ctx context.Context, ref string, me domain.UserID) (domain.JSONSchema, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		s, err := h.Schemas.SchemaByID(ctx, id)
+		if err != nil {
+			return domain.JSONSchema{}, err
+		}
+		if !s.Global() && *s.OwnerID != me {
+			return domain.JSONSchema{}, domain.ErrNotFound
+		}
+		return s, nil
+	}
+	if !domain.ValidSchemaName(ref) {
+		return domain.JSONSchema{}, domain.ErrBadParams
+	}
+	return h.Schemas.SchemaByName(ctx, me, ref)
+}
+
+// checkSchema: JSON-тело против схемы документа. Схема уже привязанного
+// документа берётся без проверки доступа — её выбрал тот, кто привязал.
+func (h *Handler) checkSchema(ctx context.Context, id *uuid.UUID, body domain.DocJSON) error {
+	if id == nil {
+		return nil
+	}
+	s, err := h.Schemas.SchemaByID(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return h.Validator.Validate(s, body)
+}
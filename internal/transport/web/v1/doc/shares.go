@@ -0,0 +1,284 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Сколько логинов можно передать за один запрос
+const maxGrantsPerRequest = 100
+
+// grantIn: задаётся login или group.
+type grantIn struct {
+	Login      string `json:"login,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Permission string `json:"permission,omitempty"` // viewer (по умолчанию) | commenter | editor | co_owner
+}
+
+type addSharesRequest struct {
+	Grants []grantIn `json:"grants"`
+}
+
+// Результат по каждому логину/группе: неизвестные не теряются молча.
+type grantResult struct {
+	Login      string `json:"login,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"` // user_not_found | group_not_found | bad_target | bad_permission | is_owner | failed
+}
+
+type sharesResponse struct {
+	Results []grantResult `json:"results"`
+}
+
+// shareTarget: общий пролог ручек шаринга — пользователь, скоуп, документ и право co_owner.
+func (h *Handler) shareTarget(r *http.Request, scope string) (domain.User, domain.Document, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.Document{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), scope) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrBadParams
+	}
+	d, _, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrNotFound
+	}
+	if !domain.PermAllows(d.Permission, domain.PermCoOwner) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	return me, d, nil
+}
+
+// ListShares godoc
+// @Summary     List document grants
+// @Description Доступно владельцу и co_owner.
+// @Tags        shares
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.DocShare}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/shares [get]
+func (h *Handler) ListShares(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.shares.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.shareTarget(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "share target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	grants, err := h.Shares.ListGrants(r.Context(), d.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list grants failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "count", len(grants))
+	v1.WriteOKData(w, r, grants)
+}
+
+// AddShares godoc
+// @Summary     Grant access to document
+// @Description Выдаёт/меняет права по логинам и группам. Результат — по каждому элементу отдельно.
+// @Description Доступно владельцу и co_owner; право owner выдать нельзя.
+// @Tags        shares
+// @Accept      json
+// @Produce     json
+// @Param       id      path string           true "document id"
+// @Param       request body addSharesRequest true "grants: [{login|group, permission}]"
+// @Success     200 {object} domain.APIEnvelope{response=sharesResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/shares [post]
+func (h *Handler) AddShares(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.shares.add"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.shareTarget(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "share target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req addSharesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if len(req.Grants) == 0 || len(req.Grants) > maxGrantsPerRequest {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "grants", len(req.Grants))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	// логин владельца — чтобы не заводить ему бессмысленный грант
+	ownerLogin := ""
+	if owner, err := h.Users.UserByID(r.Context(), d.OwnerID); err == nil {
+		ownerLogin = owner.Login
+	}
+
+	out := sharesResponse{Results: make([]grantResult, 0, len(req.Grants))}
+	var affected []domain.UserID
+	for _, g := range req.Grants {
+		login := strings.TrimSpace(g.Login)
+		group := strings.TrimSpace(g.Group)
+		perm := g.Permission
+		if perm == "" {
+			perm = domain.PermViewer
+		}
+		res := grantResult{Login: login, Group: group, Permission: perm}
+		switch {
+		case login != "" && group != "":
+			res.Error = "bad_target" // нужно что-то одно
+		case login == "" && group == "":
+			res.Error = "user_not_found"
+		case !domain.ValidGrantPermission(perm):
+			res.Error = "bad_permission"
+		case group != "":
+			// группе: права получают все её участники
+			members, err := h.Shares.UpsertGroupGrant(r.Context(), d.ID, group, perm)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				res.Error = "group_not_found"
+			case err != nil:
+				logx.Error(h.Log, reqID, op, "db upsert group grant failed", err, "doc_id", d.ID, "group", group)
+				res.Error = "failed"
+			default:
+				res.OK = true
+				affected = append(affected, members...)
+			}
+		case login == ownerLogin:
+			res.Error = "is_owner"
+		default:
+			uid, err := h.Shares.UpsertGrant(r.Context(), d.ID, login, perm)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				res.Error = "user_not_found"
+			case err != nil:
+				logx.Error(h.Log, reqID, op, "db upsert grant failed", err, "doc_id", d.ID, "login", login)
+				res.Error = "failed"
+			default:
+				res.OK = true
+				affected = append(affected, uid)
+			}
+		}
+		out.Results = append(out.Results, res)
+	}
+
+	h.bumpLists(r.Context(), affected...)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "requested", len(req.Grants), "affected_users", len(affected))
+	v1.WriteOKResponse(w, r, out)
+}
+
+// RevokeShare godoc
+// @Summary     Revoke access to document
+// @Description Доступно владельцу и co_owner.
+// @Tags        shares
+// @Produce     json
+// @Param       id    path string true "document id"
+// @Param       login path string true "user login"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/shares/{login} [delete]
+func (h *Handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.shares.revoke"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.shareTarget(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "share target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	login := r.PathValue("login")
+	uid, err := h.Shares.RemoveGrant(r.Context(), d.ID, login)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db remove grant failed", err, "doc_id", d.ID, "login", login)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	h.bumpLists(r.Context(), uid)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "login", login)
+	v1.WriteOKResponse(w, r, map[string]bool{login: true})
+}
+
+// RevokeGroupShare godoc
+// @Summary     Revoke group access to document
+// @Description Доступно владельцу и co_owner.
+// @Tags        shares
+// @Produce     json
+// @Param       id    path string true "document id"
+// @Param       group path string true "group name"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/shares/groups/{group} [delete]
+func (h *Handler) RevokeGroupShare(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.shares.revoke_group"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.shareTarget(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "share target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	group := r.PathValue("group")
+	members, err := h.Shares.RemoveGroupGrant(r.Context(), d.ID, group)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db remove group grant failed", err, "doc_id", d.ID, "group", group)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	h.bumpLists(r.Context(), members...)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "group", group, "members", len(members))
+	v1.WriteOKResponse(w, r, map[string]bool{group: true})
+}
@@ -0,0 +1,259 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Сколько ждёт согласия запрос на передачу владения
+const transferTTL = 7 * 24 * time.Hour
+
+type transferRequest struct {
+	To         string `json:"to"`                    // логин нового владельца
+	KeepEditor bool   `json:"keep_editor,omitempty"` // оставить себе право editor
+}
+
+// RequestTransfer godoc
+// @Summary     Request document ownership transfer
+// @Description Только владелец. Документ переходит к получателю после его согласия (POST /api/transfers/{id}/accept).
+// @Description Новый запрос по тому же документу заменяет прежний; запрос действует 7 дней.
+// @Tags        transfers
+// @Accept      json
+// @Produce     json
+// @Param       id      path string          true "document id"
+// @Param       request body transferRequest true "to, keep_editor"
+// @Success     200 {object} domain.APIEnvelope{data=domain.DocTransfer}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/transfer [post]
+func (h *Handler) RequestTransfer(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.transfer.request"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "unauthorized: no user in ctx", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsShare) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsShare)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad doc id", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	var req transferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	login := strings.TrimSpace(req.To)
+	if login == "" || login == me.Login {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "to", login)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	d, _, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc not found or no access", err, "doc_id", docID)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+	// передать владение может только владелец, не co_owner
+	if d.Permission != domain.PermOwner {
+		logx.Error(h.Log, reqID, op, "not an owner", domain.ErrForbidden, "user_id", me.ID, "doc_id", d.ID, "perm", d.Permission)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	to, err := h.Users.UserByLogin(r.Context(), login)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "recipient not found", err, "to", login)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+	if to.Disabled() {
+		logx.Error(h.Log, reqID, op, "recipient disabled", domain.ErrConflict, "to", login)
+		v1.WriteDomainError(w, r, domain.ErrConflict)
+		return
+	}
+
+	t, err := h.Transfers.CreateTransfer(r.Context(), domain.DocTransfer{
+		DocID:      d.ID,
+		DocName:    d.Name,
+		FromUserID: me.ID,
+		From:       me.Login,
+		ToUserID:   to.ID,
+		To:         to.Login,
+		KeepEditor: req.KeepEditor,
+		ExpiresAt:  time.Now().Add(transferTTL),
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create transfer failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "transfer_id", t.ID, "to", to.Login)
+	v1.WriteOKData(w, r, t)
+}
+
+// ListTransfers godoc
+// @Summary     List pending ownership transfers
+// @Description Входящие и исходящие действующие запросы пользователя.
+// @Tags        transfers
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.DocTransfer}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/transfers [get]
+func (h *Handler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.transfer.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "unauthorized: no user in ctx", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsRead) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsRead)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	ts, err := h.Transfers.ListTransfers(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list transfers failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(ts))
+	v1.WriteOKData(w, r, ts)
+}
+
+// AcceptTransfer godoc
+// @Summary     Accept ownership transfer
+// @Description Только получатель. Документ (с файлом и учётом места) переходит к нему; прежний владелец
+// @Description получает editor, если это было указано в запросе.
+// @Tags        transfers
+// @Produce     json
+// @Param       id path string true "transfer id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/transfers/{id}/accept [post]
+func (h *Handler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.transfer.accept"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, id, err := transferTarget(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "transfer target rejected", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	mv, err := h.Transfers.AcceptTransfer(r.Context(), id, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db accept failed", err, "transfer_id", id)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+		case errors.Is(err, domain.ErrConflict):
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+
+	if err := InvalidateOwnership(r.Context(), h.Cache, mv); err != nil {
+		logx.Error(h.Log, reqID, op, "cache invalidation failed", err, "transfer_id", id)
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "transfer_id", id, "from", mv.From, "docs", len(mv.Docs))
+	v1.WriteOKResponse(w, r, map[string]bool{id.String(): true})
+}
+
+// CancelTransfer godoc
+// @Summary     Cancel or decline ownership transfer
+// @Description Отправитель отменяет запрос, получатель — отклоняет.
+// @Tags        transfers
+// @Produce     json
+// @Param       id path string true "transfer id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/transfers/{id} [delete]
+func (h *Handler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.transfer.cancel"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, id, err := transferTarget(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "transfer target rejected", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	if err := h.Transfers.CancelTransfer(r.Context(), id, me.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "db cancel failed", err, "transfer_id", id)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "transfer_id", id)
+	v1.WriteOKResponse(w, r, map[string]bool{id.String(): true})
+}
+
+// transferTarget: пользователь со скоупом docs:share и id запроса из пути.
+func transferTarget(r *http.Request) (domain.User, uuid.UUID, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, uuid.Nil, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsShare) {
+		return domain.User{}, uuid.Nil, domain.ErrForbidden
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, uuid.Nil, domain.ErrBadParams
+	}
+	return me, id, nil
+}
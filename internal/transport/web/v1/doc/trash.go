@@ -0,0 +1,160 @@
+package doc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// ListTrash godoc
+// @Summary     List trashed documents
+// @Description Удалённые документы, которыми пользователь может управлять (владелец и co_owner), от новых к старым.
+// @Description Через TRASH_RETENTION после удаления документ удаляется окончательно.
+// @Tags        trash
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.Document}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/trash [get]
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.trash.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		logx.Error(h.Log, reqID, op, "unauthorized: no user in ctx", domain.ErrUnauth)
+		v1.WriteDomainError(w, r, domain.ErrUnauth)
+		return
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsRead) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsRead)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	docs, err := h.Trash.ListTrash(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list trash failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(docs))
+	v1.WriteOKData(w, r, docs)
+}
+
+// RestoreTrash godoc
+// @Summary     Restore document from trash
+// @Description Владелец и co_owner. Документ возвращается с прежними грантами, ревизиями и JSON; 409 — в папке с unique_names уже есть документ с таким именем.
+// @Tags        trash
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Document}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/trash/{id}/restore [post]
+func (h *Handler) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.trash.restore"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, docID, err := trashTarget(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "trash target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	d, err := h.Trash.RestoreDoc(r.Context(), docID, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db restore failed", err, "doc_id", docID)
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrConflict) {
+			v1.WriteDomainError(w, r, err)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// документ снова виден в списках владельца, получателей грантов и (если public) анонимов
+	affected := []domain.UserID{me.ID, d.OwnerID}
+	if audience, err := h.Shares.DocAudience(r.Context(), d.ID); err == nil {
+		affected = append(affected, audience...)
+	} else {
+		logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", d.ID)
+	}
+	h.bumpLists(r.Context(), affected...)
+	if d.Public {
+		h.bumpAnonLists(r.Context())
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID)
+	v1.WriteOKData(w, r, d)
+}
+
+// PurgeTrash godoc
+// @Summary     Permanently delete trashed document
+// @Description Владелец и co_owner. Удаляются документ, гранты, ревизии и контент (если на него больше никто не ссылается).
+// @Tags        trash
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/trash/{id} [delete]
+func (h *Handler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.trash.purge"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, docID, err := trashTarget(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "trash target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	keys, err := h.Trash.PurgeDoc(r.Context(), docID, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db purge failed", err, "doc_id", docID)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// строки удалены — теперь можно чистить storage
+	h.dropBlobs(r.Context(), keys...)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", docID, "keys", len(keys))
+	v1.WriteOKResponse(w, r, map[string]bool{docID.String(): true})
+}
+
+// trashTarget: пользователь со скоупом docs:write и id документа из пути.
+func trashTarget(r *http.Request) (domain.User, domain.DocID, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, uuid.Nil, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsWrite) {
+		return domain.User{}, uuid.Nil, domain.ErrForbidden
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, uuid.Nil, domain.ErrBadParams
+	}
+	return me, id, nil
+}
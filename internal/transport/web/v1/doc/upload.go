@@ -3,12 +3,14 @@ package doc
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/EgorLis/my-docs/internal/transport/web/logx"
 	"github.com/EgorLis/my-docs/internal/transport/web/mw"
 	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
 )
 
 // MetaDTO описывает meta JSON.
@@ -19,11 +21,22 @@ type MetaDTO struct {
 	Token  string   `json:"token"` // игнорируем
 	Mime   string   `json:"mime"`
 	Grant  []string `json:"grant"`
+	// Папка (право editor и выше); пусто — корень
+	FolderID *uuid.UUID `json:"folder_id"`
+	// Теги и атрибуты: {"project": {"type": "string", "value": "apollo"}};
+	// type string/number выводится из значения, date — YYYY-MM-DD
+	Tags  []string                  `json:"tags"`
+	Attrs map[string]domain.DocAttr `json:"attrs"`
+	// JSON-схема (id или имя): json проверяется по ней, иначе 422
+	Schema string `json:"schema"`
 }
 
 // Upload godoc
 // @Summary     Upload new document
 // @Description multipart/form-data: meta(JSON), json(JSON, optional), file(binary, optional)
+// @Description meta.folder_id — папка (право editor); в папке с unique_names имя занято — 409.
+// @Description meta.tags и meta.attrs — теги и типизированные атрибуты (string|number|date).
+// @Description meta.schema — JSON-схема (id или имя); json не соответствует ей — 422 с путями ошибок в error.details.
 // @Tags        docs
 // @Accept      multipart/form-data
 // @Produce     json
@@ -34,6 +47,10 @@ type MetaDTO struct {
 // @Success     200 {object} domain.APIEnvelope{data=object}
 // @Failure     400 {object} domain.APIEnvelope
 // @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Failure     422 {object} domain.APIEnvelope
 // @Failure     500 {object} domain.APIEnvelope
 // @Router      /api/docs [post]
 func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +70,11 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		v1.WriteDomainError(w, r, domain.ErrUnauth)
 		return
 	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsWrite) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsWrite)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
 	if err := r.ParseMultipartForm(64 << 20); err != nil {
 		logx.Error(h.Log, reqID, op, "parse multipart failed", err)
 		v1.WriteDomainError(w, r, domain.ErrBadParams)
@@ -68,6 +90,36 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// выдача доступа другим — отдельный скоуп
+	if len(metaIn.Grant) > 0 && !mw.HasScope(r.Context(), domain.ScopeDocsShare) {
+		logx.Error(h.Log, reqID, op, "missing scope", domain.ErrForbidden, "scope", domain.ScopeDocsShare)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	// теги и атрибуты проверяются до загрузки файла в storage
+	tags, ok := domain.NormalizeTags(metaIn.Tags)
+	if !ok {
+		logx.Error(h.Log, reqID, op, "bad tags", domain.ErrBadParams, "tags", len(metaIn.Tags))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	attrs, err := normalizeAttrs(metaIn.Attrs)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad attrs", err, "attrs", len(metaIn.Attrs))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	// папка проверяется до загрузки файла в storage
+	if metaIn.FolderID != nil {
+		if err := h.folderTarget(r.Context(), *metaIn.FolderID, me.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "folder rejected", err, "folder_id", *metaIn.FolderID)
+			v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+			return
+		}
+	}
+
 	// json — опциональный документ
 	var jsonBody domain.DocJSON
 	if js := r.FormValue("json"); js != "" {
@@ -78,6 +130,23 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// схема проверяется до загрузки файла в storage
+	var schemaID *uuid.UUID
+	if metaIn.Schema != "" {
+		sch, err := h.resolveSchema(r.Context(), metaIn.Schema, me.ID)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "schema rejected", err, "schema", metaIn.Schema)
+			v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+			return
+		}
+		if err := h.Validator.Validate(sch, jsonBody); err != nil {
+			logx.Error(h.Log, reqID, op, "json does not match schema", err, "schema_id", sch.ID)
+			v1.WriteDomainError(w, r, err) // 422 с error.details
+			return
+		}
+		schemaID = &sch.ID
+	}
+
 	// file — опционально
 	var (
 		filename   string
@@ -129,20 +198,50 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		SizeBytes:  size,
 		StorageKey: storageKey,
 		SHA256:     shaSum,
+		FolderID:   metaIn.FolderID,
+		Tags:       tags,
+		Attrs:      attrs,
+		SchemaID:   schemaID,
 	}, jsonBody)
 	if err != nil {
 		logx.Error(h.Log, reqID, op, "db create doc failed", err, "name", metaIn.Name, "mime", mime, "file", metaIn.File)
+		if errors.Is(err, domain.ErrConflict) {
+			// имя занято в папке: файл уже в storage — убираем, если он ничей
+			if storageKey != "" {
+				h.dropBlobs(r.Context(), storageKey)
+			}
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+			return
+		}
 		v1.WriteDomainError(w, r, domain.ErrUnexpected)
 		return
 	}
 
-	// шаринг (grant)
+	// шаринг (grant) — при загрузке выдаём только чтение
+	affected := []domain.UserID{me.ID}
 	for _, login := range metaIn.Grant {
-		_ = h.Shares.UpsertReadGrant(r.Context(), doc.ID, login, true)
+		uid, err := h.Shares.UpsertGrant(r.Context(), doc.ID, login, domain.PermViewer)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "grant failed", err, "doc_id", doc.ID, "login", login)
+			continue
+		}
+		affected = append(affected, uid)
 	}
 
-	// инвалидация кэша списков владельца
-	_ = h.Cache.Del(r.Context(), domain.CacheKeyDocList(me.ID.String(), "*"))
+	// документ в папке виден её владельцу и получателям грантов на папку
+	if doc.FolderID != nil {
+		if audience, err := h.Shares.DocAudience(r.Context(), doc.ID); err == nil {
+			affected = append(affected, audience...)
+		} else {
+			logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", doc.ID)
+		}
+	}
+
+	// инвалидация кэша списков владельца и получивших доступ
+	h.bumpLists(r.Context(), affected...)
+	if doc.Public {
+		h.bumpAnonLists(r.Context())
+	}
 
 	// ответ по ТЗ
 	out := map[string]any{"json": jsonBody}
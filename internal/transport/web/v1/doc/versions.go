@@ -0,0 +1,377 @@
+package doc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Ответ на замену контента и откат: документ и новая текущая ревизия
+type revisionResponse struct {
+	Doc      domain.Document    `json:"doc"`
+	Revision domain.DocRevision `json:"revision"`
+}
+
+// PutContent godoc
+// @Summary     Upload new content revision
+// @Description Тело запроса — новый файл (макс. 1ГБ), MIME — из Content-Type (пусто — прежний).
+// @Description id, гранты и ссылки сохраняются; прежний контент остаётся в истории ревизий.
+// @Description Обязателен If-Match с текущим ETag (412 при несовпадении, 428 без него). Право editor.
+// @Description Только для документов-файлов: у JSON-документа — 409.
+// @Tags        versions
+// @Accept      application/octet-stream
+// @Produce     json
+// @Param       id       path   string true "document id"
+// @Param       If-Match header string true "current ETag"
+// @Success     200 {object} domain.APIEnvelope{data=revisionResponse}
+// @Header      200 {string} ETag "new ETag"
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Failure     412 {object} domain.APIEnvelope
+// @Failure     428 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/content [put]
+func (h *Handler) PutContent(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.content.put"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.revisionDoc(r, domain.ScopeDocsWrite, domain.PermEditor)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if err := checkIfMatch(w, r, d); err != nil {
+		logx.Error(h.Log, reqID, op, "precondition failed", err, "doc_id", d.ID, "if_match", r.Header.Get("If-Match"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	// JSON-документ файлом не становится: иначе рядом с новым контентом осталось бы
+	// прежнее JSON-тело и его схема
+	if !d.File {
+		logx.Error(h.Log, reqID, op, "not a file document", domain.ErrConflict, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrConflict)
+		return
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = d.MIME
+	} else if _, _, err := mime.ParseMediaType(ct); err != nil {
+		logx.Error(h.Log, reqID, op, "bad content type", err, "content_type", ct)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	res, err := h.Storage.Put(r.Context(), r.Body, d.Name, ct)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "storage put failed", err, "doc_id", d.ID, "mime", ct)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	upd, rev, err := h.Revisions.AddRevision(r.Context(), d.ID, d.Version, domain.DocRevision{
+		StorageKey: res.StorageKey,
+		SizeBytes:  res.Size,
+		SHA256:     res.SHA256,
+		MIME:       ct,
+		AuthorID:   &me.ID,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db add revision failed", err, "doc_id", d.ID)
+		// загруженный объект никому не нужен (если такой контент не лежал раньше)
+		h.dropBlobs(r.Context(), res.StorageKey)
+		if errors.Is(err, domain.ErrPrecondition) {
+			v1.WriteDomainError(w, r, domain.ErrPrecondition)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	rev.Author = me.Login
+
+	h.afterRevision(r.Context(), op, me, d, upd)
+	upd.Permission = d.Permission
+
+	w.Header().Set("ETag", weakETag(upd.Version, upd.SHA256))
+	w.Header().Set("Last-Modified", httpTime(upd.UpdatedAt))
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "revision", rev.Revision, "size", rev.SizeBytes)
+	v1.WriteOKData(w, r, revisionResponse{Doc: upd, Revision: rev})
+}
+
+// ListVersions godoc
+// @Summary     List content revisions
+// @Description От новых к старым; current — текущая ревизия. Доступно всем, кто видит документ.
+// @Tags        versions
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.DocRevision}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/versions [get]
+func (h *Handler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.versions.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.revisionDoc(r, domain.ScopeDocsRead, domain.PermViewer)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	revs, err := h.Revisions.ListRevisions(r.Context(), d.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list revisions failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "count", len(revs))
+	v1.WriteOKData(w, r, revs)
+}
+
+// GetVersion godoc
+// @Summary     Download content revision
+// @Description Контент ревизии с поддержкой Range и HEAD. Доступно всем, кто видит документ.
+// @Tags        versions
+// @Produce     octet-stream
+// @Param       id  path string true "document id"
+// @Param       rev path int    true "revision number"
+// @Success     200 {file}  []byte
+// @Success     206 {file}  []byte
+// @Header      200 {string} ETag "SHA256 контента ревизии"
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/versions/{rev} [get]
+func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.versions.get"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	_, d, err := h.revisionDoc(r, domain.ScopeDocsRead, domain.PermViewer)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	num, err := revisionNumber(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad revision", err, "rev_raw", r.PathValue("rev"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	rev, err := h.Revisions.RevisionByNumber(r.Context(), d.ID, num)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db revision failed", err, "doc_id", d.ID, "revision", num)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// контент ревизии неизменен — сильный ETag по хэшу
+	etag := `"` + rev.Checksum + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", httpTime(rev.CreatedAt))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("Accept-Ranges", "bytes")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
+		w.WriteHeader(http.StatusNotModified)
+		logx.Info(h.Log, reqID, op, "not modified by etag", "doc_id", d.ID, "revision", num)
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", rev.MIME)
+		w.Header().Set("Content-Length", strconv.FormatInt(rev.SizeBytes, 10))
+		w.WriteHeader(http.StatusOK)
+		logx.Info(h.Log, reqID, op, "head ok", "doc_id", d.ID, "revision", num)
+		return
+	}
+
+	rangeHdr := r.Header.Get("Range")
+	rc, contentLen, contentRange, _, _, err := h.Storage.Get(r.Context(), rev.StorageKey, rangeHdr)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "storage get failed", err, "doc_id", d.ID, "revision", num, "range", rangeHdr)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	defer rc.Close()
+
+	// MIME — ревизии: объект с тем же контентом мог быть загружен с другим типом
+	w.Header().Set("Content-Type", rev.MIME)
+	w.Header().Set("Content-Length", strconv.FormatInt(contentLen, 10))
+	if contentRange != "" {
+		w.Header().Set("Content-Range", contentRange)
+		w.WriteHeader(http.StatusPartialContent)
+		logx.Info(h.Log, reqID, op, "partial content", "doc_id", d.ID, "revision", num, "range", contentRange, "len", contentLen)
+	} else {
+		w.WriteHeader(http.StatusOK)
+		logx.Info(h.Log, reqID, op, "file ok", "doc_id", d.ID, "revision", num, "len", contentLen)
+	}
+	_, _ = io.Copy(w, rc)
+}
+
+// RestoreVersion godoc
+// @Summary     Restore content revision
+// @Description Копия указанной ревизии становится новой текущей ревизией (история не переписывается).
+// @Description Обязателен If-Match с текущим ETag (412 при несовпадении, 428 без него). Право editor.
+// @Description Только для документов-файлов: у JSON-документа — 409.
+// @Tags        versions
+// @Produce     json
+// @Param       id       path   string true "document id"
+// @Param       rev      path   int    true "revision number"
+// @Param       If-Match header string true "current ETag"
+// @Success     200 {object} domain.APIEnvelope{data=revisionResponse}
+// @Header      200 {string} ETag "new ETag"
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     412 {object} domain.APIEnvelope
+// @Failure     428 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/versions/{rev}/restore [post]
+func (h *Handler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	const op = "docs.versions.restore"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.revisionDoc(r, domain.ScopeDocsWrite, domain.PermEditor)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	num, err := revisionNumber(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad revision", err, "rev_raw", r.PathValue("rev"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if err := checkIfMatch(w, r, d); err != nil {
+		logx.Error(h.Log, reqID, op, "precondition failed", err, "doc_id", d.ID, "if_match", r.Header.Get("If-Match"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	upd, rev, err := h.Revisions.RestoreRevision(r.Context(), d.ID, d.Version, num, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db restore failed", err, "doc_id", d.ID, "revision", num)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+		case errors.Is(err, domain.ErrPrecondition):
+			v1.WriteDomainError(w, r, domain.ErrPrecondition)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+	rev.Author = me.Login
+
+	h.afterRevision(r.Context(), op, me, d, upd)
+	upd.Permission = d.Permission
+
+	w.Header().Set("ETag", weakETag(upd.Version, upd.SHA256))
+	w.Header().Set("Last-Modified", httpTime(upd.UpdatedAt))
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "from", num, "revision", rev.Revision)
+	v1.WriteOKData(w, r, revisionResponse{Doc: upd, Revision: rev})
+}
+
+// revisionDoc: пользователь со скоупом scope и документ из пути, на который у него есть право need.
+// Нет документа или доступа — ErrNotFound.
+func (h *Handler) revisionDoc(r *http.Request, scope string, need domain.Permission) (domain.User, domain.Document, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.Document{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), scope) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrBadParams
+	}
+	d, _, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrNotFound
+	}
+	if !domain.PermAllows(d.Permission, need) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	return me, d, nil
+}
+
+// checkIfMatch: If-Match обязателен и должен совпасть с текущим ETag документа.
+// При несовпадении клиенту отдаётся актуальный ETag.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, d domain.Document) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return domain.ErrPreconditionReq
+	}
+	if etag := weakETag(d.Version, d.SHA256); !matchETag(ifMatch, etag) {
+		w.Header().Set("ETag", etag)
+		return domain.ErrPrecondition
+	}
+	return nil
+}
+
+func revisionNumber(r *http.Request) (int, error) {
+	n, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil || n <= 0 {
+		return 0, domain.ErrBadParams
+	}
+	return n, nil
+}
+
+// afterRevision: чистка ревизий сверх лимита и осиротевших объектов, сброс кеша.
+// Ошибки только логируются — ревизия уже сохранена.
+func (h *Handler) afterRevision(ctx context.Context, op string, me domain.User, before, after domain.Document) {
+	reqID := mw.RequestIDFromCtx(ctx)
+
+	if pruned, err := h.Revisions.PruneRevisions(ctx, after.ID, h.KeepRevisions); err != nil {
+		logx.Error(h.Log, reqID, op, "prune revisions failed", err, "doc_id", after.ID)
+	} else if len(pruned) > 0 {
+		h.dropBlobs(ctx, pruned...)
+		logx.Info(h.Log, reqID, op, "revisions pruned", "doc_id", after.ID, "count", len(pruned))
+	}
+	// прежний текущий объект мог остаться без ссылок (ревизия вычищена или её не было)
+	if before.StorageKey != "" && before.StorageKey != after.StorageKey {
+		h.dropBlobs(ctx, before.StorageKey)
+	}
+
+	// мета (ETag, размер, mime) в кеше устарела; размер и mime видны в списках
+	if err := h.Cache.Del(ctx, domain.CacheKeyDocMeta(after.ID), domain.CacheKeyDocJSON(after.ID)); err != nil {
+		logx.Error(h.Log, reqID, op, "cache del failed", err, "doc_id", after.ID)
+	}
+	affected := []domain.UserID{me.ID, after.OwnerID}
+	if audience, err := h.Shares.DocAudience(ctx, after.ID); err == nil {
+		affected = append(affected, audience...)
+	} else {
+		logx.Error(h.Log, reqID, op, "doc audience failed", err, "doc_id", after.ID)
+	}
+	h.bumpLists(ctx, affected...)
+	if after.Public {
+		h.bumpAnonLists(ctx)
+	}
+}
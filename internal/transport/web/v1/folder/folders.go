@@ -0,0 +1,410 @@
+package folder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+	"github.com/google/uuid"
+)
+
+type createRequest struct {
+	Name        string     `json:"name"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"` // пусто — в корне
+	UniqueNames bool       `json:"unique_names,omitempty"`
+}
+
+// Папка и путь к ней (хлебные крошки, от корня к самой папке)
+type folderResponse struct {
+	Folder domain.Folder        `json:"folder"`
+	Path   []domain.FolderCrumb `json:"path"`
+}
+
+// Create godoc
+// @Summary     Create folder
+// @Description Папка в корне или внутри своей папки. Имя — без "/", уникально среди соседних папок (409).
+// @Description unique_names — имена документов внутри папки должны быть уникальны.
+// @Tags        folders
+// @Accept      json
+// @Produce     json
+// @Param       request body createRequest true "name, parent_id, unique_names"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Folder}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/folders [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsWrite)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if !domain.ValidFolderName(name) {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "name", name)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.ParentID != nil {
+		if err := h.ownParent(r.Context(), *req.ParentID, me.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "parent rejected", err, "parent_id", *req.ParentID)
+			v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+			return
+		}
+	}
+
+	f, err := h.Folders.CreateFolder(r.Context(), domain.Folder{
+		OwnerID: me.ID, ParentID: req.ParentID, Name: name, UniqueNames: req.UniqueNames,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "user_id", me.ID, "name", name)
+		if errors.Is(err, domain.ErrConflict) {
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	f.Permission = domain.PermOwner
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "name", f.Name)
+	v1.WriteOKData(w, r, f)
+}
+
+// List godoc
+// @Summary     List folders
+// @Description Без parent — свои корневые папки и папки, расшаренные пользователю; с parent — подпапки.
+// @Tags        folders
+// @Produce     json
+// @Param       parent query string false "parent folder id"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.Folder}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var parent *uuid.UUID
+	if s := r.URL.Query().Get("parent"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			logx.Error(h.Log, reqID, op, "bad parent id", err, "parent_raw", s)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+		if _, err := h.Folders.FolderByID(r.Context(), id, me.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "parent not found or no access", err, "parent_id", id)
+			v1.WriteDomainError(w, r, err)
+			return
+		}
+		parent = &id
+	}
+
+	folders, err := h.Folders.ListFolders(r.Context(), me.ID, parent)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(folders))
+	v1.WriteOKData(w, r, folders)
+}
+
+// Get godoc
+// @Summary     Get folder with breadcrumbs
+// @Description Путь начинается с корня владельца; у получателя гранта — с верхней доступной ему папки.
+// @Tags        folders
+// @Produce     json
+// @Param       id path string true "folder id"
+// @Success     200 {object} domain.APIEnvelope{data=folderResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.get"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsRead, false)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+	h.writeWithPath(w, r, op, me, f)
+}
+
+// Lookup godoc
+// @Summary     Find own folder by path
+// @Description Путь из имён папок от своего корня: /Проекты/2025/Отчёты. Расшаренные папки — по id.
+// @Tags        folders
+// @Produce     json
+// @Param       path query string true "folder path"
+// @Success     200 {object} domain.APIEnvelope{data=folderResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders/lookup [get]
+func (h *Handler) Lookup(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.lookup"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	raw := r.URL.Query().Get("path")
+	var names []string
+	for _, seg := range strings.Split(raw, "/") {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			names = append(names, seg)
+		}
+	}
+	if len(names) == 0 {
+		logx.Error(h.Log, reqID, op, "empty path", domain.ErrBadParams, "path_raw", raw)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	f, err := h.Folders.FolderByPath(r.Context(), me.ID, names)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder not found", err, "path_raw", raw)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	h.writeWithPath(w, r, op, me, f)
+}
+
+func (h *Handler) writeWithPath(w http.ResponseWriter, r *http.Request, op string, me domain.User, f domain.Folder) {
+	reqID := mw.RequestIDFromCtx(r.Context())
+	path, err := h.Folders.FolderPath(r.Context(), f.ID, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db path failed", err, "folder_id", f.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "depth", len(path))
+	v1.WriteOKData(w, r, folderResponse{Folder: f, Path: path})
+}
+
+// Update godoc
+// @Summary     Rename or move folder
+// @Description Merge patch поверх {"name","parent_id","unique_names"}: parent_id — новая родительская папка
+// @Description (своя; null — в корень). Только владелец. 409 — имя занято, перенос внутрь самой себя,
+// @Description или unique_names, когда в папке уже есть одноимённые документы.
+// @Tags        folders
+// @Accept      json
+// @Produce     json
+// @Param       id      path string true "folder id"
+// @Param       request body object true "merge patch"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Folder}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/folders/{id} [patch]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.update"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsWrite, true)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	u, err := parseUpdate(patch)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "validation failed", err, "folder_id", f.ID)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if u.Move && u.ParentID != nil {
+		if err := h.ownParent(r.Context(), *u.ParentID, me.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "parent rejected", err, "parent_id", *u.ParentID)
+			v1.WriteDomainError(w, r, err)
+			return
+		}
+	}
+
+	// перенос меняет унаследованные права: кто видел содержимое на старом месте
+	var before []domain.UserID
+	if u.Move {
+		before = h.audience(r.Context(), f.ID)
+	}
+
+	upd, err := h.Folders.UpdateFolder(r.Context(), f.ID, u)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db update failed", err, "folder_id", f.ID)
+		switch {
+		case errors.Is(err, domain.ErrConflict):
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+		case errors.Is(err, domain.ErrNotFound):
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+	upd.Permission = f.Permission
+
+	if u.Move {
+		h.bumpLists(r.Context(), append(before, h.audience(r.Context(), f.ID)...)...)
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "moved", u.Move)
+	v1.WriteOKData(w, r, upd)
+}
+
+// parseUpdate: merge patch → FolderUpdate. Имя и unique_names удалить нельзя (null → ошибка).
+func parseUpdate(patch map[string]json.RawMessage) (domain.FolderUpdate, error) {
+	var u domain.FolderUpdate
+	for k, raw := range patch {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		switch k {
+		case "name":
+			var s string
+			if isNull || json.Unmarshal(raw, &s) != nil {
+				return u, domain.ErrBadParams
+			}
+			s = strings.TrimSpace(s)
+			if !domain.ValidFolderName(s) {
+				return u, domain.ErrBadParams
+			}
+			u.Name = &s
+		case "unique_names":
+			var b bool
+			if isNull || json.Unmarshal(raw, &b) != nil {
+				return u, domain.ErrBadParams
+			}
+			u.UniqueNames = &b
+		case "parent_id":
+			u.Move = true
+			if isNull {
+				continue // в корень
+			}
+			var id uuid.UUID
+			if json.Unmarshal(raw, &id) != nil {
+				return u, domain.ErrBadParams
+			}
+			u.ParentID = &id
+		default:
+			return u, domain.ErrBadParams
+		}
+	}
+	return u, nil
+}
+
+// Delete godoc
+// @Summary     Delete folder
+// @Description Только владелец. Непустая папка удаляется только с recursive=true (иначе 409):
+// @Description подпапки удаляются, документы поддерева уходят в корзину и восстанавливаются в корень.
+// @Tags        folders
+// @Produce     json
+// @Param       id        path  string true  "folder id"
+// @Param       recursive query bool   false "удалить вместе с содержимым"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/folders/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.delete"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsWrite, true)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	// после удаления папки и её грантов аудиторию уже не собрать
+	affected := h.audience(r.Context(), f.ID)
+
+	docs, err := h.Folders.DeleteFolder(r.Context(), f.ID, recursive)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db delete failed", err, "folder_id", f.ID)
+		switch {
+		case errors.Is(err, domain.ErrConflict):
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+		case errors.Is(err, domain.ErrNotFound):
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+
+	ids := make([]domain.DocID, 0, len(docs))
+	public := false
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+		public = public || d.Public
+	}
+	if err := doc.InvalidateDocs(r.Context(), h.Cache, ids...); err != nil {
+		logx.Error(h.Log, reqID, op, "cache del failed", err, "docs", len(ids))
+	}
+	h.bumpLists(r.Context(), append(affected, me.ID)...)
+	if public {
+		if err := doc.InvalidateAnonLists(r.Context(), h.Cache); err != nil {
+			logx.Error(h.Log, reqID, op, "bump anon list version failed", err)
+		}
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "trashed", len(docs))
+	v1.WriteOKResponse(w, r, map[string]bool{f.ID.String(): true})
+}
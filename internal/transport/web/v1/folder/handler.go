@@ -0,0 +1,82 @@
+package folder
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+	"github.com/google/uuid"
+)
+
+// Handler — папки документов: дерево, перенос, шаринг папки целиком.
+type Handler struct {
+	Log     *log.Logger
+	Folders domain.FoldersRepo
+	Cache   domain.Cache
+}
+
+// caller: читать папки можно со скоупом docs:read, менять — с docs:write,
+// шарить — с docs:share.
+func caller(r *http.Request, scope string) (domain.User, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), scope) {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return me, nil
+}
+
+// target: папка из пути с правами вызывающего. Недоступная папка выглядит
+// несуществующей; owner — менять папку может только владелец.
+func (h *Handler) target(r *http.Request, scope string, owner bool) (domain.User, domain.Folder, error) {
+	me, err := caller(r, scope)
+	if err != nil {
+		return domain.User{}, domain.Folder{}, err
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, domain.Folder{}, domain.ErrBadParams
+	}
+	f, err := h.Folders.FolderByID(r.Context(), id, me.ID)
+	if err != nil {
+		return domain.User{}, domain.Folder{}, err
+	}
+	if owner && f.Permission != domain.PermOwner {
+		return domain.User{}, domain.Folder{}, domain.ErrForbidden
+	}
+	return me, f, nil
+}
+
+// ownParent: новая папка или перенос — только внутрь своей же папки.
+func (h *Handler) ownParent(ctx context.Context, id uuid.UUID, me domain.UserID) error {
+	p, err := h.Folders.FolderByID(ctx, id, me)
+	if err != nil {
+		return err
+	}
+	if p.Permission != domain.PermOwner {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// bumpLists: права на папку и её место в дереве влияют на видимость документов.
+func (h *Handler) bumpLists(ctx context.Context, users ...domain.UserID) {
+	if err := doc.InvalidateLists(ctx, h.Cache, users...); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "folders.lists", "bump list version failed", err, "users", len(users))
+	}
+}
+
+// audience — для инвалидации; ошибка не критична (списки доживут свой TTL).
+func (h *Handler) audience(ctx context.Context, id uuid.UUID) []domain.UserID {
+	users, err := h.Folders.FolderAudience(ctx, id)
+	if err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "folders.lists", "folder audience failed", err, "folder_id", id)
+	}
+	return users
+}
@@ -0,0 +1,159 @@
+package folder
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+type shareRequest struct {
+	Login      string `json:"login"`
+	Permission string `json:"permission,omitempty"` // viewer (по умолчанию) | commenter | editor | co_owner
+}
+
+// ListShares godoc
+// @Summary     List folder grants
+// @Description Только владелец папки. Гранты предков здесь не показываются — они видны на самих предках.
+// @Tags        folders
+// @Produce     json
+// @Param       id path string true "folder id"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.FolderShare}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders/{id}/shares [get]
+func (h *Handler) ListShares(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.shares.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsRead, true)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+
+	grants, err := h.Folders.ListFolderGrants(r.Context(), f.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list grants failed", err, "folder_id", f.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "count", len(grants))
+	v1.WriteOKData(w, r, grants)
+}
+
+// AddShare godoc
+// @Summary     Grant access to folder
+// @Description Право действует на все документы папки и её подпапок, в том числе добавленные позже.
+// @Description Только владелец папки; право owner выдать нельзя.
+// @Tags        folders
+// @Accept      json
+// @Produce     json
+// @Param       id      path string       true "folder id"
+// @Param       request body shareRequest true "login, permission"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders/{id}/shares [post]
+func (h *Handler) AddShare(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.shares.add"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsShare, true)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	login := strings.TrimSpace(req.Login)
+	perm := req.Permission
+	if perm == "" {
+		perm = domain.PermViewer
+	}
+	// владельцу грант на свою папку не нужен
+	if login == "" || login == me.Login || !domain.ValidGrantPermission(perm) {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "login", login, "permission", perm)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	uid, err := h.Folders.UpsertFolderGrant(r.Context(), f.ID, login, perm)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db upsert grant failed", err, "folder_id", f.ID, "login", login)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// получателю становится видно содержимое папки
+	h.bumpLists(r.Context(), uid)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "login", login, "permission", perm)
+	v1.WriteOKResponse(w, r, map[string]bool{login: true})
+}
+
+// RevokeShare godoc
+// @Summary     Revoke folder grant
+// @Description Только владелец папки. Личные гранты на документы внутри папки остаются.
+// @Tags        folders
+// @Produce     json
+// @Param       id    path string true "folder id"
+// @Param       login path string true "user login"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/folders/{id}/shares/{login} [delete]
+func (h *Handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	const op = "folders.shares.revoke"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, f, err := h.target(r, domain.ScopeDocsShare, true)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "folder target rejected", err, "folder_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	login := r.PathValue("login")
+	uid, err := h.Folders.RemoveFolderGrant(r.Context(), f.ID, login)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db remove grant failed", err, "folder_id", f.ID, "login", login)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	h.bumpLists(r.Context(), uid)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "folder_id", f.ID, "login", login)
+	v1.WriteOKResponse(w, r, map[string]bool{login: true})
+}
@@ -0,0 +1,186 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+type createRequest struct {
+	Name string `json:"name"`
+}
+
+type groupResponse struct {
+	Group   domain.Group         `json:"group"`
+	Members []domain.GroupMember `json:"members"`
+}
+
+// Create godoc
+// @Summary     Create group
+// @Description Создатель становится администратором группы. Имя уникально: 3–64 символа [A-Za-z0-9_.-].
+// @Tags        groups
+// @Accept      json
+// @Produce     json
+// @Param       request body createRequest true "name"
+// @Success     200 {object} domain.APIEnvelope{data=domain.Group}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/groups [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if !domain.ValidGroupName(name) {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "name", name)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	g, err := h.Groups.CreateGroup(r.Context(), name, me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "user_id", me.ID, "name", name)
+		if errors.Is(err, domain.ErrConflict) {
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "group_id", g.ID, "name", g.Name)
+	v1.WriteOKData(w, r, g)
+}
+
+// List godoc
+// @Summary     List groups
+// @Description Группы, в которых состоит пользователь; администратор системы видит все.
+// @Tags        groups
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.Group}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/groups [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var member *domain.UserID
+	if !me.IsAdmin() {
+		member = &me.ID
+	}
+	groups, err := h.Groups.ListGroups(r.Context(), member)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(groups))
+	v1.WriteOKData(w, r, groups)
+}
+
+// Get godoc
+// @Summary     Get group with members
+// @Description Доступно участникам группы и администратору системы.
+// @Tags        groups
+// @Produce     json
+// @Param       id path string true "group id"
+// @Success     200 {object} domain.APIEnvelope{data=groupResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/groups/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.get"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	t, err := h.target(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "group target rejected", err, "group_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", t.me.ID, "group_id", t.group.ID, "members", len(t.members))
+	v1.WriteOKData(w, r, groupResponse{Group: t.group, Members: t.members})
+}
+
+// Delete godoc
+// @Summary     Delete group
+// @Description Доступно администратору группы и администратору системы. Групповые гранты удаляются вместе с группой.
+// @Tags        groups
+// @Produce     json
+// @Param       id path string true "group id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/groups/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.delete"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	t, err := h.target(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "group target rejected", err, "group_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+	if !t.manage {
+		logx.Error(h.Log, reqID, op, "not a group admin", domain.ErrForbidden, "user_id", t.me.ID, "group_id", t.group.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	members, err := h.Groups.DeleteGroup(r.Context(), t.group.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db delete failed", err, "group_id", t.group.ID)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// бывшие участники теряют доступ к документам группы
+	h.bumpLists(r.Context(), members...)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", t.me.ID, "group_id", t.group.ID, "members", len(members))
+	v1.WriteOKResponse(w, r, map[string]bool{t.group.ID.String(): true})
+}
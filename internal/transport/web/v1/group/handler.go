@@ -0,0 +1,92 @@
+package group
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	"github.com/EgorLis/my-docs/internal/transport/web/v1/doc"
+	"github.com/google/uuid"
+)
+
+// Handler — группы пользователей для шаринга документов командам.
+type Handler struct {
+	Log    *log.Logger
+	Groups domain.GroupsRepo
+	Cache  domain.Cache
+}
+
+// caller: читать группы можно со скоупом docs:read, менять — с docs:share.
+func caller(r *http.Request, scope string) (domain.User, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), scope) {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return me, nil
+}
+
+// target: группа из пути, её состав и права вызывающего в ней.
+// Чужая группа для не-участника выглядит несуществующей.
+type target struct {
+	me      domain.User
+	group   domain.Group
+	members []domain.GroupMember
+	manage  bool // администратор группы или системы
+}
+
+func (h *Handler) target(r *http.Request, scope string) (target, error) {
+	me, err := caller(r, scope)
+	if err != nil {
+		return target{}, err
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return target{}, domain.ErrBadParams
+	}
+	g, err := h.Groups.GroupByID(r.Context(), id)
+	if err != nil {
+		return target{}, err
+	}
+	members, err := h.Groups.GroupMembers(r.Context(), id)
+	if err != nil {
+		return target{}, err
+	}
+
+	t := target{me: me, group: g, members: members, manage: me.IsAdmin()}
+	member := me.IsAdmin()
+	for _, m := range members {
+		if m.UserID == me.ID {
+			member = true
+			t.manage = t.manage || m.Admin
+		}
+	}
+	if !member {
+		return target{}, domain.ErrNotFound
+	}
+	return t, nil
+}
+
+// lastAdmin: login — единственный администратор группы.
+func (t target) lastAdmin(login string) bool {
+	admins, isAdmin := 0, false
+	for _, m := range t.members {
+		if m.Admin {
+			admins++
+			isAdmin = isAdmin || m.Login == login
+		}
+	}
+	return isAdmin && admins == 1
+}
+
+// bumpLists: состав группы влияет на видимость документов — сбрасываем списки.
+func (h *Handler) bumpLists(ctx context.Context, users ...domain.UserID) {
+	if err := doc.InvalidateLists(ctx, h.Cache, users...); err != nil {
+		logx.Error(h.Log, mw.RequestIDFromCtx(ctx), "groups.lists", "bump list version failed", err, "users", len(users))
+	}
+}
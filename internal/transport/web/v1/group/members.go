@@ -0,0 +1,144 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+type memberRequest struct {
+	Login string `json:"login"`
+	Admin bool   `json:"admin,omitempty"` // может менять состав группы
+}
+
+// AddMember godoc
+// @Summary     Add or update group member
+// @Description Добавляет участника или меняет его флаг admin. Доступно администратору группы и администратору системы.
+// @Description Снять флаг admin с последнего администратора нельзя (409).
+// @Tags        groups
+// @Accept      json
+// @Produce     json
+// @Param       id      path string        true "group id"
+// @Param       request body memberRequest true "login, admin"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/groups/{id}/members [post]
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.members.add"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	t, err := h.target(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "group target rejected", err, "group_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if !t.manage {
+		logx.Error(h.Log, reqID, op, "not a group admin", domain.ErrForbidden, "user_id", t.me.ID, "group_id", t.group.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	login := strings.TrimSpace(req.Login)
+	if login == "" {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if !req.Admin && t.lastAdmin(login) {
+		logx.Error(h.Log, reqID, op, "last admin demotion", domain.ErrConflict, "group_id", t.group.ID, "login", login)
+		v1.WriteDomainError(w, r, domain.ErrConflict)
+		return
+	}
+
+	uid, err := h.Groups.UpsertGroupMember(r.Context(), t.group.ID, login, req.Admin)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db upsert member failed", err, "group_id", t.group.ID, "login", login)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// новому участнику становятся видны документы группы
+	h.bumpLists(r.Context(), uid)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", t.me.ID, "group_id", t.group.ID, "login", login, "admin", req.Admin)
+	v1.WriteOKResponse(w, r, map[string]bool{login: true})
+}
+
+// RemoveMember godoc
+// @Summary     Remove group member
+// @Description Администратор группы удаляет любого участника; любой участник может выйти сам.
+// @Description Последнего администратора удалить нельзя (409) — сначала назначьте другого или удалите группу.
+// @Tags        groups
+// @Produce     json
+// @Param       id    path string true "group id"
+// @Param       login path string true "member login"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/groups/{id}/members/{login} [delete]
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	const op = "groups.members.remove"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	t, err := h.target(r, domain.ScopeDocsShare)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "group target rejected", err, "group_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	login := r.PathValue("login")
+	if !t.manage && login != t.me.Login {
+		logx.Error(h.Log, reqID, op, "not a group admin", domain.ErrForbidden, "user_id", t.me.ID, "group_id", t.group.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	if t.lastAdmin(login) {
+		logx.Error(h.Log, reqID, op, "last admin removal", domain.ErrConflict, "group_id", t.group.ID, "login", login)
+		v1.WriteDomainError(w, r, domain.ErrConflict)
+		return
+	}
+
+	uid, err := h.Groups.RemoveGroupMember(r.Context(), t.group.ID, login)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db remove member failed", err, "group_id", t.group.ID, "login", login)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	// бывший участник теряет доступ к документам группы
+	h.bumpLists(r.Context(), uid)
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", t.me.ID, "group_id", t.group.ID, "login", login)
+	v1.WriteOKResponse(w, r, map[string]bool{login: true})
+}
@@ -0,0 +1,107 @@
+package invite
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// Верхняя граница числа использований одного приглашения
+const maxUsesLimit = 1000
+
+type createRequest struct {
+	Role      string     `json:"role,omitempty"`     // user (по умолчанию) | admin
+	MaxUses   int        `json:"max_uses,omitempty"` // 0 — одноразовое
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type createResponse struct {
+	Code string        `json:"code"` // показывается один раз
+	Meta domain.Invite `json:"meta"`
+}
+
+// Create godoc
+// @Summary     Create invite
+// @Description Выпускает код приглашения. Сырой код возвращается только в этом ответе.
+// @Description Администратор может задать любую роль; обычный пользователь (если INVITES_ALLOW_USERS) — только user.
+// @Tags        invites
+// @Accept      json
+// @Produce     json
+// @Param       request body createRequest true "role, max_uses, expires_at"
+// @Success     200 {object} domain.APIEnvelope{response=createResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/invites [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "invites.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := h.caller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.Role == "" {
+		req.Role = domain.RoleUser
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if !domain.ValidRole(req.Role) || req.MaxUses < 1 || req.MaxUses > maxUsesLimit {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "role", req.Role, "max_uses", req.MaxUses)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		logx.Error(h.Log, reqID, op, "expires_at in the past", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	// обычный пользователь не может раздавать права выше своих
+	if req.Role != domain.RoleUser && !me.IsAdmin() {
+		logx.Error(h.Log, reqID, op, "role not allowed", domain.ErrForbidden, "user_id", me.ID, "role", req.Role)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	rnd, _, err := token.NewOpaque()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate code failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	raw := domain.InviteCodePrefix + rnd
+
+	inv, err := h.Invites.CreateInvite(r.Context(), domain.Invite{
+		CodeHash:  token.HashOpaque(raw),
+		CreatedBy: &me.ID,
+		Role:      req.Role,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "invite_id", inv.ID, "role", inv.Role, "max_uses", inv.MaxUses)
+	v1.WriteOKResponse(w, r, createResponse{Code: raw, Meta: inv})
+}
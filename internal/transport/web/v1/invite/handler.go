@@ -0,0 +1,41 @@
+package invite
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+)
+
+// Handler — приглашения на регистрацию.
+type Handler struct {
+	Log     *log.Logger
+	Invites domain.InvitesRepo
+	// Разрешить обычным пользователям приглашать (только с ролью user).
+	AllowUsers bool
+}
+
+// caller: управлять приглашениями можно только из JWT-сессии; обычный
+// пользователь — лишь при включённом INVITES_ALLOW_USERS.
+func (h *Handler) caller(r *http.Request) (domain.User, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.ErrUnauth
+	}
+	if info, _ := mw.AuthInfoFromCtx(r.Context()); info.Kind != mw.AuthJWT {
+		return domain.User{}, domain.ErrForbidden
+	}
+	if !me.IsAdmin() && !h.AllowUsers {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return me, nil
+}
+
+// scope: администратор видит и отзывает любые приглашения, остальные — только свои.
+func scope(me domain.User) *domain.UserID {
+	if me.IsAdmin() {
+		return nil
+	}
+	return &me.ID
+}
@@ -0,0 +1,42 @@
+package invite
+
+import (
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// List godoc
+// @Summary     List invites
+// @Description Приглашения без сырых кодов: администратору — все, пользователю — выпущенные им.
+// @Tags        invites
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.Invite}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/invites [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "invites.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := h.caller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	list, err := h.Invites.ListInvites(r.Context(), scope(me))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(list))
+	v1.WriteOKData(w, r, list)
+}
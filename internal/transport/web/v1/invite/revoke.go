@@ -0,0 +1,56 @@
+package invite
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Revoke godoc
+// @Summary     Revoke invite
+// @Tags        invites
+// @Produce     json
+// @Param       id path string true "invite id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/invites/{id} [delete]
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "invites.revoke"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := h.caller(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad invite id", err, "id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	if err := h.Invites.RevokeInvite(r.Context(), id, scope(me)); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke failed", err, "invite_id", id)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "invite_id", id)
+	v1.WriteOKResponse(w, r, map[string]bool{id.String(): true})
+}
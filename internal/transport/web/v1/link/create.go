@@ -0,0 +1,115 @@
+package link
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// Минимальная длина пароля ссылки (политика паролей аккаунтов тут избыточна)
+const minLinkPasswordLen = 6
+
+type createRequest struct {
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Password     string     `json:"password,omitempty"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"`
+}
+
+type createResponse struct {
+	Token string           `json:"token"` // показывается один раз
+	URL   string           `json:"url"`   // относительный путь: /s/{token}
+	Meta  domain.ShareLink `json:"meta"`
+}
+
+// Create godoc
+// @Summary     Create anonymous share link
+// @Description Ссылка вида /s/{token} открывает документ без аккаунта. Сырой токен возвращается только в этом ответе.
+// @Description Доступно владельцу и co_owner.
+// @Tags        links
+// @Accept      json
+// @Produce     json
+// @Param       id      path string        true "document id"
+// @Param       request body createRequest false "expires_at, password, max_downloads"
+// @Success     200 {object} domain.APIEnvelope{response=createResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/links [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "links.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.target(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	// тело опционально: пустое — бессрочная ссылка без пароля и лимита
+	var req createRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logx.Error(h.Log, reqID, op, "bad json", err)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		logx.Error(h.Log, reqID, op, "expires_at in the past", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.MaxDownloads != nil && *req.MaxDownloads < 1 {
+		logx.Error(h.Log, reqID, op, "bad max_downloads", domain.ErrBadParams, "max_downloads", *req.MaxDownloads)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.Password != "" && len(req.Password) < minLinkPasswordLen {
+		logx.Error(h.Log, reqID, op, "password too short", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	var passHash string
+	if req.Password != "" {
+		if passHash, err = h.Hasher.Hash(req.Password); err != nil {
+			logx.Error(h.Log, reqID, op, "hash failed", err)
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+	}
+
+	rnd, _, err := token.NewOpaque()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate token failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	raw := domain.ShareLinkPrefix + rnd
+
+	l, err := h.Links.CreateShareLink(r.Context(), domain.ShareLink{
+		DocID:        d.ID,
+		CreatedBy:    &me.ID,
+		TokenHash:    token.HashOpaque(raw),
+		PassHash:     passHash,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "link_id", l.ID, "password", l.HasPassword)
+	v1.WriteOKResponse(w, r, createResponse{Token: raw, URL: "/s/" + raw, Meta: l})
+}
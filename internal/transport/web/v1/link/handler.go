@@ -0,0 +1,45 @@
+package link
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	"github.com/google/uuid"
+)
+
+// Handler — анонимные ссылки на документы.
+type Handler struct {
+	Log      *log.Logger
+	Links    domain.ShareLinksRepo
+	Docs     domain.DocsRepo
+	Storage  domain.BlobStorage
+	Hasher   domain.PasswordHasher
+	Throttle domain.LoginThrottle
+	// Метки засчитанных скачиваний: докачка по ним бесплатна
+	Cache domain.Cache
+}
+
+// target: управлять ссылками могут владелец и co_owner (со скоупом docs:share).
+func (h *Handler) target(r *http.Request) (domain.User, domain.Document, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.Document{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), domain.ScopeDocsShare) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrBadParams
+	}
+	d, _, err := h.Docs.DocByID(r.Context(), docID, domain.AsUser(me))
+	if err != nil {
+		return domain.User{}, domain.Document{}, domain.ErrNotFound
+	}
+	if !domain.PermAllows(d.Permission, domain.PermCoOwner) {
+		return domain.User{}, domain.Document{}, domain.ErrForbidden
+	}
+	return me, d, nil
+}
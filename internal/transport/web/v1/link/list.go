@@ -0,0 +1,45 @@
+package link
+
+import (
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// List godoc
+// @Summary     List share links of document
+// @Description Ссылки без сырых токенов (включая отозванные и истёкшие). Доступно владельцу и co_owner.
+// @Tags        links
+// @Produce     json
+// @Param       id path string true "document id"
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.ShareLink}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/links [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "links.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.target(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	list, err := h.Links.ListShareLinks(r.Context(), d.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "doc_id", d.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "count", len(list))
+	v1.WriteOKData(w, r, list)
+}
@@ -0,0 +1,226 @@
+package link
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// Open godoc
+// @Summary     Open document by anonymous share link
+// @Description Без аутентификации. Пароль ссылки — в заголовке X-Share-Password или через HTTP Basic (логин любой).
+// @Description Файлы отдаются с поддержкой Range; не считается только докачка — один диапазон файла не с нулевого байта после засчитанного скачивания тем же клиентом.
+// @Tags        links
+// @Produce     json
+// @Param       token path string true "share link token (mds_...)"
+// @Param       X-Share-Password header string false "link password"
+// @Success     200 {file}  []byte "when file"
+// @Success     200 {object} domain.APIEnvelope "when JSON document"
+// @Success     206 {file}  []byte "partial content"
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     429 {object} domain.APIEnvelope
+// @Router      /s/{token} [get]
+func (h *Handler) Open(w http.ResponseWriter, r *http.Request) {
+	const op = "links.open"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", "/s/…") // токен в лог не пишем
+
+	// токен в URL: не кешировать, не отдавать в Referer, не индексировать
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	// против перебора токенов/паролей каждая попытка считается с IP заранее;
+	// верный токен (и пароль) попытку возвращает
+	ip := mw.ClientIP(r)
+	st, err := h.Throttle.Attempt(r.Context(), "", ip)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "throttle attempt failed", err, "ip", ip)
+	}
+	if st.Blocked() {
+		logx.Error(h.Log, reqID, op, "throttled", st.Err(), "ip", ip, "retry_after", st.RetryAfter)
+		v1.WriteThrottled(w, r, st.Err(), st.RetryAfter)
+		return
+	}
+	refund := func() {
+		if err := h.Throttle.Succeed(r.Context(), "", ip); err != nil {
+			logx.Error(h.Log, reqID, op, "throttle succeed failed", err, "ip", ip)
+		}
+	}
+
+	raw := r.PathValue("token")
+	if !strings.HasPrefix(raw, domain.ShareLinkPrefix) {
+		logx.Error(h.Log, reqID, op, "bad token prefix", domain.ErrNotFound, "ip", ip)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+	l, err := h.Links.ShareLinkByHash(r.Context(), token.HashOpaque(raw))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			logx.Error(h.Log, reqID, op, "link not found or inactive", err, "ip", ip)
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		refund()
+		logx.Error(h.Log, reqID, op, "db get link failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	if l.HasPassword {
+		pass := r.Header.Get("X-Share-Password")
+		if pass == "" {
+			_, pass, _ = r.BasicAuth()
+		}
+		if pass == "" {
+			// браузер покажет окно ввода пароля; это не попытка подбора
+			refund()
+			w.Header().Set("WWW-Authenticate", `Basic realm="mydocs share link", charset="UTF-8"`)
+			logx.Error(h.Log, reqID, op, "password required", domain.ErrUnauth, "link_id", l.ID)
+			v1.WriteDomainError(w, r, domain.ErrUnauth)
+			return
+		}
+		ok, err := h.Hasher.Verify(pass, l.PassHash)
+		if err != nil || !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mydocs share link", charset="UTF-8"`)
+			logx.Error(h.Log, reqID, op, "bad link password", domain.ErrUnauth, "link_id", l.ID, "ip", ip)
+			v1.WriteDomainError(w, r, domain.ErrUnauth)
+			return
+		}
+	}
+	refund()
+
+	// ссылка даёт доступ независимо от ACL — читаем документ системным принципалом
+	d, dj, err := h.Docs.DocByID(r.Context(), l.DocID, domain.System)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "doc not found", err, "link_id", l.ID, "doc_id", l.DocID)
+		v1.WriteDomainError(w, r, domain.ErrNotFound)
+		return
+	}
+	w.Header().Set("Last-Modified", v1.HTTPTime(d.UpdatedAt))
+
+	if r.Method == http.MethodHead {
+		if d.File {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Type", d.MIME)
+		}
+		w.WriteHeader(http.StatusOK)
+		logx.Info(h.Log, reqID, op, "head ok", "link_id", l.ID, "doc_id", d.ID)
+		return
+	}
+
+	// докачка файла (один диапазон не с нуля) — не новое скачивание, но только если
+	// этот клиент уже скачивал по ссылке (метка в кеше); ссылка при этом уже прошла
+	// activeLink. JSON отдаётся целиком при любом Range — считается всегда
+	rangeHdr := r.Header.Get("Range")
+	resumeKey := domain.CacheKeyShareResume(l.ID.String(), resumeClient(ip, r.UserAgent()))
+	if !d.File || !isResume(rangeHdr) || !h.resumable(r, reqID, op, resumeKey) {
+		if err := h.Links.CountShareDownload(r.Context(), l.ID); err != nil {
+			logx.Error(h.Log, reqID, op, "count download failed", err, "link_id", l.ID)
+			if errors.Is(err, domain.ErrNotFound) {
+				v1.WriteDomainError(w, r, domain.ErrNotFound)
+				return
+			}
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+			return
+		}
+		if d.File && h.Cache != nil {
+			if err := h.Cache.Set(r.Context(), resumeKey, []byte("1"), int(resumeTTL.Seconds())); err != nil {
+				logx.Error(h.Log, reqID, op, "set resume mark failed", err, "link_id", l.ID)
+			}
+		}
+	}
+
+	if !d.File {
+		if dj == nil {
+			dj = domain.DocJSON{}
+		}
+		logx.Info(h.Log, reqID, op, "json ok", "link_id", l.ID, "doc_id", d.ID)
+		v1.WriteOKData(w, r, dj)
+		return
+	}
+
+	rc, contentLen, contentRange, contentType, _, err := h.Storage.Get(r.Context(), d.StorageKey, rangeHdr)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "storage get failed", err, "doc_id", d.ID, "range", rangeHdr)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	defer rc.Close()
+
+	if contentType == "" {
+		contentType = d.MIME
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(contentLen, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.Name}))
+
+	if contentRange != "" {
+		w.Header().Set("Content-Range", contentRange)
+		w.WriteHeader(http.StatusPartialContent)
+		logx.Info(h.Log, reqID, op, "partial content", "link_id", l.ID, "doc_id", d.ID, "range", contentRange, "len", contentLen)
+	} else {
+		w.WriteHeader(http.StatusOK)
+		logx.Info(h.Log, reqID, op, "file ok", "link_id", l.ID, "doc_id", d.ID, "len", contentLen)
+	}
+	_, _ = io.Copy(w, rc)
+}
+
+// resumeTTL — сколько после засчитанного скачивания клиент может докачивать бесплатно.
+const resumeTTL = time.Hour
+
+// resumeClient — клиент для метки докачки: IP и User-Agent, в ключе только хэш.
+func resumeClient(ip, ua string) string {
+	sum := sha256.Sum256([]byte(ip + "\x00" + ua))
+	return hex.EncodeToString(sum[:16])
+}
+
+// resumable: клиент уже скачивал файл по ссылке. Без кеша или при ошибке — нет,
+// такой запрос считается новым скачиванием.
+func (h *Handler) resumable(r *http.Request, reqID, op, key string) bool {
+	if h.Cache == nil {
+		return false
+	}
+	b, err := h.Cache.Get(r.Context(), key)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "get resume mark failed", err)
+		return false
+	}
+	return len(b) > 0
+}
+
+// isResume: Range — ровно один диапазон bytes=N- или bytes=N-M с N > 0.
+// Суффиксный (bytes=-N), составной и некорректный Range докачкой не считаются.
+func isResume(h string) bool {
+	spec, ok := strings.CutPrefix(h, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return false
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start <= 0 {
+		return false
+	}
+	if last == "" {
+		return true
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	return err == nil && end >= start
+}
@@ -0,0 +1,57 @@
+package link
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+// Revoke godoc
+// @Summary     Revoke share link
+// @Tags        links
+// @Produce     json
+// @Param       id     path string true "document id"
+// @Param       linkID path string true "link id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/docs/{id}/links/{linkID} [delete]
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "links.revoke"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, d, err := h.target(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "target rejected", err, "doc_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("linkID"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad link id", err, "id_raw", r.PathValue("linkID"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	if err := h.Links.RevokeShareLink(r.Context(), d.ID, id); err != nil {
+		logx.Error(h.Log, reqID, op, "revoke failed", err, "link_id", id)
+		if errors.Is(err, domain.ErrNotFound) {
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "doc_id", d.ID, "link_id", id)
+	v1.WriteOKResponse(w, r, map[string]bool{id.String(): true})
+}
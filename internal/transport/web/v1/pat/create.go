@@ -0,0 +1,103 @@
+package pat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EgorLis/my-docs/internal/auth/token"
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+type createRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // null — бессрочный
+}
+
+type createResponse struct {
+	Token string               `json:"token"` // показывается один раз
+	Meta  domain.PersonalToken `json:"meta"`
+}
+
+// Create godoc
+// @Summary     Create personal access token
+// @Description Выпускает PAT для автоматизации. Сырой токен возвращается только в этом ответе.
+// @Description Доступно только из JWT-сессии. Скоупы: docs:read, docs:write, docs:share.
+// @Tags        tokens
+// @Accept      json
+// @Produce     json
+// @Param       request body createRequest true "name, scopes, expires_at"
+// @Success     200 {object} domain.APIEnvelope{response=createResponse}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     500 {object} domain.APIEnvelope
+// @Router      /api/tokens [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "tokens.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := sessionUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		logx.Error(h.Log, reqID, op, "empty name or scopes", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	for _, sc := range req.Scopes {
+		if !domain.ValidScope(sc) {
+			logx.Error(h.Log, reqID, op, "unknown scope", domain.ErrBadParams, "scope", sc)
+			v1.WriteDomainError(w, r, domain.ErrBadParams)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		logx.Error(h.Log, reqID, op, "expires_at in the past", domain.ErrBadParams)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	rnd, _, err := token.NewOpaque()
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "generate token failed", err)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+	// префикс входит в хэш: middleware хэширует токен целиком
+	raw := domain.PersonalTokenPrefix + rnd
+	hash := token.HashOpaque(raw)
+
+	t, err := h.Tokens.CreatePersonalToken(r.Context(), domain.PersonalToken{
+		UserID:    me.ID,
+		Name:      req.Name,
+		TokenHash: hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "token_id", t.ID, "scopes", strings.Join(t.Scopes, ","))
+	v1.WriteOKResponse(w, r, createResponse{Token: raw, Meta: t})
+}
@@ -0,0 +1,28 @@
+package pat
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+)
+
+// Handler — управление персональными токенами доступа (PAT).
+type Handler struct {
+	Log    *log.Logger
+	Tokens domain.PersonalTokensRepo
+}
+
+// sessionUser: управлять токенами можно только из интерактивной JWT-сессии —
+// PAT не должен уметь выпускать/отзывать другие PAT.
+func sessionUser(r *http.Request) (domain.User, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.ErrUnauth
+	}
+	if info, _ := mw.AuthInfoFromCtx(r.Context()); info.Kind != mw.AuthJWT {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return me, nil
+}
@@ -0,0 +1,42 @@
+package pat
+
+import (
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+)
+
+// List godoc
+// @Summary     List personal access tokens
+// @Description Токены текущего пользователя (без сырых значений), включая отозванные.
+// @Tags        tokens
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.PersonalToken}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/tokens [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "tokens.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := sessionUser(r)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "not a session", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	list, err := h.Tokens.ListPersonalTokens(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(list))
+	v1.WriteOKData(w, r, list)
+}
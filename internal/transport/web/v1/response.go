@@ -4,23 +4,48 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/EgorLis/my-docs/internal/domain"
 	"github.com/EgorLis/my-docs/internal/transport/web/mw"
 )
 
-// MapDomainError решает HTTP-статус + error.code/text для конверта
+// MapDomainError решает HTTP-статус + error.code/text для конверта;
+// подробности *domain.ValidationError попадают в error.details.
 func MapDomainError(err error) (httpStatus int, env domain.APIEnvelope) {
+	httpStatus, env = mapDomainError(err)
+	var ve *domain.ValidationError
+	if errors.As(err, &ve) && env.Error != nil {
+		env.Error.Details = ve.Details
+	}
+	return httpStatus, env
+}
+
+func mapDomainError(err error) (httpStatus int, env domain.APIEnvelope) {
 	switch {
 	case errors.Is(err, domain.ErrBadParams):
 		return http.StatusBadRequest, domain.Fail(domain.ErrCodeBadParams, "bad params")
-	case errors.Is(err, domain.ErrUnauth):
+	case errors.Is(err, domain.ErrSchemaViolation):
+		return http.StatusUnprocessableEntity, domain.Fail(domain.ErrCodeSchemaViolation, "schema violation")
+	case errors.Is(err, domain.ErrUnauth), errors.Is(err, domain.ErrTokenReused):
 		return http.StatusUnauthorized, domain.Fail(domain.ErrCodeUnauth, "unauthorized")
+	case errors.Is(err, domain.ErrAccountDisabled):
+		return http.StatusForbidden, domain.Fail(domain.ErrCodeAccountDisabled, "account disabled")
 	case errors.Is(err, domain.ErrForbidden):
 		return http.StatusForbidden, domain.Fail(domain.ErrCodeForbidden, "forbidden")
+	case errors.Is(err, domain.ErrTooManyAttempts):
+		return http.StatusTooManyRequests, domain.Fail(domain.ErrCodeTooManyAttempts, "too many attempts")
+	case errors.Is(err, domain.ErrAccountLocked):
+		return http.StatusTooManyRequests, domain.Fail(domain.ErrCodeAccountLocked, "account temporarily locked")
 	case errors.Is(err, domain.ErrMethodNotAllowed):
 		return http.StatusMethodNotAllowed, domain.Fail(domain.ErrCodeMethodNotAllowed, "method not allowed")
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict, domain.Fail(domain.ErrCodeConflict, "conflict")
+	case errors.Is(err, domain.ErrPrecondition):
+		return http.StatusPreconditionFailed, domain.Fail(domain.ErrCodePrecondition, "precondition failed")
+	case errors.Is(err, domain.ErrPreconditionReq):
+		return http.StatusPreconditionRequired, domain.Fail(domain.ErrCodePreconditionReq, "precondition required")
 	case errors.Is(err, domain.ErrNotImplemented):
 		return http.StatusNotImplemented, domain.Fail(domain.ErrCodeNotImplemented, "not implemented")
 	case errors.Is(err, domain.ErrNotFound):
@@ -57,6 +82,16 @@ func WriteDomainError(w http.ResponseWriter, r *http.Request, err error) {
 	WriteEnvelope(w, r, status, env)
 }
 
+// WriteThrottled — 429 с Retry-After (в секундах, округление вверх).
+func WriteThrottled(w http.ResponseWriter, r *http.Request, err error, retryAfter time.Duration) {
+	secs := int64((retryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	WriteDomainError(w, r, err)
+}
+
 // Стандартный формат времени заголовков
 func HTTPTime(t time.Time) string {
 	return t.UTC().Format(http.TimeFormat)
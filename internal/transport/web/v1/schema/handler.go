@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	"github.com/google/uuid"
+)
+
+// Handler — реестр JSON-схем: свои схемы пользователя и глобальные
+// (их регистрируют администраторы).
+type Handler struct {
+	Log       *log.Logger
+	Schemas   domain.SchemasRepo
+	Validator domain.SchemaValidator
+}
+
+// caller: смотреть схемы можно со скоупом docs:read, менять — с docs:write.
+func caller(r *http.Request, scope string) (domain.User, error) {
+	me, ok := mw.UserFromCtx(r.Context())
+	if !ok {
+		return domain.User{}, domain.ErrUnauth
+	}
+	if !mw.HasScope(r.Context(), scope) {
+		return domain.User{}, domain.ErrForbidden
+	}
+	return me, nil
+}
+
+// visible: чужая личная схема выглядит несуществующей.
+func (h *Handler) visible(ctx context.Context, id uuid.UUID, me domain.User) (domain.JSONSchema, error) {
+	s, err := h.Schemas.SchemaByID(ctx, id)
+	if err != nil {
+		return domain.JSONSchema{}, err
+	}
+	if !s.Global() && *s.OwnerID != me.ID {
+		return domain.JSONSchema{}, domain.ErrNotFound
+	}
+	return s, nil
+}
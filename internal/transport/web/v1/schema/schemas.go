@@ -0,0 +1,222 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorLis/my-docs/internal/domain"
+	"github.com/EgorLis/my-docs/internal/transport/web/logx"
+	"github.com/EgorLis/my-docs/internal/transport/web/mw"
+	v1 "github.com/EgorLis/my-docs/internal/transport/web/v1"
+	"github.com/google/uuid"
+)
+
+type createRequest struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema" swaggertype:"object"`
+	Global bool            `json:"global,omitempty"` // только администратор
+}
+
+// Create godoc
+// @Summary     Register JSON schema
+// @Description JSON Schema draft 2020-12; внешние $ref запрещены. Имя уникально среди своих схем
+// @Description (у глобальных — среди глобальных), 409 если занято. Тело схемы неизменно: новая версия — новое имя.
+// @Description Некорректная схема — 400 с подробностями в error.details.
+// @Tags        schemas
+// @Accept      json
+// @Produce     json
+// @Param       request body createRequest true "name, schema, global"
+// @Success     200 {object} domain.APIEnvelope{data=domain.JSONSchema}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/schemas [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "schemas.create"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsWrite)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logx.Error(h.Log, reqID, op, "bad json", err)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if !domain.ValidSchemaName(name) || len(req.Schema) == 0 {
+		logx.Error(h.Log, reqID, op, "validation failed", domain.ErrBadParams, "name", name)
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+	if req.Global && !me.IsAdmin() {
+		logx.Error(h.Log, reqID, op, "global schema requires admin", domain.ErrForbidden, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+	if err := h.Validator.Check(req.Schema); err != nil {
+		logx.Error(h.Log, reqID, op, "schema does not compile", err, "name", name)
+		v1.WriteDomainError(w, r, err) // 400 с error.details
+		return
+	}
+
+	s := domain.JSONSchema{Name: name, Body: req.Schema}
+	if !req.Global {
+		s.OwnerID = &me.ID
+	}
+	s, err = h.Schemas.CreateSchema(r.Context(), s)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db create failed", err, "user_id", me.ID, "name", name)
+		if errors.Is(err, domain.ErrConflict) {
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+			return
+		}
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "schema_id", s.ID, "name", s.Name, "global", s.Global())
+	v1.WriteOKData(w, r, s)
+}
+
+// List godoc
+// @Summary     List JSON schemas
+// @Description Свои схемы и глобальные. При совпадении имён документы ссылаются на свою схему.
+// @Tags        schemas
+// @Produce     json
+// @Success     200 {object} domain.APIEnvelope{data=[]domain.JSONSchema}
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Router      /api/schemas [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "schemas.list"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+
+	list, err := h.Schemas.ListSchemas(r.Context(), me.ID)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "db list failed", err, "user_id", me.ID)
+		v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "count", len(list))
+	v1.WriteOKData(w, r, list)
+}
+
+// Get godoc
+// @Summary     Get JSON schema
+// @Tags        schemas
+// @Produce     json
+// @Param       id path string true "schema id"
+// @Success     200 {object} domain.APIEnvelope{data=domain.JSONSchema}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Router      /api/schemas/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "schemas.get"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsRead)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad schema id", err, "schema_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	s, err := h.visible(r.Context(), id, me)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "schema not found or no access", err, "schema_id", id)
+		v1.WriteDomainError(w, r, err) // доменные ошибки; сбой БД → 500
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "schema_id", s.ID)
+	v1.WriteOKData(w, r, s)
+}
+
+// Delete godoc
+// @Summary     Delete JSON schema
+// @Description Свою схему удаляет владелец, глобальную — администратор.
+// @Description Пока на схему ссылается хотя бы один документ (в том числе в корзине) — 409.
+// @Tags        schemas
+// @Produce     json
+// @Param       id path string true "schema id"
+// @Success     200 {object} domain.APIEnvelope{response=object}
+// @Failure     400 {object} domain.APIEnvelope
+// @Failure     401 {object} domain.APIEnvelope
+// @Failure     403 {object} domain.APIEnvelope
+// @Failure     404 {object} domain.APIEnvelope
+// @Failure     409 {object} domain.APIEnvelope
+// @Router      /api/schemas/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "schemas.delete"
+	reqID := mw.RequestIDFromCtx(r.Context())
+	logx.Info(h.Log, reqID, op, "start", "method", r.Method, "path", r.URL.Path)
+
+	me, err := caller(r, domain.ScopeDocsWrite)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "caller not allowed", err)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "bad schema id", err, "schema_id_raw", r.PathValue("id"))
+		v1.WriteDomainError(w, r, domain.ErrBadParams)
+		return
+	}
+
+	s, err := h.visible(r.Context(), id, me)
+	if err != nil {
+		logx.Error(h.Log, reqID, op, "schema not found or no access", err, "schema_id", id)
+		v1.WriteDomainError(w, r, err)
+		return
+	}
+	if s.Global() && !me.IsAdmin() {
+		logx.Error(h.Log, reqID, op, "global schema requires admin", domain.ErrForbidden, "schema_id", id)
+		v1.WriteDomainError(w, r, domain.ErrForbidden)
+		return
+	}
+
+	if err := h.Schemas.DeleteSchema(r.Context(), s.ID); err != nil {
+		logx.Error(h.Log, reqID, op, "db delete failed", err, "schema_id", s.ID)
+		switch {
+		case errors.Is(err, domain.ErrConflict):
+			v1.WriteDomainError(w, r, domain.ErrConflict)
+		case errors.Is(err, domain.ErrNotFound):
+			v1.WriteDomainError(w, r, domain.ErrNotFound)
+		default:
+			v1.WriteDomainError(w, r, domain.ErrUnexpected)
+		}
+		return
+	}
+
+	logx.Info(h.Log, reqID, op, "ok", "user_id", me.ID, "schema_id", s.ID, "name", s.Name)
+	v1.WriteOKResponse(w, r, map[string]bool{s.ID.String(): true})
+}